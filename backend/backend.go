@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
 	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+	"github.com/OpenSlides/openslides-vote-service/backend/mirror"
 	"github.com/OpenSlides/openslides-vote-service/backend/postgres"
 	"github.com/OpenSlides/openslides-vote-service/backend/redis"
+	"github.com/OpenSlides/openslides-vote-service/backend/sqlite"
 	"github.com/OpenSlides/openslides-vote-service/vote"
 )
 
@@ -23,22 +26,60 @@ var (
 	envPostgresDatabase     = environment.NewVariable("VOTE_DATABASE_NAME", "openslides", "Name of the database to save long running polls.")
 	envPostgresPasswordFile = environment.NewVariable("VOTE_DATABASE_PASSWORD_FILE", "/run/secrets/postgres_password", "Password of the postgres database used for long polls.")
 
+	envMemoryJournalFile = environment.NewVariable("VOTE_MEMORY_JOURNAL_FILE", "", "File for the journal of the memory backend. If set, the votes of fast polls survive a restart in single instance mode.")
+
+	envSQLiteFile = environment.NewVariable("VOTE_SQLITE_FILE", "", "File of a sqlite database. If set, it is used for fast and long polls instead of redis and postgres. Needs VOTE_SINGLE_INSTANCE.")
+
 	envSingleInstance = environment.NewVariable("VOTE_SINGLE_INSTANCE", "false", "More performance if the serice is not scalled horizontally.")
+
+	envBackendFast       = environment.NewVariable("VOTE_BACKEND_FAST", "", "Backend for fast polls. One of memory, redis, postgres or sqlite. Memory and sqlite need VOTE_SINGLE_INSTANCE. If empty, it is redis, memory with VOTE_SINGLE_INSTANCE or sqlite with VOTE_SQLITE_FILE.")
+	envBackendLong       = environment.NewVariable("VOTE_BACKEND_LONG", "", "Backend for long polls. One of memory, redis, postgres or sqlite. If empty, it is postgres or sqlite with VOTE_SQLITE_FILE.")
+	envBackendFastMirror = environment.NewVariable("VOTE_BACKEND_FAST_MIRROR", "", "Backend, that gets a copy of all votes of fast polls. One of memory, redis, postgres or sqlite. If empty, fast polls are not mirrored.")
+	envMirrorMaxLag      = environment.NewVariable("VOTE_BACKEND_FAST_MIRROR_MAX_LAG", "1000", "Number of votes that can wait to be written to the mirror. If more votes are waiting, new votes are delayed.")
+
+	envBackends = environment.NewVariable("VOTE_BACKENDS", "", "Additional backends as comma separated list of name=backend, for example archive=sqlite. Polls use a backend, if their backend field is the name.")
 )
 
-// Build builds a fast and a long backends from the environment.
-func Build(lookup environment.Environmenter) (fast, long func(context.Context) (vote.Backend, error), singleInstance bool, err error) {
+// Builder starts a backend.
+type Builder func(ctx context.Context) (vote.Backend, error)
+
+// Build builds the backends from the environment.
+//
+// The returned map contains a builder for each backend name. It always
+// contains the names fast and long. If two names use the same kind of backend,
+// the builders return the same instance.
+func Build(lookup environment.Environmenter) (backends map[string]Builder, singleInstance bool, err error) {
 	// All environment variables have to be called in this function and not in a
 	// sub function. In other case they will not be included in the generated
 	// file environment.md.
 
-	buildMemory := func(_ context.Context) (vote.Backend, error) {
-		return memory.New(), nil
+	memoryJournalFile := envMemoryJournalFile.Value(lookup)
+	buildMemory := func(context.Context) (vote.Backend, error) {
+		if memoryJournalFile == "" {
+			return memory.New(), nil
+		}
+
+		m, err := memory.NewWithJournal(memoryJournalFile)
+		if err != nil {
+			return nil, fmt.Errorf("open memory journal: %w", err)
+		}
+		return m, nil
+	}
+
+	// With the failover, the service starts without redis and uses the long
+	// backend, until redis is reachable.
+	failover, err := vote.FailoverEnabled(lookup)
+	if err != nil {
+		return nil, false, err
 	}
 
 	redisAddr := envRedisHost.Value(lookup) + ":" + envRedisPort.Value(lookup)
 	buildRedis := func(ctx context.Context) (vote.Backend, error) {
 		r := redis.New(redisAddr)
+		if failover {
+			return r, nil
+		}
+
 		r.Wait(ctx)
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
@@ -47,10 +88,9 @@ func Build(lookup environment.Environmenter) (fast, long func(context.Context) (
 		return r, nil
 	}
 
-	dbPassword, err := environment.ReadSecret(lookup, envPostgresPasswordFile)
-	if err != nil {
-		return nil, nil, false, fmt.Errorf("reading postgres password: %w", err)
-	}
+	// The password is only needed, if postgres is used. So the error is
+	// returned when the postgres backend is build.
+	dbPassword, dbPasswordErr := environment.ReadSecret(lookup, envPostgresPasswordFile)
 
 	postgresAddr := fmt.Sprintf(
 		`user='%s' password='%s' host='%s' port='%s' dbname='%s'`,
@@ -62,6 +102,10 @@ func Build(lookup environment.Environmenter) (fast, long func(context.Context) (
 	)
 
 	buildPostgres := func(ctx context.Context) (vote.Backend, error) {
+		if dbPasswordErr != nil {
+			return nil, fmt.Errorf("reading postgres password: %w", dbPasswordErr)
+		}
+
 		p, err := postgres.New(ctx, postgresAddr)
 		if err != nil {
 			return nil, fmt.Errorf("creating postgres connection pool: %w", err)
@@ -74,14 +118,152 @@ func Build(lookup environment.Environmenter) (fast, long func(context.Context) (
 		return p, nil
 	}
 
-	long = buildPostgres
-	fast = buildRedis
+	sqliteFile := envSQLiteFile.Value(lookup)
+	buildSQLite := func(ctx context.Context) (vote.Backend, error) {
+		if sqliteFile == "" {
+			return nil, fmt.Errorf("%s is not set", envSQLiteFile.Key)
+		}
+
+		s, err := sqlite.New(sqliteFile)
+		if err != nil {
+			return nil, fmt.Errorf("open sqlite database: %w", err)
+		}
+
+		if err := s.Migrate(ctx); err != nil {
+			return nil, fmt.Errorf("creating shema: %w", err)
+		}
+		return s, nil
+	}
+
+	registry := map[string]Builder{
+		"memory":   shared(buildMemory),
+		"redis":    shared(buildRedis),
+		"postgres": shared(buildPostgres),
+		"sqlite":   shared(buildSQLite),
+	}
+
 	singleInstace, _ := strconv.ParseBool(envSingleInstance.Value(lookup))
+
+	fast := "redis"
+	long := "postgres"
 	if singleInstace {
-		fast = buildMemory
+		fast = "memory"
+	}
+
+	if sqliteFile != "" {
+		fast = "sqlite"
+		long = "sqlite"
+	}
+
+	if v := envBackendFast.Value(lookup); v != "" {
+		fast = v
+	}
+
+	if v := envBackendLong.Value(lookup); v != "" {
+		long = v
+	}
+
+	names := map[string]string{"fast": fast, "long": long}
+	rawBackends := envBackends.Value(lookup)
+	for _, entry := range strings.Split(rawBackends, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, kind, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, false, fmt.Errorf("invalid entry %q in %s. Expected name=backend", entry, envBackends.Key)
+		}
+
+		if _, exists := names[name]; exists {
+			return nil, false, fmt.Errorf("backend %s in %s is defined more than once", name, envBackends.Key)
+		}
+		names[name] = kind
+	}
+
+	backends = make(map[string]Builder, len(names))
+	for name, kind := range names {
+		builder, ok := registry[kind]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown backend %q for %s", kind, name)
+		}
+
+		if !singleInstace && localBackend(kind) {
+			return nil, false, fmt.Errorf("backend %s for %s can only be used with %s", kind, name, envSingleInstance.Key)
+		}
+		backends[name] = builder
+	}
+
+	mirrorKind := envBackendFastMirror.Value(lookup)
+	rawMaxLag := envMirrorMaxLag.Value(lookup)
+	if mirrorKind != "" {
+		buildSecondary, ok := registry[mirrorKind]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown backend %q for %s", mirrorKind, envBackendFastMirror.Key)
+		}
+
+		if !singleInstace && localBackend(mirrorKind) {
+			return nil, false, fmt.Errorf("backend %s for %s can only be used with %s", mirrorKind, envBackendFastMirror.Key, envSingleInstance.Key)
+		}
+
+		if mirrorKind == fast {
+			return nil, false, fmt.Errorf("%s has to be different from the fast backend %s", envBackendFastMirror.Key, fast)
+		}
+
+		maxLag, err := strconv.Atoi(rawMaxLag)
+		if err != nil || maxLag < 1 {
+			return nil, false, fmt.Errorf("invalid value for %s. Expected positive number, got %s", envMirrorMaxLag.Key, rawMaxLag)
+		}
+
+		buildPrimary := backends["fast"]
+		backends["fast"] = shared(func(ctx context.Context) (vote.Backend, error) {
+			primary, err := buildPrimary(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			secondary, err := buildSecondary(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("mirror: %w", err)
+			}
+
+			m := mirror.New(primary, secondary, maxLag)
+			go m.Run(ctx)
+			return m, nil
+		})
 	}
 
-	return fast, long, singleInstace, nil
+	return backends, singleInstace, nil
+}
+
+// localBackend returns true, if the backend saves the polls only for this
+// instance. With more instances, each of them would have its own polls.
+func localBackend(kind string) bool {
+	return kind == "memory" || kind == "sqlite"
+}
+
+// shared returns a builder, that only builds one backend. All calls return the
+// same instance.
+func shared(build Builder) Builder {
+	var mu sync.Mutex
+	var backend vote.Backend
+	return func(ctx context.Context) (vote.Backend, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if backend != nil {
+			return backend, nil
+		}
+
+		b, err := build(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		backend = b
+		return backend, nil
+	}
 }
 
 // encodePostgresConfig encodes a string to be used in the postgres key value style.
@@ -0,0 +1,119 @@
+package backend_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
+	"github.com/OpenSlides/openslides-vote-service/backend"
+)
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	sqliteFile := filepath.Join(t.TempDir(), "vote.db")
+
+	for _, tt := range []struct {
+		name   string
+		env    map[string]string
+		expect map[string]string
+	}{
+		{
+			"Default",
+			nil,
+			map[string]string{"fast": "redis", "long": "postgres"},
+		},
+		{
+			"Single instance",
+			map[string]string{"VOTE_SINGLE_INSTANCE": "true"},
+			map[string]string{"fast": "memory", "long": "postgres"},
+		},
+		{
+			"SQLite file",
+			map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_SQLITE_FILE": sqliteFile},
+			map[string]string{"fast": "sqlite", "long": "sqlite"},
+		},
+		{
+			"Explicit backends",
+			map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_BACKEND_FAST": "memory", "VOTE_BACKEND_LONG": "sqlite", "VOTE_SQLITE_FILE": sqliteFile},
+			map[string]string{"fast": "memory", "long": "sqlite"},
+		},
+		{
+			"Additional backends",
+			map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_BACKENDS": "archive=sqlite, other=memory", "VOTE_SQLITE_FILE": sqliteFile},
+			map[string]string{"fast": "sqlite", "long": "sqlite", "archive": "sqlite", "other": "memory"},
+		},
+		{
+			"Mirror of the fast backend",
+			map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_BACKEND_FAST": "memory", "VOTE_BACKEND_FAST_MIRROR": "sqlite", "VOTE_SQLITE_FILE": sqliteFile},
+			map[string]string{"fast": "memory mirrored to sqlite", "long": "sqlite"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backends, _, err := backend.Build(environment.ForTests(tt.env))
+			if err != nil {
+				t.Fatalf("Build returned unexpected error: %v", err)
+			}
+
+			if len(backends) != len(tt.expect) {
+				t.Errorf("Got %d backends, expected %d", len(backends), len(tt.expect))
+			}
+
+			for name, kind := range tt.expect {
+				build, ok := backends[name]
+				if !ok {
+					t.Errorf("Backend %s is missing", name)
+					continue
+				}
+
+				// Only start the backends, that do not need a server.
+				if kind == "redis" || kind == "postgres" {
+					continue
+				}
+
+				b, err := build(ctx)
+				if err != nil {
+					t.Fatalf("Building backend %s returned unexpected error: %v", name, err)
+				}
+
+				if got := b.(interface{ String() string }).String(); got != kind {
+					t.Errorf("Backend %s is %s, expected %s", name, got, kind)
+				}
+			}
+		})
+	}
+
+	t.Run("Same instance", func(t *testing.T) {
+		backends, _, err := backend.Build(environment.ForTests(map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_BACKENDS": "other=memory"}))
+		if err != nil {
+			t.Fatalf("Build returned unexpected error: %v", err)
+		}
+
+		fast, _ := backends["fast"](ctx)
+		other, _ := backends["other"](ctx)
+		if fast != other {
+			t.Errorf("fast and other are different instances")
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+	}{
+		{"Unknown backend", map[string]string{"VOTE_BACKEND_FAST": "unknown"}},
+		{"Missing name", map[string]string{"VOTE_BACKENDS": "sqlite"}},
+		{"Redefine fast", map[string]string{"VOTE_SINGLE_INSTANCE": "true", "VOTE_BACKENDS": "fast=memory"}},
+		{"Memory without single instance", map[string]string{"VOTE_BACKEND_FAST": "memory"}},
+		{"SQLite without single instance", map[string]string{"VOTE_SQLITE_FILE": "vote.db"}},
+		{"Additional memory without single instance", map[string]string{"VOTE_BACKENDS": "other=memory"}},
+		{"Memory mirror without single instance", map[string]string{"VOTE_BACKEND_FAST_MIRROR": "memory"}},
+		{"Mirror into fast backend", map[string]string{"VOTE_BACKEND_FAST_MIRROR": "redis"}},
+		{"Invalid mirror lag", map[string]string{"VOTE_BACKEND_FAST_MIRROR": "postgres", "VOTE_BACKEND_FAST_MIRROR_MAX_LAG": "0"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := backend.Build(environment.ForTests(tt.env)); err == nil {
+				t.Errorf("Build returned no error")
+			}
+		})
+	}
+}
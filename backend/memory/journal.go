@@ -0,0 +1,362 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	entryStart    = "start"
+	entryVote     = "vote"
+	entryRevote   = "revote"
+	entryStop     = "stop"
+	entryClear    = "clear"
+	entryClearAll = "clear_all"
+
+	// The following entries are only written when the journal is compacted.
+	entryVoted     = "voted"
+	entryObject    = "object"
+	entryRevoteKey = "revote_key"
+)
+
+// journalEntry is one line in the journal.
+type journalEntry struct {
+	Type   string `json:"type"`
+	PollID int    `json:"poll_id,omitempty"`
+	UserID int    `json:"user_id,omitempty"`
+	Key    uint64 `json:"key,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// NewWithJournal initializes a memory.Backend that writes all changes to an
+// append-only journal in the given file.
+//
+// If the file exists, all events in it are replayed. So after a crash or a
+// restart, the backend has the same state as before.
+//
+// Each entry is synced to disk before the vote is accepted. While a poll is
+// running, the journal contains the user ids together with the vote objects
+// in the order, in which the users have voted. When a poll is stopped or
+// cleared, the journal is compacted. Afterwards it only contains the state of
+// all polls without this link.
+func NewWithJournal(file string) (*Backend, error) {
+	b := New()
+	b.journalFile = file
+
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	if err := b.replay(content); err != nil {
+		return nil, fmt.Errorf("replay journal: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.compactJournal(); err != nil {
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return b, nil
+}
+
+// Close closes the journal file.
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.journal == nil {
+		return nil
+	}
+
+	err := b.journal.Close()
+	b.journal = nil
+	return err
+}
+
+// replay applies all entries from the content of a journal.
+//
+// The last line is ignored, if it does not end with a newline or if it can not
+// be decoded. This happens, if the service crashed while the entry was
+// written. The vote of this entry was never accepted. The compaction after the
+// replay removes the line from the journal.
+func (b *Backend) replay(content []byte) error {
+	lines := bytes.Split(content, []byte("\n"))
+
+	// The last element is either empty or an incomplete entry.
+	lines = lines[:len(lines)-1]
+
+	for i, line := range lines {
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("decoding entry in line %d: %w", i+1, err)
+		}
+
+		if err := b.apply(entry); err != nil {
+			return fmt.Errorf("applying entry in line %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// save writes the entries to the journal and applies them to the memory.
+//
+// All entries are written and synced together. If this fails, the journal is
+// truncated to its size before the write, so it does not contain a torn line.
+// If the journal is broken, no entry is applied, so no vote is accepted, that
+// is not on disk.
+//
+// It has to be called with a locked mutex.
+func (b *Backend) save(entries ...journalEntry) error {
+	if b.journalFile != "" {
+		if b.journal == nil {
+			return fmt.Errorf("journal is not open")
+		}
+
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("encoding journal entry: %w", err)
+			}
+		}
+
+		offset, err := b.journal.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf("finding end of journal: %w", err)
+		}
+
+		if err := writeJournal(b.journal, buf.Bytes()); err != nil {
+			if truncErr := b.journal.Truncate(offset); truncErr != nil {
+				// The journal contains a torn line. Close it, so no other
+				// entry is written after it.
+				b.journal.Close()
+				b.journal = nil
+				return fmt.Errorf("%w, truncating journal: %w", err, truncErr)
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := b.apply(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJournal appends the content to the journal and syncs it to disk.
+func writeJournal(journal *os.File, content []byte) error {
+	if _, err := journal.Write(content); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+
+	if err := journal.Sync(); err != nil {
+		return fmt.Errorf("syncing journal: %w", err)
+	}
+	return nil
+}
+
+// apply changes the memory with the journal entry.
+//
+// It has to be called with a locked mutex.
+func (b *Backend) apply(entry journalEntry) error {
+	pollID := entry.PollID
+
+	switch entry.Type {
+	case entryStart:
+		if b.state[pollID] == pollStateUnknown {
+			b.state[pollID] = pollStateStarted
+			b.config[pollID] = entry.Data
+		}
+
+	case entryVote:
+		b.addVoted(pollID, entry.UserID)
+		b.addObject(pollID, entry.Key, entry.Data)
+
+	case entryRevote:
+		b.addVoted(pollID, entry.UserID)
+		b.addRevoteKey(pollID, entry.UserID, entry.Key)
+		b.addObject(pollID, entry.Key, entry.Data)
+
+	case entryVoted:
+		b.addVoted(pollID, entry.UserID)
+
+	case entryObject:
+		b.addObject(pollID, entry.Key, entry.Data)
+
+	case entryRevoteKey:
+		b.addRevoteKey(pollID, entry.UserID, entry.Key)
+
+	case entryStop:
+		b.state[pollID] = pollStateStopped
+		delete(b.revoteKey, pollID)
+
+	case entryClear:
+		delete(b.voted, pollID)
+		delete(b.objects, pollID)
+		delete(b.state, pollID)
+		delete(b.config, pollID)
+		delete(b.revoteKey, pollID)
+
+	case entryClearAll:
+		b.voted = make(map[int]map[int]struct{})
+		b.objects = make(map[int]map[uint64][]byte)
+		b.state = make(map[int]int)
+		b.config = make(map[int][]byte)
+		b.revoteKey = make(map[int]map[int]uint64)
+
+	default:
+		return fmt.Errorf("unknown journal entry %q", entry.Type)
+	}
+
+	return nil
+}
+
+func (b *Backend) addVoted(pollID, userID int) {
+	if b.voted[pollID] == nil {
+		b.voted[pollID] = make(map[int]struct{})
+	}
+	b.voted[pollID][userID] = struct{}{}
+}
+
+func (b *Backend) addObject(pollID int, key uint64, object []byte) {
+	if b.objects[pollID] == nil {
+		b.objects[pollID] = make(map[uint64][]byte)
+	}
+	b.objects[pollID][key] = object
+}
+
+func (b *Backend) addRevoteKey(pollID, userID int, key uint64) {
+	if b.revoteKey[pollID] == nil {
+		b.revoteKey[pollID] = make(map[int]uint64)
+	}
+	b.revoteKey[pollID][userID] = key
+}
+
+// compactJournal replaces the journal with the current state of all polls.
+//
+// The user ids and the vote objects are written in different entries, ordered
+// by the user id and the random key. So the compacted journal does not tell
+// how and in which order the users have voted. Only for running polls that
+// use Revote, the key of the vote object is saved for each user.
+//
+// It has to be called with a locked mutex.
+func (b *Backend) compactJournal() error {
+	if b.journalFile == "" {
+		return nil
+	}
+
+	pollIDs := make([]int, 0, len(b.state))
+	for pollID := range b.state {
+		pollIDs = append(pollIDs, pollID)
+	}
+	sort.Ints(pollIDs)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, pollID := range pollIDs {
+		entries := []journalEntry{{Type: entryStart, PollID: pollID, Data: b.config[pollID]}}
+
+		for _, userID := range sortedKeys(b.voted[pollID]) {
+			entries = append(entries, journalEntry{Type: entryVoted, PollID: pollID, UserID: userID})
+		}
+
+		for _, key := range sortedKeys(b.objects[pollID]) {
+			entries = append(entries, journalEntry{Type: entryObject, PollID: pollID, Key: key, Data: b.objects[pollID][key]})
+		}
+
+		for _, userID := range sortedKeys(b.revoteKey[pollID]) {
+			entries = append(entries, journalEntry{Type: entryRevoteKey, PollID: pollID, UserID: userID, Key: b.revoteKey[pollID][userID]})
+		}
+
+		if b.state[pollID] == pollStateStopped {
+			entries = append(entries, journalEntry{Type: entryStop, PollID: pollID})
+		}
+
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("encoding journal entry: %w", err)
+			}
+		}
+	}
+
+	tmpFile := b.journalFile + ".tmp"
+	if err := writeSynced(tmpFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("writing compacted journal: %w", err)
+	}
+
+	// The new journal is opened before the rename. So the old journal is
+	// only replaced, when the new one can be used. Until then, all entries
+	// are written to the old journal.
+	journal, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("open compacted journal: %w", err)
+	}
+
+	if err := os.Rename(tmpFile, b.journalFile); err != nil {
+		journal.Close()
+		os.Remove(tmpFile)
+		return fmt.Errorf("replacing journal: %w", err)
+	}
+
+	// Sync the directory, so the rename survives a crash.
+	if dir, err := os.Open(filepath.Dir(b.journalFile)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	if b.journal != nil {
+		// The old journal is already replaced. An error on close can not
+		// lose an entry, because each entry was synced.
+		b.journal.Close()
+	}
+	b.journal = journal
+
+	return nil
+}
+
+// writeSynced writes the content to a new file and syncs it to disk.
+func writeSynced(file string, content []byte) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing file: %w", err)
+	}
+
+	return f.Close()
+}
+
+// sortedKeys returns the keys of a map in ascending order.
+func sortedKeys[K int | uint64, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
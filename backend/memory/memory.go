@@ -1,11 +1,19 @@
 // Package memory implements the vote.Backend interface.
 //
-// All data are saved in memory.
+// All data are saved in memory. The vote objects are saved with a random key
+// and returned in the order of this key. So the order of the vote objects does
+// not tell in which order the users have voted.
+//
+// Optionally, all changes are written to a journal file, so the data survives
+// a restart. See NewWithJournal.
 package memory
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"os"
 	"sort"
 	"sync"
 	"testing"
@@ -21,16 +29,29 @@ const (
 type Backend struct {
 	mu      sync.Mutex
 	voted   map[int]map[int]struct{}
-	objects map[int][][]byte
+	objects map[int]map[uint64][]byte
 	state   map[int]int
+	config  map[int][]byte
+
+	// revoteKey is the key of the vote object of each user in objects. It is
+	// only set for polls that use Revote and is removed on stop.
+	revoteKey map[int]map[int]uint64
+
+	// journal is the open journal file. It is nil, if the backend was created
+	// without a journal.
+	journal     *os.File
+	journalFile string
 }
 
 // New initializes a new memory.Backend.
 func New() *Backend {
 	b := Backend{
 		voted:   make(map[int]map[int]struct{}),
-		objects: make(map[int][][]byte),
+		objects: make(map[int]map[uint64][]byte),
 		state:   make(map[int]int),
+		config:  make(map[int][]byte),
+
+		revoteKey: make(map[int]map[int]uint64),
 	}
 	return &b
 }
@@ -40,34 +61,77 @@ func (b *Backend) String() string {
 }
 
 // Start opens opens a poll.
-func (b *Backend) Start(ctx context.Context, pollID int) error {
+func (b *Backend) Start(ctx context.Context, pollID int, config []byte) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.state[pollID] == pollStateStopped {
+	if b.state[pollID] != pollStateUnknown {
 		return nil
 	}
-	b.state[pollID] = pollStateStarted
-	return nil
+
+	return b.save(journalEntry{Type: entryStart, PollID: pollID, Data: config})
+}
+
+// Config returns the config of a poll.
+func (b *Backend) Config(ctx context.Context, pollID int) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state[pollID] == pollStateUnknown {
+		return nil, doesNotExistError{fmt.Errorf("Poll does not exist")}
+	}
+
+	return b.config[pollID], nil
 }
 
 // Stop stopps a poll.
 func (b *Backend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	objects, userIDs, _, err := b.StopChecked(ctx, pollID)
+	return objects, userIDs, err
+}
+
+// StopChecked stopps a poll like Stop and tells, if the poll was already
+// stopped before.
+func (b *Backend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.state[pollID] == pollStateUnknown {
-		return nil, nil, doesNotExistError{fmt.Errorf("Poll does not exist")}
+		return nil, nil, false, doesNotExistError{fmt.Errorf("Poll does not exist")}
 	}
 
-	b.state[pollID] = pollStateStopped
+	// The journal is only changed, when the poll is stopped the first time.
+	stoppedBefore := b.state[pollID] == pollStateStopped
+	if !stoppedBefore {
+		if err := b.save(journalEntry{Type: entryStop, PollID: pollID}); err != nil {
+			return nil, nil, false, err
+		}
+
+		// Remove the link between the users and their vote objects from
+		// revotes and the voting order from the journal.
+		if err := b.compactJournal(); err != nil {
+			return nil, nil, false, err
+		}
+	}
 
 	userIDs := make([]int, 0, len(b.voted[pollID]))
 	for id := range b.voted[pollID] {
 		userIDs = append(userIDs, id)
 	}
 	sort.Ints(userIDs)
-	return b.objects[pollID], userIDs, nil
+
+	keys := make([]uint64, 0, len(b.objects[pollID]))
+	for key := range b.objects[pollID] {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	objects := make([][]byte, len(keys))
+	for i, key := range keys {
+		objects[i] = b.objects[pollID][key]
+	}
+
+	return objects, userIDs, stoppedBefore, nil
 }
 
 // Vote saves a vote.
@@ -83,17 +147,96 @@ func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byt
 		return stoppedError{fmt.Errorf("poll is stopped")}
 	}
 
-	if b.voted[pollID] == nil {
-		b.voted[pollID] = make(map[int]struct{})
-	}
-
 	if _, ok := b.voted[pollID][userID]; ok {
 		return doubleVoteError{fmt.Errorf("user has already voted")}
 	}
 
-	b.voted[pollID][userID] = struct{}{}
-	b.objects[pollID] = append(b.objects[pollID], object)
-	return nil
+	key, err := b.newKey(pollID)
+	if err != nil {
+		return fmt.Errorf("creating key for vote object: %w", err)
+	}
+
+	return b.save(journalEntry{Type: entryVote, PollID: pollID, UserID: userID, Key: key, Data: object})
+}
+
+// Revote saves a vote. If the user has already voted, the old vote is
+// replaced.
+func (b *Backend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state[pollID] == pollStateUnknown {
+		return doesNotExistError{fmt.Errorf("poll is not started")}
+	}
+
+	if b.state[pollID] == pollStateStopped {
+		return stoppedError{fmt.Errorf("poll is stopped")}
+	}
+
+	key, ok := b.revoteKey[pollID][userID]
+	if !ok {
+		var err error
+		key, err = b.newKey(pollID)
+		if err != nil {
+			return fmt.Errorf("creating key for vote object: %w", err)
+		}
+	}
+
+	return b.save(journalEntry{Type: entryRevote, PollID: pollID, UserID: userID, Key: key, Data: object})
+}
+
+// Import starts a poll with the given users and vote objects.
+func (b *Backend) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state[pollID] != pollStateUnknown {
+		return fmt.Errorf("poll %d already exists", pollID)
+	}
+
+	entries := []journalEntry{{Type: entryStart, PollID: pollID, Data: config}}
+	for _, userID := range userIDs {
+		entries = append(entries, journalEntry{Type: entryVoted, PollID: pollID, UserID: userID})
+	}
+
+	keys := make(map[uint64]struct{}, len(objects))
+	for _, object := range objects {
+		// newKey only checks the saved objects and not the new ones.
+		var key uint64
+		for {
+			var err error
+			key, err = b.newKey(pollID)
+			if err != nil {
+				return fmt.Errorf("creating key for vote object: %w", err)
+			}
+
+			if _, exists := keys[key]; !exists {
+				break
+			}
+		}
+		keys[key] = struct{}{}
+
+		entries = append(entries, journalEntry{Type: entryObject, PollID: pollID, Key: key, Data: object})
+	}
+
+	return b.save(entries...)
+}
+
+// newKey returns a random key for a new vote object of a poll.
+//
+// It has to be called with a locked mutex.
+func (b *Backend) newKey(pollID int) (uint64, error) {
+	for {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, fmt.Errorf("reading random bytes: %w", err)
+		}
+
+		key := binary.LittleEndian.Uint64(buf[:])
+		if _, exists := b.objects[pollID][key]; !exists {
+			return key, nil
+		}
+	}
 }
 
 // Clear removes all data for a poll.
@@ -101,10 +244,11 @@ func (b *Backend) Clear(ctx context.Context, pollID int) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	delete(b.voted, pollID)
-	delete(b.objects, pollID)
-	delete(b.state, pollID)
-	return nil
+	if err := b.save(journalEntry{Type: entryClear, PollID: pollID}); err != nil {
+		return err
+	}
+
+	return b.compactJournal()
 }
 
 // ClearAll removes all data for all polls.
@@ -112,10 +256,11 @@ func (b *Backend) ClearAll(ctx context.Context) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.voted = make(map[int]map[int]struct{})
-	b.objects = make(map[int][][]byte)
-	b.state = make(map[int]int)
-	return nil
+	if err := b.save(journalEntry{Type: entryClearAll}); err != nil {
+		return err
+	}
+
+	return b.compactJournal()
 }
 
 // Voted returns for all polls, which users have voted.
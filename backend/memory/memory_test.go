@@ -1,6 +1,11 @@
 package memory_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/OpenSlides/openslides-vote-service/backend/memory"
@@ -12,3 +17,231 @@ func TestBackend(t *testing.T) {
 
 	test.Backend(t, m)
 }
+
+func TestBackendWithJournal(t *testing.T) {
+	m, err := memory.NewWithJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatalf("NewWithJournal returned unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	test.Backend(t, m)
+}
+
+func TestJournalReplay(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "journal")
+
+	m, err := memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal returned unexpected error: %v", err)
+	}
+
+	m.Start(ctx, 1, []byte("config"))
+	m.Vote(ctx, 1, 5, []byte("vote of 5"))
+	m.Start(ctx, 2, nil)
+	m.Revote(ctx, 2, 6, []byte("first vote of 6"))
+	m.Start(ctx, 3, nil)
+	m.Vote(ctx, 3, 7, []byte("vote of 7"))
+	m.Stop(ctx, 3)
+	m.Start(ctx, 4, nil)
+	m.Clear(ctx, 4)
+	m.Close()
+
+	// Simulate a crash while an entry was written.
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	f.WriteString(`{"type":"vote","poll_id":1,"user_id":8`)
+	f.Close()
+
+	m, err = memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal after restart returned unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	voted, err := m.Voted(ctx)
+	if err != nil {
+		t.Fatalf("Voted returned unexpected error: %v", err)
+	}
+
+	expectVoted := map[int][]int{1: {5}, 2: {6}, 3: {7}}
+	if !reflect.DeepEqual(voted, expectVoted) {
+		t.Errorf("Voted returned %v, expected %v", voted, expectVoted)
+	}
+
+	config, err := m.Config(ctx, 1)
+	if err != nil || string(config) != "config" {
+		t.Errorf("Config returned %q, %v, expected config", config, err)
+	}
+
+	if err := m.Vote(ctx, 3, 8, []byte("vote")); err == nil {
+		t.Errorf("Vote on stopped poll returned no error")
+	}
+
+	if err := m.Revote(ctx, 2, 6, []byte("second vote of 6")); err != nil {
+		t.Fatalf("Revote returned unexpected error: %v", err)
+	}
+
+	objects, _, err := m.Stop(ctx, 2)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if len(objects) != 1 || string(objects[0]) != "second vote of 6" {
+		t.Errorf("Got objects %q, expected the second vote", objects)
+	}
+
+	objects, _, err = m.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if len(objects) != 1 || string(objects[0]) != "vote of 5" {
+		t.Errorf("Got objects %q, expected the vote of user 5", objects)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading journal: %v", err)
+	}
+
+	if strings.Contains(string(content), `"type":"vote"`) || strings.Contains(string(content), `"type":"revote"`) {
+		t.Errorf("Journal contains votes with user ids after stop:\n%s", content)
+	}
+}
+
+func TestJournalTornLine(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "journal")
+
+	m, err := memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal returned unexpected error: %v", err)
+	}
+
+	m.Start(ctx, 1, nil)
+	m.Vote(ctx, 1, 5, []byte("vote of 5"))
+	m.Close()
+
+	// Simulate a crash, that left garbage with a newline at the end of the
+	// journal.
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	f.WriteString("{\"type\":\"vote\",\"poll\x00\x00\n")
+	f.Close()
+
+	m, err = memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal after restart returned unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	voted, err := m.Voted(ctx)
+	if err != nil {
+		t.Fatalf("Voted returned unexpected error: %v", err)
+	}
+
+	if expect := map[int][]int{1: {5}}; !reflect.DeepEqual(voted, expect) {
+		t.Errorf("Voted returned %v, expected %v", voted, expect)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading journal: %v", err)
+	}
+
+	if strings.Contains(string(content), "\x00") {
+		t.Errorf("Journal still contains the torn line")
+	}
+}
+
+func TestJournalStopTwice(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "journal")
+
+	m, err := memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal returned unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	m.Start(ctx, 1, nil)
+	m.Vote(ctx, 1, 5, []byte("vote of 5"))
+
+	if _, _, err := m.Stop(ctx, 1); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	before, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("stat journal: %v", err)
+	}
+
+	if _, _, err := m.Stop(ctx, 1); err != nil {
+		t.Fatalf("Second stop returned unexpected error: %v", err)
+	}
+
+	after, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("stat journal: %v", err)
+	}
+
+	if !os.SameFile(before, after) || before.Size() != after.Size() {
+		t.Errorf("Second stop changed the journal")
+	}
+}
+
+func TestJournalCompactionFails(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "journal")
+
+	m, err := memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal returned unexpected error: %v", err)
+	}
+
+	m.Start(ctx, 1, nil)
+	m.Start(ctx, 2, nil)
+
+	// The compacted journal can not be written, if a directory has its name.
+	if err := os.Mkdir(file+".tmp", 0o700); err != nil {
+		t.Fatalf("creating directory: %v", err)
+	}
+
+	if _, _, err := m.Stop(ctx, 1); err == nil {
+		t.Errorf("Stop returned no error, expected the error from the compaction")
+	}
+
+	if err := m.Vote(ctx, 2, 5, []byte("vote of 5")); err != nil {
+		t.Fatalf("Vote after failed compaction returned unexpected error: %v", err)
+	}
+	m.Close()
+
+	if err := m.Vote(ctx, 2, 6, []byte("vote of 6")); err == nil {
+		t.Errorf("Vote with a closed journal returned no error")
+	}
+
+	if err := os.Remove(file + ".tmp"); err != nil {
+		t.Fatalf("removing directory: %v", err)
+	}
+
+	m, err = memory.NewWithJournal(file)
+	if err != nil {
+		t.Fatalf("NewWithJournal after restart returned unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	voted, err := m.Voted(ctx)
+	if err != nil {
+		t.Fatalf("Voted returned unexpected error: %v", err)
+	}
+
+	if expect := map[int][]int{2: {5}}; !reflect.DeepEqual(voted, expect) {
+		t.Errorf("Voted returned %v, expected %v", voted, expect)
+	}
+}
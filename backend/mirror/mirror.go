@@ -0,0 +1,522 @@
+// Package mirror implements the vote.Backend interface by mirroring a primary
+// backend into a secondary backend.
+//
+// All votes are saved in the primary backend. Each successful vote is written
+// to the secondary backend asynchronously. So the secondary backend is only a
+// few votes behind the primary backend. If the primary backend loses a poll,
+// for example because redis was restarted without persistence, the poll is
+// continued and stopped with the data from the secondary backend.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-vote-service/log"
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+// mirrorAttempts is the number of times a vote is written to the secondary
+// backend before it is given up.
+const mirrorAttempts = 3
+
+// secondaryStopTimeout is the time to stop a poll in the secondary backend,
+// after the request to stop it was canceled.
+const secondaryStopTimeout = 5 * time.Second
+
+// mirrorJob is a vote that has to be written to the secondary backend.
+//
+// If done is set, it is not a vote but a marker. It is closed, when all jobs
+// before it are done.
+type mirrorJob struct {
+	pollID int
+	userID int
+	object []byte
+	revote bool
+	done   chan struct{}
+}
+
+// Backend saves the votes in a primary backend and mirrors them into a
+// secondary backend.
+//
+// Has to be initialized with New() and needs a running Run().
+type Backend struct {
+	primary   vote.Backend
+	secondary vote.Backend
+	queue     chan mirrorJob
+
+	mu        sync.Mutex
+	recovered map[int]bool // recovered holds the polls, that only exist in the secondary backend.
+}
+
+// New initializes a mirror backend.
+//
+// maxLag is the number of votes, that can wait to be written to the secondary
+// backend. If more votes are waiting, new votes block until there is space in
+// the queue.
+func New(primary, secondary vote.Backend, maxLag int) *Backend {
+	return &Backend{
+		primary:   primary,
+		secondary: secondary,
+		queue:     make(chan mirrorJob, maxLag),
+		recovered: make(map[int]bool),
+	}
+}
+
+// Run writes the votes to the secondary backend until the context is
+// canceled.
+func (b *Backend) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-b.queue:
+			if job.done != nil {
+				close(job.done)
+				continue
+			}
+
+			b.mirror(ctx, job)
+		}
+	}
+}
+
+// mirror writes one vote to the secondary backend.
+//
+// If the poll does not exist or is stopped in the secondary backend, the vote
+// is dropped. Other errors are retried a few times.
+func (b *Backend) mirror(ctx context.Context, job mirrorJob) {
+	for attempt := 1; ; attempt++ {
+		err := b.secondaryVote(ctx, job)
+		if err == nil || isDoubleVote(err) || isStopped(err) || isDoesNotExist(err) {
+			return
+		}
+
+		if attempt == mirrorAttempts {
+			log.Info("Error: vote on poll %d could not be mirrored to %s: %v", job.pollID, b.secondary, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond * time.Duration(attempt)):
+		}
+	}
+}
+
+func (b *Backend) secondaryVote(ctx context.Context, job mirrorJob) error {
+	if job.revote {
+		return b.secondary.Revote(ctx, job.pollID, job.userID, job.object)
+	}
+	return b.secondary.Vote(ctx, job.pollID, job.userID, job.object)
+}
+
+// flush blocks until all votes, that are waiting in the queue, are written to
+// the secondary backend.
+func (b *Backend) flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	select {
+	case b.queue <- mirrorJob{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Backend) String() string {
+	return fmt.Sprintf("%s mirrored to %s", b.primary, b.secondary)
+}
+
+// Mirror returns the secondary backend.
+func (b *Backend) Mirror() vote.Backend {
+	return b.secondary
+}
+
+// Ping checks the connection to the primary backend.
+func (b *Backend) Ping(ctx context.Context) error {
+	pinger, ok := b.primary.(vote.Pinger)
+	if !ok {
+		return nil
+	}
+	return pinger.Ping(ctx)
+}
+
+// Start starts the poll in both backends.
+func (b *Backend) Start(ctx context.Context, pollID int, config []byte) error {
+	if err := b.primary.Start(ctx, pollID, config); err != nil {
+		return err
+	}
+
+	if err := b.secondary.Start(ctx, pollID, config); err != nil {
+		return fmt.Errorf("starting poll in mirror: %w", err)
+	}
+	return nil
+}
+
+// Import imports the poll into the primary backend and, if possible, into the
+// secondary backend.
+//
+// The primary backend has to implement the vote.Importer interface. If the
+// secondary backend can not import the poll, the votes of the poll are not
+// mirrored.
+func (b *Backend) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	primary, ok := b.primary.(vote.Importer)
+	if !ok {
+		return fmt.Errorf("backend %s can not import polls", b.primary)
+	}
+
+	if err := primary.Import(ctx, pollID, config, userIDs, objects); err != nil {
+		return err
+	}
+
+	secondary, ok := b.secondary.(vote.Importer)
+	if !ok {
+		log.Info("Poll %d is not mirrored. Backend %s can not import polls", pollID, b.secondary)
+		return nil
+	}
+
+	if err := secondary.Import(ctx, pollID, config, userIDs, objects); err != nil {
+		log.Info("Error: importing poll %d into mirror %s: %v", pollID, b.secondary, err)
+	}
+	return nil
+}
+
+// Config returns the config of the poll from the primary backend, or from the
+// secondary backend, if the primary backend does not know the poll.
+func (b *Backend) Config(ctx context.Context, pollID int) ([]byte, error) {
+	if b.isRecovered(pollID) {
+		return b.secondary.Config(ctx, pollID)
+	}
+
+	config, err := b.primary.Config(ctx, pollID)
+	if isDoesNotExist(err) {
+		if secondaryConfig, secondaryErr := b.secondary.Config(ctx, pollID); secondaryErr == nil {
+			return secondaryConfig, nil
+		}
+	}
+	return config, err
+}
+
+// Vote saves the vote in the primary backend and adds it to the queue for
+// the secondary backend.
+func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byte) error {
+	return b.vote(ctx, mirrorJob{pollID: pollID, userID: userID, object: object})
+}
+
+// Revote saves the vote like Vote but replaces an existing vote.
+func (b *Backend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	return b.vote(ctx, mirrorJob{pollID: pollID, userID: userID, object: object, revote: true})
+}
+
+func (b *Backend) vote(ctx context.Context, job mirrorJob) error {
+	if b.isRecovered(job.pollID) {
+		return b.secondaryVote(ctx, job)
+	}
+
+	var err error
+	if job.revote {
+		err = b.primary.Revote(ctx, job.pollID, job.userID, job.object)
+	} else {
+		err = b.primary.Vote(ctx, job.pollID, job.userID, job.object)
+	}
+
+	if isDoesNotExist(err) {
+		recovered, recoverErr := b.recover(ctx, job.pollID)
+		if recoverErr != nil {
+			return fmt.Errorf("recovering poll from mirror: %w", recoverErr)
+		}
+
+		if recovered {
+			return b.secondaryVote(ctx, job)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case b.queue <- job:
+	case <-ctx.Done():
+		// The vote is saved in the primary backend. So the request was
+		// successful, even if the mirror misses the vote.
+		log.Info("Error: vote on poll %d was not mirrored: %v", job.pollID, ctx.Err())
+	}
+
+	return nil
+}
+
+// recover checks, if a poll, that does not exist in the primary backend,
+// exists in the secondary backend. In this case, all further votes are saved
+// in the secondary backend.
+func (b *Backend) recover(ctx context.Context, pollID int) (bool, error) {
+	if _, err := b.secondary.Config(ctx, pollID); err != nil {
+		if isDoesNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("fetching config from mirror: %w", err)
+	}
+
+	// Votes in the queue have to be written, before the secondary backend
+	// checks for double votes.
+	if err := b.flush(ctx); err != nil {
+		return false, fmt.Errorf("waiting for mirror: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.recovered[pollID] {
+		log.Info("Poll %d does not exist in %s. Using the mirror %s", pollID, b.primary, b.secondary)
+		b.recovered[pollID] = true
+	}
+
+	return true, nil
+}
+
+func (b *Backend) isRecovered(pollID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recovered[pollID]
+}
+
+// Stop stops the poll in both backends and compares the results.
+//
+// The result of the secondary backend is only used, if the primary backend has
+// lost the poll. Other errors of the primary backend are returned. See
+// reconcile for the case, that both backends have different votes.
+func (b *Backend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	objects, userIDs, _, err := b.stop(ctx, pollID, stopBackend)
+	return objects, userIDs, err
+}
+
+// StopChecked stops the poll like Stop and tells, if the poll was already
+// stopped before. Both backends have to implement vote.StopChecker.
+func (b *Backend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
+	return b.stop(ctx, pollID, stopChecked)
+}
+
+// stopFunc stops a poll in one of the backends.
+type stopFunc func(ctx context.Context, backend vote.Backend, pollID int) ([][]byte, []int, bool, error)
+
+func stopBackend(ctx context.Context, backend vote.Backend, pollID int) ([][]byte, []int, bool, error) {
+	objects, userIDs, err := backend.Stop(ctx, pollID)
+	return objects, userIDs, false, err
+}
+
+func stopChecked(ctx context.Context, backend vote.Backend, pollID int) ([][]byte, []int, bool, error) {
+	stopper, ok := backend.(vote.StopChecker)
+	if !ok {
+		return nil, nil, false, fmt.Errorf("backend %s can not tell, if a poll was stopped before", backend)
+	}
+	return stopper.StopChecked(ctx, pollID)
+}
+
+func (b *Backend) stop(ctx context.Context, pollID int, stop stopFunc) ([][]byte, []int, bool, error) {
+	objects, userIDs, stoppedBefore, err := stop(ctx, b.primary, pollID)
+
+	// The result of the secondary backend misses the votes, that are not
+	// mirrored yet, and the votes in the queues of other instances. So it is
+	// only used, if the primary backend has lost the poll. On other errors,
+	// the poll is still running in both backends and the stop can be retried.
+	useSecondary := err != nil && (isDoesNotExist(err) || b.isRecovered(pollID))
+	if err != nil && !useSecondary {
+		return nil, nil, false, err
+	}
+
+	// After the primary backend is stopped, no new votes of this instance are
+	// added to the queue.
+	flushErr := b.flush(ctx)
+	if flushErr != nil {
+		log.Info("Error: waiting for mirror of poll %d: %v", pollID, flushErr)
+
+		// The poll has to be stopped in the secondary backend, even if the
+		// request was canceled.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), secondaryStopTimeout)
+		defer cancel()
+	}
+
+	secondaryObjects, secondaryUserIDs, secondaryStoppedBefore, secondaryErr := stop(ctx, b.secondary, pollID)
+
+	switch {
+	case useSecondary && secondaryErr != nil:
+		return nil, nil, false, err
+
+	case useSecondary:
+		if !b.isRecovered(pollID) {
+			log.Info("Poll %d does not exist in %s. Using the result from the mirror %s", pollID, b.primary, b.secondary)
+		}
+		return secondaryObjects, secondaryUserIDs, secondaryStoppedBefore, nil
+
+	case secondaryErr != nil:
+		if !isDoesNotExist(secondaryErr) {
+			log.Info("Error: stopping poll %d in mirror %s: %v", pollID, b.secondary, secondaryErr)
+		}
+		return objects, userIDs, stoppedBefore, nil
+
+	case flushErr != nil:
+		// The secondary backend misses votes. Comparing the results would
+		// only log wrong differences.
+		return objects, userIDs, stoppedBefore, nil
+	}
+
+	objects, userIDs = reconcile(pollID, objects, userIDs, secondaryObjects, secondaryUserIDs)
+	return objects, userIDs, stoppedBefore, nil
+}
+
+// reconcile compares the results from both backends and returns the result to
+// use.
+//
+// The primary backend is the source of truth. Only if the secondary backend
+// has all users of the primary backend and more, the primary backend has lost
+// votes, and the result of the secondary backend is used. The secondary
+// backend can miss votes, since it is written asynchronously and other
+// instances of the service have their own queue.
+//
+// All differences are logged.
+func reconcile(pollID int, objects [][]byte, userIDs []int, secondaryObjects [][]byte, secondaryUserIDs []int) ([][]byte, []int) {
+	missingInPrimary := difference(secondaryUserIDs, userIDs)
+	missingInSecondary := difference(userIDs, secondaryUserIDs)
+
+	switch {
+	case len(missingInPrimary) > 0 && len(missingInSecondary) == 0:
+		log.Info("Poll %d: primary backend misses the votes of %d users. Using the result of the mirror", pollID, len(missingInPrimary))
+		return secondaryObjects, secondaryUserIDs
+
+	case len(missingInPrimary) > 0 || len(missingInSecondary) > 0:
+		log.Info("Poll %d: primary backend misses the votes of %d users, mirror misses the votes of %d users. Using the result of the primary backend", pollID, len(missingInPrimary), len(missingInSecondary))
+
+	case !sameObjects(objects, secondaryObjects):
+		log.Info("Poll %d: primary backend and mirror have different vote objects. Using the result of the primary backend", pollID)
+	}
+
+	return objects, userIDs
+}
+
+// difference returns the ids in a that are not in b.
+func difference(a, b []int) []int {
+	set := make(map[int]struct{}, len(b))
+	for _, id := range b {
+		set[id] = struct{}{}
+	}
+
+	var diff []int
+	for _, id := range a {
+		if _, ok := set[id]; !ok {
+			diff = append(diff, id)
+		}
+	}
+	return diff
+}
+
+// sameObjects returns true, if both lists contain the same objects in any
+// order.
+func sameObjects(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	count := make(map[string]int, len(a))
+	for _, object := range a {
+		count[string(object)]++
+	}
+
+	for _, object := range b {
+		count[string(object)]--
+		if count[string(object)] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear removes the poll from both backends.
+func (b *Backend) Clear(ctx context.Context, pollID int) error {
+	b.mu.Lock()
+	delete(b.recovered, pollID)
+	b.mu.Unlock()
+
+	if err := b.primary.Clear(ctx, pollID); err != nil {
+		return err
+	}
+
+	if err := b.secondary.Clear(ctx, pollID); err != nil {
+		return fmt.Errorf("clearing poll in mirror: %w", err)
+	}
+	return nil
+}
+
+// ClearAll removes all data from both backends.
+func (b *Backend) ClearAll(ctx context.Context) error {
+	b.mu.Lock()
+	b.recovered = make(map[int]bool)
+	b.mu.Unlock()
+
+	if err := b.primary.ClearAll(ctx); err != nil {
+		return err
+	}
+
+	if err := b.secondary.ClearAll(ctx); err != nil {
+		return fmt.Errorf("clearing mirror: %w", err)
+	}
+	return nil
+}
+
+// Voted returns the users, that have voted, from the primary backend. For
+// recovered polls, they are read from the secondary backend.
+func (b *Backend) Voted(ctx context.Context) (map[int][]int, error) {
+	voted, err := b.primary.Voted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	recovered := make([]int, 0, len(b.recovered))
+	for pollID := range b.recovered {
+		recovered = append(recovered, pollID)
+	}
+	b.mu.Unlock()
+
+	if len(recovered) == 0 {
+		return voted, nil
+	}
+
+	secondaryVoted, err := b.secondary.Voted(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching voted from mirror: %w", err)
+	}
+
+	for _, pollID := range recovered {
+		if userIDs, ok := secondaryVoted[pollID]; ok {
+			voted[pollID] = userIDs
+		}
+	}
+	return voted, nil
+}
+
+func isDoesNotExist(err error) bool {
+	var errNotExist interface{ DoesNotExist() }
+	return errors.As(err, &errNotExist)
+}
+
+func isDoubleVote(err error) bool {
+	var errDoubleVote interface{ DoubleVote() }
+	return errors.As(err, &errDoubleVote)
+}
+
+func isStopped(err error) bool {
+	var errStopped interface{ Stopped() }
+	return errors.As(err, &errStopped)
+}
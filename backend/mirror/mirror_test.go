@@ -0,0 +1,229 @@
+package mirror_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+	"github.com/OpenSlides/openslides-vote-service/backend/mirror"
+	"github.com/OpenSlides/openslides-vote-service/backend/test"
+)
+
+func TestImplementBackendInterface(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mirror.New(memory.New(), memory.New(), 10)
+	go m.Run(ctx)
+
+	test.Backend(t, m)
+}
+
+func TestMirror(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := memory.New()
+	secondary := memory.New()
+	m := mirror.New(primary, secondary, 10)
+	go m.Run(ctx)
+
+	if err := m.Start(ctx, 1, []byte("config")); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	for _, userID := range []int{1, 2} {
+		if err := m.Vote(ctx, 1, userID, []byte("vote")); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+	}
+
+	t.Run("Votes are mirrored", func(t *testing.T) {
+		// Stop in the mirror waits for the queue.
+		if _, _, err := m.Stop(ctx, 2); err == nil {
+			t.Fatalf("Stop on unknown poll did not return an error")
+		}
+
+		secondary.AssertUserHasVoted(t, 1, 1)
+		secondary.AssertUserHasVoted(t, 1, 2)
+	})
+
+	t.Run("Primary loses the poll", func(t *testing.T) {
+		primary.ClearAll(ctx)
+
+		config, err := m.Config(ctx, 1)
+		if err != nil {
+			t.Fatalf("Config returned unexpected error: %v", err)
+		}
+
+		if string(config) != "config" {
+			t.Errorf("Config returned %q, expected %q", config, "config")
+		}
+
+		err = m.Vote(ctx, 1, 1, []byte("vote"))
+		var errDoubleVote interface{ DoubleVote() }
+		if !errors.As(err, &errDoubleVote) {
+			t.Errorf("Second vote returned %v, expected a DoubleVote error", err)
+		}
+
+		if err := m.Vote(ctx, 1, 3, []byte("vote")); err != nil {
+			t.Fatalf("Vote after the loss returned unexpected error: %v", err)
+		}
+
+		objects, userIDs, err := m.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		sort.Ints(userIDs)
+		if len(objects) != 3 || len(userIDs) != 3 || userIDs[0] != 1 || userIDs[2] != 3 {
+			t.Errorf("Stop returned %d objects from %v, expected 3 objects from [1 2 3]", len(objects), userIDs)
+		}
+	})
+}
+
+func TestMirrorReconcile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, tt := range []struct {
+		name           string
+		primaryVotes   []int
+		secondaryVotes []int
+		expect         []int
+	}{
+		{"Same votes", []int{1, 2}, []int{1, 2}, []int{1, 2}},
+		{"Mirror is behind", []int{1, 2, 3}, []int{1, 2}, []int{1, 2, 3}},
+		{"Primary lost votes", []int{1}, []int{1, 2}, []int{1, 2}},
+		{"Both differ", []int{1, 2}, []int{1, 3}, []int{1, 2}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := memory.New()
+			secondary := memory.New()
+			m := mirror.New(primary, secondary, 10)
+			go m.Run(ctx)
+
+			// Write directly into the backends, to simulate the differences.
+			primary.Start(ctx, 1, nil)
+			secondary.Start(ctx, 1, nil)
+			for _, userID := range tt.primaryVotes {
+				primary.Vote(ctx, 1, userID, []byte("vote"))
+			}
+			for _, userID := range tt.secondaryVotes {
+				secondary.Vote(ctx, 1, userID, []byte("vote"))
+			}
+
+			_, userIDs, err := m.Stop(ctx, 1)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			sort.Ints(userIDs)
+			if len(userIDs) != len(tt.expect) {
+				t.Fatalf("Stop returned %v, expected %v", userIDs, tt.expect)
+			}
+
+			for i := range userIDs {
+				if userIDs[i] != tt.expect[i] {
+					t.Errorf("Stop returned %v, expected %v", userIDs, tt.expect)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMirrorImport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := memory.New()
+	secondary := memory.New()
+	m := mirror.New(primary, secondary, 10)
+	go m.Run(ctx)
+
+	if err := m.Import(ctx, 1, []byte("config"), []int{1, 2}, [][]byte{[]byte("vote"), []byte("vote")}); err != nil {
+		t.Fatalf("Import returned unexpected error: %v", err)
+	}
+
+	if err := m.Vote(ctx, 1, 3, []byte("vote")); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	if _, _, err := m.Stop(ctx, 1); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	for _, userID := range []int{1, 2, 3} {
+		primary.AssertUserHasVoted(t, 1, userID)
+		secondary.AssertUserHasVoted(t, 1, userID)
+	}
+}
+
+// failingStop is a memory backend, that can not stop a poll.
+type failingStop struct {
+	*memory.Backend
+}
+
+func (b failingStop) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	return nil, nil, errors.New("connection reset")
+}
+
+func TestMirrorStopErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("Primary fails", func(t *testing.T) {
+		secondary := memory.New()
+		m := mirror.New(failingStop{memory.New()}, secondary, 10)
+		go m.Run(ctx)
+
+		if err := m.Start(ctx, 1, nil); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if err := m.Vote(ctx, 1, 1, []byte("vote")); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		if _, _, err := m.Stop(ctx, 1); err == nil {
+			t.Fatalf("Stop returned no error, expected the error from the primary backend")
+		}
+
+		if err := secondary.Vote(ctx, 1, 2, []byte("vote")); err != nil {
+			t.Errorf("Poll in the mirror was stopped: %v", err)
+		}
+	})
+
+	t.Run("Request canceled", func(t *testing.T) {
+		primary := memory.New()
+		secondary := memory.New()
+
+		// Without Run, waiting for the queue fails.
+		m := mirror.New(primary, secondary, 10)
+
+		primary.Start(ctx, 1, nil)
+		secondary.Start(ctx, 1, nil)
+		primary.Vote(ctx, 1, 1, []byte("vote"))
+
+		canceledCtx, cancelRequest := context.WithCancel(ctx)
+		cancelRequest()
+
+		_, userIDs, err := m.Stop(canceledCtx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(userIDs) != 1 || userIDs[0] != 1 {
+			t.Errorf("Stop returned %v, expected [1]", userIDs)
+		}
+
+		err = secondary.Vote(ctx, 1, 2, []byte("vote"))
+		var errStopped interface{ Stopped() }
+		if !errors.As(err, &errStopped) {
+			t.Errorf("Vote on the mirror returned %v, expected a Stopped error", err)
+		}
+	})
+}
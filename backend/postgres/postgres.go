@@ -1,13 +1,15 @@
 package postgres
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
 	_ "embed" // Needed for file embedding
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"sort"
+	mathrand "math/rand/v2"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/OpenSlides/openslides-vote-service/log"
@@ -19,11 +21,38 @@ import (
 //go:embed schema.sql
 var schema string
 
+// batchWindow is the time, the first vote of a batch waits for more votes.
+const batchWindow = 100 * time.Millisecond
+
+// batchTimeout is the time to write a batch of votes.
+const batchTimeout = 10 * time.Second
+
 // Backend holds the state of the backend.
 //
 // Has to be initializes with New().
 type Backend struct {
 	pool *pgxpool.Pool
+
+	batchMu sync.Mutex
+	batch   *voteBatch // batch collects the votes, that are written next.
+}
+
+// voteBatch is a list of votes, that are written in one transaction.
+//
+// done is closed, when the batch was written. Afterwards, the err of each job
+// is set.
+type voteBatch struct {
+	jobs []*voteJob
+	done chan struct{}
+}
+
+// voteJob is one vote of a batch.
+type voteJob struct {
+	pollID int
+	userID int
+	object []byte
+	revote bool
+	err    error
 }
 
 // New creates a new connection pool.
@@ -68,6 +97,11 @@ func (b *Backend) Wait(ctx context.Context) {
 	}
 }
 
+// Ping checks the connection to postgres.
+func (b *Backend) Ping(ctx context.Context) error {
+	return b.pool.Ping(ctx)
+}
+
 // Migrate creates the database schema.
 func (b *Backend) Migrate(ctx context.Context) error {
 	if _, err := b.pool.Exec(ctx, schema); err != nil {
@@ -82,147 +116,348 @@ func (b *Backend) Close() {
 }
 
 // Start starts a poll.
-func (b *Backend) Start(ctx context.Context, pollID int) error {
-	sql := `INSERT INTO vote.poll (id, stopped) VALUES ($1, false) ON CONFLICT DO NOTHING;
+func (b *Backend) Start(ctx context.Context, pollID int, config []byte) error {
+	sql := `INSERT INTO vote.poll (id, stopped, config) VALUES ($1, false, $2) ON CONFLICT DO NOTHING;
 	`
-	log.Debug("SQL: `%s` (values: %d)", sql, pollID)
-	if _, err := b.pool.Exec(ctx, sql, pollID); err != nil {
+	log.Debug("SQL: `%s` (values: %d, [config])", sql, pollID)
+	if _, err := b.pool.Exec(ctx, sql, pollID, config); err != nil {
 		return fmt.Errorf("insert poll: %w", err)
 	}
 	return nil
 }
 
+// Config returns the config of a poll.
+func (b *Backend) Config(ctx context.Context, pollID int) ([]byte, error) {
+	sql := `SELECT config FROM vote.poll WHERE id = $1;`
+	log.Debug("SQL: `%s` (values: %d)", sql, pollID)
+
+	var config []byte
+	if err := b.pool.QueryRow(ctx, sql, pollID).Scan(&config); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, doesNotExistError{fmt.Errorf("unknown poll")}
+		}
+		return nil, fmt.Errorf("fetching poll config: %w", err)
+	}
+
+	return config, nil
+}
+
 // Vote adds a vote to a poll.
 //
-// If an transaction error happens, the vote is saved again. This is done until
-// either the vote is saved or the given context is canceled.
+// The vote is saved together with the other votes of its batch. See
+// writeBatch.
 func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byte) error {
-	return continueOnTransactionError(ctx, func() error {
-		return b.voteOnce(ctx, pollID, userID, object)
-	})
+	return b.vote(ctx, &voteJob{pollID: pollID, userID: userID, object: object})
 }
 
-// voteOnce tries to add the vote once.
-func (b *Backend) voteOnce(ctx context.Context, pollID int, userID int, object []byte) (err error) {
-	log.Debug("SQL: Begin transaction for vote")
+// Revote adds a vote to a poll. If the user has already voted, the old vote is
+// replaced.
+//
+// The user id is saved with the vote object until the poll is stopped.
+func (b *Backend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	return b.vote(ctx, &voteJob{pollID: pollID, userID: userID, object: object, revote: true})
+}
+
+// vote adds the vote to the current batch and waits until the batch is
+// written.
+//
+// The first vote of a batch waits batchWindow for more votes and writes the
+// batch afterwards.
+func (b *Backend) vote(ctx context.Context, job *voteJob) error {
+	b.batchMu.Lock()
+	batch := b.batch
+	first := batch == nil
+	if first {
+		batch = &voteBatch{done: make(chan struct{})}
+		b.batch = batch
+	}
+	batch.jobs = append(batch.jobs, job)
+	b.batchMu.Unlock()
+
+	if first {
+		go func() {
+			time.Sleep(batchWindow)
+
+			b.batchMu.Lock()
+			b.batch = nil
+			b.batchMu.Unlock()
+
+			// The batch contains the votes of other requests. So it is
+			// written, even if this request is canceled.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), batchTimeout)
+			defer cancel()
+
+			err := continueOnTransactionError(ctx, func() error {
+				return b.writeBatch(ctx, batch.jobs)
+			})
+			if err != nil {
+				for _, job := range batch.jobs {
+					job.err = err
+				}
+			}
+			close(batch.done)
+		}()
+	}
+
+	select {
+	case <-batch.done:
+		return job.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeBatch saves the votes of a batch in one transaction.
+//
+// The row of a user in vote.voted and its vote object are written in the same
+// transaction. So they have the same transaction id (xmin) until the poll is
+// stopped. All votes of a batch share this id and the rows are written in a
+// random order. So the transaction id only tells, that a user has one of the
+// vote objects of the batch.
+//
+// The rows of the polls are locked with FOR SHARE. So many batches can be
+// saved at the same time, but Stop waits until they are finished. A double vote
+// is prevented by the primary key of vote.voted.
+func (b *Backend) writeBatch(ctx context.Context, jobs []*voteJob) (err error) {
+	log.Debug("SQL: Begin transaction for %d votes", len(jobs))
 	defer func() {
-		log.Debug("SQL: End transaction for vote with error: %v", err)
+		log.Debug("SQL: End transaction for votes with error: %v", err)
 	}()
 
-	err = pgx.BeginTxFunc(
-		ctx,
-		b.pool,
-		pgx.TxOptions{
-			IsoLevel: "REPEATABLE READ",
-		},
-		func(tx pgx.Tx) error {
-			sql := `SELECT stopped, user_ids FROM vote.poll	WHERE id = $1;`
-			log.Debug("SQL: `%s` (values: %d)", sql, pollID)
+	// The errors of a failed attempt are not valid for the next one.
+	for _, job := range jobs {
+		job.err = nil
+	}
+
+	err = pgx.BeginFunc(ctx, b.pool, func(tx pgx.Tx) error {
+		jobs = shuffled(jobs)
+
+		pollErr := make(map[int]error)
+		for _, job := range jobs {
+			if _, ok := pollErr[job.pollID]; ok {
+				continue
+			}
+
+			sql := "SELECT stopped FROM vote.poll WHERE id = $1 FOR SHARE;"
+			log.Debug("SQL: `%s` (values: %d)", sql, job.pollID)
 
 			var stopped bool
-			var uIDsRaw []byte
-			if err := tx.QueryRow(ctx, sql, pollID).Scan(&stopped, &uIDsRaw); err != nil {
-				if errors.Is(err, pgx.ErrNoRows) {
-					return doesNotExistError{fmt.Errorf("unknown poll")}
+			if err := tx.QueryRow(ctx, sql, job.pollID).Scan(&stopped); err != nil {
+				if !errors.Is(err, pgx.ErrNoRows) {
+					return fmt.Errorf("fetching poll data: %w", err)
 				}
-				return fmt.Errorf("fetching poll data: %w", err)
+				pollErr[job.pollID] = doesNotExistError{fmt.Errorf("unknown poll")}
+				continue
 			}
 
+			pollErr[job.pollID] = nil
 			if stopped {
-				return stoppedError{fmt.Errorf("poll is stopped")}
+				pollErr[job.pollID] = stoppedError{fmt.Errorf("poll is stopped")}
 			}
+		}
 
-			uIDs, err := userIDListFromBytes(uIDsRaw)
-			if err != nil {
-				return fmt.Errorf("parsing user ids: %w", err)
+		var saved []*voteJob
+		for _, job := range jobs {
+			if job.err = pollErr[job.pollID]; job.err != nil {
+				continue
 			}
 
-			if err := uIDs.add(int32(userID)); err != nil {
-				return fmt.Errorf("adding userID to voted users: %w", err)
+			if job.revote {
+				// The update locks the row of the user, so a second revote of
+				// the same user waits until this one is finished.
+				sql := `INSERT INTO vote.voted (poll_id, user_id) VALUES ($1, $2)
+				ON CONFLICT (poll_id, user_id) DO UPDATE SET user_id = EXCLUDED.user_id;`
+				log.Debug("SQL: `%s` (values: %d, [userID])", sql, job.pollID)
+				if _, err := tx.Exec(ctx, sql, job.pollID, job.userID); err != nil {
+					return fmt.Errorf("writing voted user: %w", err)
+				}
+
+				sql = "DELETE FROM vote.objects WHERE poll_id = $1 AND user_id = $2;"
+				log.Debug("SQL: `%s` (values: %d, [userID])", sql, job.pollID)
+				if _, err := tx.Exec(ctx, sql, job.pollID, job.userID); err != nil {
+					return fmt.Errorf("removing old vote: %w", err)
+				}
+			} else {
+				sql := "INSERT INTO vote.voted (poll_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;"
+				log.Debug("SQL: `%s` (values: %d, [userID])", sql, job.pollID)
+				result, err := tx.Exec(ctx, sql, job.pollID, job.userID)
+				if err != nil {
+					return fmt.Errorf("writing voted user: %w", err)
+				}
+
+				if result.RowsAffected() == 0 {
+					job.err = doubleVoteError{fmt.Errorf("User has already voted")}
+					continue
+				}
 			}
 
-			uIDsRaw, err = uIDs.toBytes()
-			if err != nil {
-				return fmt.Errorf("converting user ids to bytes: %w", err)
+			saved = append(saved, job)
+		}
+
+		// The vote objects are written in another order than the users.
+		for _, job := range shuffled(saved) {
+			// The user id is only saved for revotes. It is needed to find the
+			// vote object, if the user votes again.
+			var objectUserID *int
+			if job.revote {
+				objectUserID = &job.userID
 			}
 
-			sql = "UPDATE vote.poll SET user_ids = $1 WHERE id = $2;"
-			log.Debug("SQL: `%s` (values: [user_ids]), %d", sql, pollID)
-			if _, err := tx.Exec(ctx, sql, uIDsRaw, pollID); err != nil {
-				return fmt.Errorf("writing user ids: %w", err)
+			objectID, err := randomID()
+			if err != nil {
+				return fmt.Errorf("creating id for vote object: %w", err)
 			}
 
-			sql = "INSERT INTO vote.objects (poll_id, vote) VALUES ($1, $2);"
-			log.Debug("SQL: `%s` (values: %d, [vote]", sql, pollID)
-			if _, err := tx.Exec(ctx, sql, pollID, object); err != nil {
+			sql := "INSERT INTO vote.objects (id, poll_id, user_id, vote) VALUES ($1, $2, $3, $4);"
+			log.Debug("SQL: `%s` (values: [id], %d, [userID], [vote]", sql, job.pollID)
+			if _, err := tx.Exec(ctx, sql, objectID, job.pollID, objectUserID, job.object); err != nil {
 				return fmt.Errorf("writing vote: %w", err)
 			}
+		}
 
-			return nil
-		},
-	)
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("running transaction: %w", err)
 	}
 	return nil
 }
 
+// Import starts a poll with the given users and vote objects.
+func (b *Backend) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	return continueOnTransactionError(ctx, func() error {
+		return pgx.BeginFunc(ctx, b.pool, func(tx pgx.Tx) error {
+			// The poll has to be checked before it is inserted. A unique
+			// violation would be retried by continueOnTransactionError.
+			sql := "SELECT EXISTS(SELECT 1 FROM vote.poll WHERE id = $1);"
+			log.Debug("SQL: `%s` (values: %d)", sql, pollID)
+
+			var exists bool
+			if err := tx.QueryRow(ctx, sql, pollID).Scan(&exists); err != nil {
+				return fmt.Errorf("fetching poll exists: %w", err)
+			}
+
+			if exists {
+				return fmt.Errorf("poll %d already exists", pollID)
+			}
+
+			sql = "INSERT INTO vote.poll (id, stopped, config) VALUES ($1, false, $2);"
+			log.Debug("SQL: `%s` (values: %d, [config])", sql, pollID)
+			if _, err := tx.Exec(ctx, sql, pollID, config); err != nil {
+				return fmt.Errorf("insert poll: %w", err)
+			}
+
+			sql = "INSERT INTO vote.voted (poll_id, user_id) SELECT $1, unnest($2::integer[]) ON CONFLICT DO NOTHING;"
+			log.Debug("SQL: `%s` (values: %d, [user_ids])", sql, pollID)
+			if _, err := tx.Exec(ctx, sql, pollID, userIDs); err != nil {
+				return fmt.Errorf("insert voted users: %w", err)
+			}
+
+			sql = "INSERT INTO vote.objects (id, poll_id, vote) VALUES ($1, $2, $3);"
+			log.Debug("SQL: `%s` (values: [id], %d, [vote]) for %d objects", sql, pollID, len(objects))
+			for _, object := range objects {
+				objectID, err := randomID()
+				if err != nil {
+					return fmt.Errorf("creating id for vote object: %w", err)
+				}
+
+				if _, err := tx.Exec(ctx, sql, objectID, pollID, object); err != nil {
+					return fmt.Errorf("writing vote: %w", err)
+				}
+			}
+
+			return nil
+		})
+	})
+}
+
 // Stop ends a poll and returns all vote objects and users who have voted.
 //
 // If an transaction error happens, the poll is stopped again. This is done
 // until either the poll is stopped or the given context is canceled.
 func (b *Backend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	objects, userIDs, _, err := b.StopChecked(ctx, pollID)
+	return objects, userIDs, err
+}
+
+// StopChecked ends a poll like Stop and tells, if the poll was already stopped
+// before.
+func (b *Backend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
 	var objs [][]byte
 	var userIDs []int
+	var stoppedBefore bool
 	err := continueOnTransactionError(ctx, func() error {
-		o, uids, err := b.stopOnce(ctx, pollID)
+		o, uids, stopped, err := b.stopOnce(ctx, pollID)
 		if err != nil {
 			return err
 		}
 		objs = o
 		userIDs = uids
+		stoppedBefore = stopped
 		return nil
 	})
 
-	return objs, userIDs, err
+	return objs, userIDs, stoppedBefore, err
 }
 
 // stopOnce ends a poll and returns all vote objects.
-func (b *Backend) stopOnce(ctx context.Context, pollID int) (objects [][]byte, users []int, err error) {
+//
+// The transaction uses the isolation level READ COMMITTED. The lock of the
+// poll waits for all votes and stops, that have locked the poll. The following
+// queries see these votes.
+func (b *Backend) stopOnce(ctx context.Context, pollID int) (objects [][]byte, users []int, stopped bool, err error) {
 	log.Debug("SQL: Begin transaction for vote")
 	defer func() {
 		log.Debug("SQL: End transaction for vote with error: %v", err)
 	}()
 
-	err = pgx.BeginTxFunc(
+	err = pgx.BeginFunc(
 		ctx,
 		b.pool,
-		pgx.TxOptions{
-			IsoLevel: "REPEATABLE READ",
-		},
 		func(tx pgx.Tx) error {
-			sql := "SELECT EXISTS(SELECT 1 FROM vote.poll WHERE id = $1);"
+			sql := "SELECT stopped FROM vote.poll WHERE id = $1 FOR UPDATE;"
 			log.Debug("SQL: `%s` (values: %d", sql, pollID)
 
-			var exists bool
-			if err := tx.QueryRow(ctx, sql, pollID).Scan(&exists); err != nil {
-				return fmt.Errorf("fetching poll exists: %w", err)
+			if err := tx.QueryRow(ctx, sql, pollID).Scan(&stopped); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return doesNotExistError{fmt.Errorf("Poll does not exist")}
+				}
+				return fmt.Errorf("fetching poll stopped: %w", err)
 			}
 
-			if !exists {
-				return doesNotExistError{fmt.Errorf("Poll does not exist")}
-			}
+			if !stopped {
+				sql = "UPDATE vote.poll SET stopped = true WHERE id = $1;"
+				if _, err := tx.Exec(ctx, sql, pollID); err != nil {
+					return fmt.Errorf("setting poll %d to stopped: %w", pollID, err)
+				}
 
-			sql = "UPDATE vote.poll SET stopped = true WHERE id = $1;"
-			if _, err := tx.Exec(ctx, sql, pollID); err != nil {
-				return fmt.Errorf("setting poll %d to stopped: %w", pollID, err)
+				// Each row was written in the transaction of its batch. The
+				// transaction id of the rows (xmin) would tell, which vote
+				// objects belong to which users and in which order the users
+				// have voted. The updates write all rows of the poll again in
+				// this transaction. The first update also removes the link
+				// between users and vote objects from revotes.
+				sql = "UPDATE vote.objects SET user_id = NULL WHERE poll_id = $1;"
+				log.Debug("SQL: `%s` (values: %d", sql, pollID)
+				if _, err := tx.Exec(ctx, sql, pollID); err != nil {
+					return fmt.Errorf("rewriting vote objects: %w", err)
+				}
+
+				sql = "UPDATE vote.voted SET user_id = user_id WHERE poll_id = $1;"
+				log.Debug("SQL: `%s` (values: %d", sql, pollID)
+				if _, err := tx.Exec(ctx, sql, pollID); err != nil {
+					return fmt.Errorf("rewriting voted users: %w", err)
+				}
 			}
 
+			// The objects are ordered by their random id. So the order does
+			// not tell in which order the users have voted.
 			sql = `
 			SELECT Obj.vote
 			FROM vote.poll Poll
 			LEFT JOIN vote.objects Obj ON Obj.poll_id = Poll.id
-			WHERE Poll.id = $1;
+			WHERE Poll.id = $1
+			ORDER BY Obj.id;
 			`
 			log.Debug("SQL: `%s` (values: %d", sql, pollID)
 			rows, err := tx.Query(ctx, sql, pollID)
@@ -246,32 +481,25 @@ func (b *Backend) stopOnce(ctx context.Context, pollID int) (objects [][]byte, u
 				return fmt.Errorf("parsing query rows: %w", err)
 			}
 
-			sql = `
-			SELECT user_ids
-			FROM vote.poll
-			WHERE poll.id = $1;
-			`
-			var rawUserIDs []byte
-			if err := tx.QueryRow(ctx, sql, pollID).Scan(&rawUserIDs); err != nil {
-				return fmt.Errorf("fetching poll data: %w", err)
-			}
-
-			uIDs, err := userIDListFromBytes(rawUserIDs)
+			sql = "SELECT user_id FROM vote.voted WHERE poll_id = $1 ORDER BY user_id;"
+			log.Debug("SQL: `%s` (values: %d", sql, pollID)
+			userRows, err := tx.Query(ctx, sql, pollID)
 			if err != nil {
-				return fmt.Errorf("parsing user ids: %w", err)
+				return fmt.Errorf("fetching voted users: %w", err)
 			}
 
-			for _, id := range uIDs {
-				users = append(users, int(id))
+			users, err = pgx.CollectRows(userRows, pgx.RowTo[int])
+			if err != nil {
+				return fmt.Errorf("parsing voted users: %w", err)
 			}
 
 			return nil
 		},
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("running transaction: %w", err)
+		return nil, nil, false, fmt.Errorf("running transaction: %w", err)
 	}
-	return objects, users, nil
+	return objects, users, stopped, nil
 }
 
 // Clear removes all data about a poll from the database.
@@ -307,33 +535,41 @@ func (b *Backend) ClearAll(ctx context.Context) error {
 
 // Voted returns for all polls the userIDs, that have voted.
 func (b *Backend) Voted(ctx context.Context) (map[int][]int, error) {
-	sql := `SELECT id, user_ids	FROM vote.poll;`
+	sql := `
+	SELECT Poll.id, Voted.user_id
+	FROM vote.poll Poll
+	LEFT JOIN vote.voted Voted ON Voted.poll_id = Poll.id
+	ORDER BY Poll.id, Voted.user_id;
+	`
 
 	log.Debug("SQL: `%s`", sql)
 	rows, err := b.pool.Query(ctx, sql)
 	if err != nil {
-		return nil, fmt.Errorf("fetching user_ids from all poll objects: %w", err)
+		return nil, fmt.Errorf("fetching voted users of all polls: %w", err)
 	}
+	defer rows.Close()
 
 	out := make(map[int][]int)
 	for rows.Next() {
 		var pid int
-		var rawUIDs []byte
-		if err := rows.Scan(&pid, &rawUIDs); err != nil {
+		var uid *int
+		if err := rows.Scan(&pid, &uid); err != nil {
 			return nil, fmt.Errorf("parsing row: %w", err)
 		}
 
-		uIDs, err := userIDListFromBytes(rawUIDs)
-		if err != nil {
-			return nil, fmt.Errorf("parsing user ids: %w", err)
+		if _, ok := out[pid]; !ok {
+			out[pid] = []int{}
 		}
 
-		out[pid] = make([]int, 0, uIDs.len())
-		for _, uid := range uIDs {
-			out[pid] = append(out[pid], int(uid))
+		if uid != nil {
+			out[pid] = append(out[pid], *uid)
 		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("parsing query rows: %w", err)
+	}
+
 	return out, nil
 }
 
@@ -352,59 +588,32 @@ func continueOnTransactionError(ctx context.Context, f func() error) error {
 			break
 		}
 
-		// The error code is returned if another vote has manipulated the vote
-		// users while this vote was saved.
-		if perr.Code != "40001" {
+		// The error code 40001 is returned on a serialization failure. The
+		// error code 23505 is returned, if the random id of a vote object
+		// already exists.
+		if perr.Code != "40001" && perr.Code != "23505" {
 			break
 		}
 	}
 	return err
 }
 
-type userIDList []int32
-
-func userIDListFromBytes(raw []byte) (userIDList, error) {
-	ints := make([]int32, len(raw)/4)
-	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &ints); err != nil {
-		return nil, fmt.Errorf("decoding user ids: %w", err)
-	}
-	return userIDList(ints), nil
-}
-
-func (u userIDList) toBytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, u); err != nil {
-		return nil, fmt.Errorf("encoding user id %v: %w", u, err)
-	}
-
-	return buf.Bytes(), nil
+// shuffled returns the jobs in a random order.
+func shuffled(jobs []*voteJob) []*voteJob {
+	out := slices.Clone(jobs)
+	mathrand.Shuffle(len(out), func(i, j int) {
+		out[i], out[j] = out[j], out[i]
+	})
+	return out
 }
 
-// add adds the userID to the userIDs
-func (u *userIDList) add(userID int32) error {
-	// idx is either the index of userID or the place where it should be
-	// inserted.
-	ints := []int32(*u)
-	idx := sort.Search(len(ints), func(i int) bool { return ints[i] >= userID })
-	if idx < len(ints) && ints[idx] == userID {
-		return doubleVoteError{fmt.Errorf("User has already voted")}
+// randomID returns a random positive id for a vote object.
+func randomID() (int64, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, fmt.Errorf("reading random bytes: %w", err)
 	}
-
-	// Insert the index at the correct order.
-	ints = append(ints[:idx], append([]int32{userID}, ints[idx:]...)...)
-	*u = ints
-	return nil
-}
-
-// contains returns true if the userID is contains the list of userIDs.
-func (u *userIDList) contains(userID int32) bool {
-	ints := []int32(*u)
-	idx := sort.Search(len(ints), func(i int) bool { return ints[i] >= userID })
-	return idx < len(ints) && ints[idx] == userID
-}
-
-func (u *userIDList) len() int {
-	return len([]int32(*u))
+	return int64(binary.LittleEndian.Uint64(buf[:]) >> 1), nil
 }
 
 type doesNotExistError struct {
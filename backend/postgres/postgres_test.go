@@ -1,16 +1,27 @@
 package postgres_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/OpenSlides/openslides-vote-service/backend/postgres"
 	"github.com/OpenSlides/openslides-vote-service/backend/test"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest/v3"
 )
 
-func startPostgres(t *testing.T) (string, func()) {
+func startPostgres(t testing.TB) (string, func()) {
 	t.Helper()
 
 	pool, err := dockertest.NewPool("")
@@ -61,3 +72,259 @@ func TestImplementBackendInterface(t *testing.T) {
 
 	test.Backend(t, p)
 }
+
+func TestStopHidesVotingOrder(t *testing.T) {
+	ctx := context.Background()
+	port, close := startPostgres(t)
+	defer close()
+
+	addr := fmt.Sprintf(`user=postgres password='password' host=localhost port=%s dbname=database`, port)
+	p, err := postgres.New(ctx, addr)
+	if err != nil {
+		t.Fatalf("Creating postgres backend returned: %v", err)
+	}
+	defer p.Close()
+
+	p.Wait(ctx)
+	if err := p.Migrate(ctx); err != nil {
+		t.Fatalf("Creating db schema: %v", err)
+	}
+
+	conn, err := pgx.Connect(ctx, addr)
+	if err != nil {
+		t.Fatalf("Connecting to postgres: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	if err := p.Start(ctx, 1, []byte(`{}`)); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	// The votes are sent at the same time, so they are written in one batch.
+	insertOrder := []int{4, 2, 5, 1, 3}
+	var wg sync.WaitGroup
+	for _, userID := range insertOrder {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Vote(ctx, 1, userID, []byte(`"Y"`)); err != nil {
+				t.Errorf("Vote returned unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// xmin is the id of the transaction, that has written the row.
+	xminOrder := func(table string) []int {
+		sql := fmt.Sprintf("SELECT count(*) FROM %s WHERE poll_id = 1 GROUP BY xmin::text ORDER BY min(xmin::text::bigint);", table)
+		rows, err := conn.Query(ctx, sql)
+		if err != nil {
+			t.Fatalf("Fetching rows of %s by xmin: %v", table, err)
+		}
+
+		counts, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			t.Fatalf("Fetching rows of %s by xmin: %v", table, err)
+		}
+		return counts
+	}
+
+	for _, table := range []string{"vote.objects", "vote.voted"} {
+		got := xminOrder(table)
+		if len(got) != 1 || got[0] != len(insertOrder) {
+			t.Errorf("Before stop, rows of %s are grouped by xmin as %v, expected all %d rows in one batch", table, got, len(insertOrder))
+		}
+	}
+
+	if _, _, err := p.Stop(ctx, 1); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	for _, table := range []string{"vote.objects", "vote.voted"} {
+		got := xminOrder(table)
+		if len(got) != 1 || got[0] != len(insertOrder) {
+			t.Errorf("After stop, rows of %s are grouped by xmin as %v, expected all %d rows with the same xmin", table, got, len(insertOrder))
+		}
+	}
+}
+
+// BenchmarkVote compares the current schema with the old schema, that saved
+// the ids of the voted users in one BYTEA column of the poll.
+//
+// Many goroutines vote on the same poll, like many delegates, that vote at the
+// same time.
+func BenchmarkVote(b *testing.B) {
+	ctx := context.Background()
+	port, close := startPostgres(b)
+	defer close()
+
+	addr := fmt.Sprintf(`user=postgres password='password' host=localhost port=%s dbname=database`, port)
+	p, err := postgres.New(ctx, addr)
+	if err != nil {
+		b.Fatalf("Creating postgres backend returned: %v", err)
+	}
+	defer p.Close()
+
+	p.Wait(ctx)
+	if err := p.Migrate(ctx); err != nil {
+		b.Fatalf("Creating db schema: %v", err)
+	}
+
+	legacy, err := newLegacyBackend(ctx, addr)
+	if err != nil {
+		b.Fatalf("Creating legacy backend: %v", err)
+	}
+	defer legacy.pool.Close()
+
+	for _, bb := range []struct {
+		name    string
+		start   func(ctx context.Context, pollID int) error
+		vote    func(ctx context.Context, pollID, userID int, object []byte) error
+		stopped func(ctx context.Context, pollID int) (int, error)
+	}{
+		{
+			"row per voter",
+			func(ctx context.Context, pollID int) error { return p.Start(ctx, pollID, nil) },
+			p.Vote,
+			func(ctx context.Context, pollID int) (int, error) {
+				_, userIDs, err := p.Stop(ctx, pollID)
+				return len(userIDs), err
+			},
+		},
+		{
+			"user_ids bytea",
+			legacy.start,
+			legacy.vote,
+			legacy.stop,
+		},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			var pollID int
+			for _, parallelism := range []int{1, 16} {
+				b.Run(fmt.Sprintf("parallelism %d", parallelism), func(b *testing.B) {
+					pollID++
+					if err := bb.start(ctx, pollID); err != nil {
+						b.Fatalf("Start: %v", err)
+					}
+
+					var userID atomic.Int64
+					b.SetParallelism(parallelism)
+					b.ResetTimer()
+					b.RunParallel(func(pb *testing.PB) {
+						for pb.Next() {
+							if err := bb.vote(ctx, pollID, int(userID.Add(1)), []byte(`{"value":"Y"}`)); err != nil {
+								b.Errorf("Vote: %v", err)
+								return
+							}
+						}
+					})
+					b.StopTimer()
+
+					count, err := bb.stopped(ctx, pollID)
+					if err != nil {
+						b.Fatalf("Stop: %v", err)
+					}
+
+					if count != int(userID.Load()) {
+						b.Errorf("Got %d voted users, expected %d", count, userID.Load())
+					}
+				})
+			}
+		})
+	}
+}
+
+// legacyBackend is the vote function of the old schema. It is only used to
+// compare the performance.
+type legacyBackend struct {
+	pool *pgxpool.Pool
+}
+
+func newLegacyBackend(ctx context.Context, addr string) (*legacyBackend, error) {
+	pool, err := pgxpool.New(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection pool: %w", err)
+	}
+
+	schema := `
+	CREATE SCHEMA vote_legacy;
+	CREATE TABLE vote_legacy.poll(
+		id INTEGER UNIQUE NOT NULL,
+		stopped BOOLEAN NOT NULL,
+		user_ids BYTEA
+	);
+	CREATE TABLE vote_legacy.objects (
+		id BIGINT PRIMARY KEY,
+		poll_id INTEGER NOT NULL REFERENCES vote_legacy.poll(id) ON DELETE CASCADE,
+		vote BYTEA
+	);
+	`
+	if _, err := pool.Exec(ctx, schema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+
+	return &legacyBackend{pool: pool}, nil
+}
+
+func (l *legacyBackend) start(ctx context.Context, pollID int) error {
+	_, err := l.pool.Exec(ctx, "INSERT INTO vote_legacy.poll (id, stopped) VALUES ($1, false);", pollID)
+	return err
+}
+
+// vote reads the user ids of the poll, adds the user id and writes them back.
+// Concurrent votes fail with a serialization error and are retried.
+func (l *legacyBackend) vote(ctx context.Context, pollID, userID int, object []byte) error {
+	for {
+		err := pgx.BeginTxFunc(ctx, l.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead}, func(tx pgx.Tx) error {
+			var stopped bool
+			var raw []byte
+			if err := tx.QueryRow(ctx, "SELECT stopped, user_ids FROM vote_legacy.poll WHERE id = $1;", pollID).Scan(&stopped, &raw); err != nil {
+				return fmt.Errorf("fetching poll: %w", err)
+			}
+
+			if stopped {
+				return fmt.Errorf("poll is stopped")
+			}
+
+			userIDs := make([]int32, len(raw)/4)
+			if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &userIDs); err != nil {
+				return fmt.Errorf("decoding user ids: %w", err)
+			}
+
+			idx, found := slices.BinarySearch(userIDs, int32(userID))
+			if found {
+				return fmt.Errorf("user has already voted")
+			}
+			userIDs = slices.Insert(userIDs, idx, int32(userID))
+
+			buf := new(bytes.Buffer)
+			if err := binary.Write(buf, binary.LittleEndian, userIDs); err != nil {
+				return fmt.Errorf("encoding user ids: %w", err)
+			}
+
+			if _, err := tx.Exec(ctx, "UPDATE vote_legacy.poll SET user_ids = $1 WHERE id = $2;", buf.Bytes(), pollID); err != nil {
+				return fmt.Errorf("writing user ids: %w", err)
+			}
+
+			if _, err := tx.Exec(ctx, "INSERT INTO vote_legacy.objects (id, poll_id, vote) VALUES ($1, $2, $3);", rand.Int64N(math.MaxInt64), pollID, object); err != nil {
+				return fmt.Errorf("writing vote: %w", err)
+			}
+			return nil
+		})
+
+		var perr *pgconn.PgError
+		if errors.As(err, &perr) && (perr.Code == "40001" || perr.Code == "23505") {
+			continue
+		}
+		return err
+	}
+}
+
+func (l *legacyBackend) stop(ctx context.Context, pollID int) (int, error) {
+	var raw []byte
+	if err := l.pool.QueryRow(ctx, "UPDATE vote_legacy.poll SET stopped = true WHERE id = $1 RETURNING user_ids;", pollID).Scan(&raw); err != nil {
+		return 0, err
+	}
+	return len(raw) / 4, nil
+}
@@ -4,18 +4,97 @@ CREATE TABLE IF NOT EXISTS vote.poll(
     id INTEGER UNIQUE NOT NULL,
     stopped BOOLEAN NOT NULL,
 
-    -- user_ids is managed by the application. It stores all user ids in a way
-    -- that makes it impossible to see the sequence in which the users have
-    -- voted.
-    user_ids BYTEA
+    -- config is the poll config from the time the poll was started.
+    config BYTEA
 );
 
+-- Add the config column to databases created by older versions. ALTER TABLE
+-- locks the whole table, so it is only called, if needed.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'vote' AND table_name = 'poll' AND column_name = 'config'
+    ) THEN
+        ALTER TABLE vote.poll ADD COLUMN config BYTEA;
+    END IF;
+END $$;
+
 CREATE TABLE IF NOT EXISTS vote.objects (
-    id SERIAL PRIMARY KEY,
+    -- id is a random number created by the application. It makes it
+    -- impossible to see the sequence in which the users have voted.
+    id BIGINT PRIMARY KEY,
 
     -- There are many raws per poll.
     poll_id INTEGER NOT NULL REFERENCES vote.poll(id) ON DELETE CASCADE,
 
+    -- user_id is only set for polls that allow to change the vote. It is
+    -- removed when the poll is stopped.
+    user_id INTEGER,
+
     -- The vote object.
     vote BYTEA
 );
+
+-- Add the user_id column to databases created by older versions.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'vote' AND table_name = 'objects' AND column_name = 'user_id'
+    ) THEN
+        ALTER TABLE vote.objects ADD COLUMN user_id INTEGER;
+    END IF;
+END $$;
+
+-- Databases created by older versions use a sequence for the id.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'vote' AND table_name = 'objects' AND column_name = 'id'
+            AND data_type <> 'bigint'
+    ) THEN
+        ALTER TABLE vote.objects ALTER COLUMN id TYPE BIGINT;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'vote' AND table_name = 'objects' AND column_name = 'id'
+            AND column_default IS NOT NULL
+    ) THEN
+        ALTER TABLE vote.objects ALTER COLUMN id DROP DEFAULT;
+    END IF;
+END $$;
+DROP SEQUENCE IF EXISTS vote.objects_id_seq;
+
+CREATE TABLE IF NOT EXISTS vote.voted (
+    -- There is one row per poll and user. The primary key prevents that a
+    -- user votes twice.
+    poll_id INTEGER NOT NULL REFERENCES vote.poll(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+
+    PRIMARY KEY (poll_id, user_id)
+);
+
+-- Databases created by older versions save the user ids as a sorted list of
+-- little endian int32 values in the column user_ids of vote.poll.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'vote' AND table_name = 'poll' AND column_name = 'user_ids'
+    ) THEN
+        INSERT INTO vote.voted (poll_id, user_id)
+        SELECT poll.id,
+            get_byte(poll.user_ids, i * 4)
+            | (get_byte(poll.user_ids, i * 4 + 1) << 8)
+            | (get_byte(poll.user_ids, i * 4 + 2) << 16)
+            | (get_byte(poll.user_ids, i * 4 + 3) << 24)
+        FROM vote.poll poll, generate_series(0, length(poll.user_ids) / 4 - 1) AS i
+        WHERE poll.user_ids IS NOT NULL
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE vote.poll DROP COLUMN user_ids;
+    END IF;
+END $$;
@@ -3,26 +3,44 @@
 // Is tries to save the votes as fast as possible. All necessary checkes are
 // done inside a lua-script so everything is done in one atomic step. It is
 // expected that there is no backup from the redis database. Everyone with
-// access to the redis database can see the vote results and how each user has
-// voted.
+// access to the redis database can see the vote results. The user ids and the
+// vote objects are saved in different keys, so it is not possible to see how a
+// user has voted.
 //
-// It uses the keys `vote_state_X`, `vote_data_X` and `vote_polls` where X is a
-// pollID.
+// It uses the keys `vote_state_X`, `vote_config_X`, `vote_voted_X`,
+// `vote_ballots_X`, `vote_revote_X` and `vote_polls` where X is a pollID.
 //
 // The key `vote_state_X` has type int. It is a number that tells the current
 // state of the poll. 1: Poll is started. 2: Poll is stopped.
 //
-// The key `vote_data_X` has type hash. The key is a user id and the value the
-// vote of the user.
+// The key `vote_config_X` has type string. It is the poll config from the time
+// the poll was started.
+//
+// The key `vote_voted_X` has type set. It contains the user ids of all users,
+// that have voted.
+//
+// The key `vote_ballots_X` has type list. It contains the vote objects. Each
+// new vote object is swapped with a random position of the list, so the order
+// of the list does not tell, in which order the users have voted.
+//
+// The key `vote_revote_X` has type hash. The key is a user id and the value the
+// vote of the user. It is only used for revotable polls, where the vote of a
+// user has to be replaced. When the poll is stopped, the vote objects are moved
+// to `vote_ballots_X` and the key is removed.
 //
 // The key `vote_polls` has type set. It contains the pollIDs of all known polls.
+//
+// Older versions saved the votes in the key `vote_data_X` with type hash. Votes
+// from this key are moved to the new keys, when the poll is stopped.
 package redis
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"math/big"
+	"slices"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -31,19 +49,31 @@ import (
 )
 
 const (
-	keyState = "vote_state_%d"
-	keyVote  = "vote_data_%d"
-	keyPolls = "vote_polls"
+	keyState      = "vote_state_%d"
+	keyConfig     = "vote_config_%d"
+	keyVoted      = "vote_voted_%d"
+	keyBallots    = "vote_ballots_%d"
+	keyRevote     = "vote_revote_%d"
+	keyLegacyVote = "vote_data_%d"
+	keyPolls      = "vote_polls"
 )
 
+// dialTimeout is the time to connect to redis. Without it, requests to an
+// unreachable redis would wait for the timeout of the operating system.
+const dialTimeout = 2 * time.Second
+
 // Backend is the vote-Backend.
 //
 // Has to be created with redis.New().
 type Backend struct {
 	pool *redis.Pool
 
+	luaScriptStart    *redis.Script
 	luaScriptVote     *redis.Script
+	luaScriptRevote   *redis.Script
+	luaScriptStop     *redis.Script
 	luaScriptClearAll *redis.Script
+	luaScriptImport   *redis.Script
 }
 
 // New creates an initializes Redis instance.
@@ -53,14 +83,20 @@ func New(addr string) *Backend {
 		Wait:        true,
 		MaxIdle:     10,
 		IdleTimeout: 240 * time.Second,
-		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", addr) },
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr, redis.DialConnectTimeout(dialTimeout))
+		},
 	}
 
 	return &Backend{
 		pool: &pool,
 
-		luaScriptVote:     redis.NewScript(2, luaVoteScript),
+		luaScriptStart:    redis.NewScript(3, luaStartScript),
+		luaScriptVote:     redis.NewScript(4, luaVoteScript),
+		luaScriptRevote:   redis.NewScript(4, luaRevoteScript),
+		luaScriptStop:     redis.NewScript(5, luaStopScript),
 		luaScriptClearAll: redis.NewScript(1, luaClearAll),
+		luaScriptImport:   redis.NewScript(5, luaImportScript),
 	}
 }
 
@@ -78,35 +114,87 @@ func (b *Backend) Wait(ctx context.Context) {
 	}
 }
 
+// Ping checks the connection to redis.
+func (b *Backend) Ping(ctx context.Context) error {
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := redis.DoContext(conn, ctx, "PING"); err != nil {
+		return fmt.Errorf("sending ping: %w", err)
+	}
+	return nil
+}
+
 func (b *Backend) String() string {
 	return "redis"
 }
 
+// luaStartScript starts a poll and saves its config, if the poll does not
+// exist.
+//
+// KEYS[1] == state key
+// KEYS[2] == config key
+// KEYS[3] == polls key
+// ARGV[1] == pollID
+// ARGV[2] == config
+const luaStartScript = `
+if redis.call("SETNX",KEYS[1],1) == 1 then
+	redis.call("SET",KEYS[2],ARGV[2])
+end
+redis.call("SADD",KEYS[3],ARGV[1])
+return 0`
+
 // Start starts the poll.
-func (b *Backend) Start(ctx context.Context, pollID int) error {
+func (b *Backend) Start(ctx context.Context, pollID int, config []byte) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	sKey := fmt.Sprintf(keyState, pollID)
+	cKey := fmt.Sprintf(keyConfig, pollID)
+
+	log.Debug("Redis: lua script start: '%s' 3 %s %s %s %d [config]", luaStartScript, sKey, cKey, keyPolls, pollID)
+	if _, err := b.luaScriptStart.Do(conn, sKey, cKey, keyPolls, pollID, config); err != nil {
+		return fmt.Errorf("executing luaStartScript: %w", err)
+	}
+	return nil
+}
+
+// Config returns the config of a poll.
+func (b *Backend) Config(ctx context.Context, pollID int) ([]byte, error) {
 	conn := b.pool.Get()
 	defer conn.Close()
 
 	sKey := fmt.Sprintf(keyState, pollID)
+	cKey := fmt.Sprintf(keyConfig, pollID)
 
-	log.Debug("Redis: SETNX %s 1", sKey)
-	if _, err := conn.Do("SETNX", sKey, 1); err != nil {
-		return fmt.Errorf("set state key to 1: %w", err)
+	log.Debug("Redis: MGET %s %s", sKey, cKey)
+	values, err := redis.ByteSlices(conn.Do("MGET", sKey, cKey))
+	if err != nil {
+		return nil, fmt.Errorf("getting poll config: %w", err)
 	}
 
-	log.Debug("Redis: SADD %s %d", keyPolls, pollID)
-	if _, err := conn.Do("SADD", keyPolls, pollID); err != nil {
-		return fmt.Errorf("add poll ID to %s: %w", keyPolls, err)
+	if values[0] == nil {
+		return nil, doesNotExistError{fmt.Errorf("poll does not exist")}
 	}
-	return nil
+
+	return values[1], nil
 }
 
 // luaVoteScript checks for condition and saves a vote if all checks pass.
 //
+// The user id and the vote object are saved in different keys. The vote object
+// is swapped with a random position of the ballot list.
+//
 // KEYS[1] == state key
-// KEYS[2] == vote data
+// KEYS[2] == voted key
+// KEYS[3] == ballots key
+// KEYS[4] == legacy vote data
 // ARGV[1] == userID
 // ARGV[2] == Vote object
+// ARGV[3] == random number
 //
 // Returns 0 on success
 // Returns 1 if the poll is not started.
@@ -122,11 +210,22 @@ if state == "2" then
 	return 2
 end
 
-local saved = redis.call("HSETNX",KEYS[2],ARGV[1],ARGV[2])
-if saved == 0 then
+if redis.call("HEXISTS",KEYS[4],ARGV[1]) == 1 then
+	return 3
+end
+
+if redis.call("SADD",KEYS[2],ARGV[1]) == 0 then
 	return 3
 end
 
+local length = redis.call("RPUSH",KEYS[3],ARGV[2])
+local position = tonumber(ARGV[3]) % length
+if position ~= length - 1 then
+	local other = redis.call("LINDEX",KEYS[3],position)
+	redis.call("LSET",KEYS[3],position,ARGV[2])
+	redis.call("LSET",KEYS[3],-1,other)
+end
+
 return 0`
 
 // Vote saves a vote in redis.
@@ -136,11 +235,18 @@ func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byt
 	conn := b.pool.Get()
 	defer conn.Close()
 
-	vKey := fmt.Sprintf(keyVote, pollID)
 	sKey := fmt.Sprintf(keyState, pollID)
+	votedKey := fmt.Sprintf(keyVoted, pollID)
+	bKey := fmt.Sprintf(keyBallots, pollID)
+	lKey := fmt.Sprintf(keyLegacyVote, pollID)
+
+	position, err := randomPosition()
+	if err != nil {
+		return fmt.Errorf("creating random position: %w", err)
+	}
 
-	log.Debug("Redis: lua script vote: '%s' 2 %s %s [userID] [vote]", luaVoteScript, sKey, vKey)
-	result, err := redis.Int(b.luaScriptVote.Do(conn, sKey, vKey, userID, object))
+	log.Debug("Redis: lua script vote: '%s' 4 %s %s %s %s [userID] [vote] [position]", luaVoteScript, sKey, votedKey, bKey, lKey)
+	result, err := redis.Int(b.luaScriptVote.Do(conn, sKey, votedKey, bKey, lKey, userID, object, position))
 	if err != nil {
 		return fmt.Errorf("executing luaVoteScript: %w", err)
 	}
@@ -158,44 +264,266 @@ func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byt
 	}
 }
 
+// randomPosition returns a random number that is used to find the position of a
+// new vote object in the ballot list. luaStopScript uses it as seed.
+//
+// The number has to be smaller than 2^53 so lua can represent it exactly.
+func randomPosition() (int64, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1<<31))
+	if err != nil {
+		return 0, fmt.Errorf("reading random number: %w", err)
+	}
+	return n.Int64(), nil
+}
+
+// luaRevoteScript checks for condition and saves a vote if all checks pass.
+// An existing vote of the user is replaced.
+//
+// The link between the user and the vote object is needed to replace the vote.
+// It is removed, when the poll is stopped.
+//
+// KEYS[1] == state key
+// KEYS[2] == voted key
+// KEYS[3] == revote key
+// KEYS[4] == legacy vote data
+// ARGV[1] == userID
+// ARGV[2] == Vote object
+//
+// Returns 0 on success
+// Returns 1 if the poll is not started.
+// Returns 2 if the poll was stopped.
+const luaRevoteScript = `
+local state = redis.call("GET",KEYS[1])
+if state == false then 
+	return 1
+end
+
+if state == "2" then
+	return 2
+end
+
+redis.call("HDEL",KEYS[4],ARGV[1])
+redis.call("SADD",KEYS[2],ARGV[1])
+redis.call("HSET",KEYS[3],ARGV[1],ARGV[2])
+return 0`
+
+// Revote saves a vote in redis. If the user has already voted, the old vote is
+// replaced.
+//
+// It also checks, that the poll is open.
+func (b *Backend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	sKey := fmt.Sprintf(keyState, pollID)
+	votedKey := fmt.Sprintf(keyVoted, pollID)
+	rKey := fmt.Sprintf(keyRevote, pollID)
+	lKey := fmt.Sprintf(keyLegacyVote, pollID)
+
+	log.Debug("Redis: lua script revote: '%s' 4 %s %s %s %s [userID] [vote]", luaRevoteScript, sKey, votedKey, rKey, lKey)
+	result, err := redis.Int(b.luaScriptRevote.Do(conn, sKey, votedKey, rKey, lKey, userID, object))
+	if err != nil {
+		return fmt.Errorf("executing luaRevoteScript: %w", err)
+	}
+
+	log.Debug("Redis: Returned %d", result)
+	switch result {
+	case 1:
+		return doesNotExistError{fmt.Errorf("poll is not started")}
+	case 2:
+		return stoppedError{fmt.Errorf("poll is stopped")}
+	default:
+		return nil
+	}
+}
+
+// luaStopScript stops a poll and returns its vote objects and user ids.
+//
+// The vote objects of revotable polls and of the legacy vote data are moved to
+// the ballot list, so there is no link between users and vote objects after
+// the poll is stopped. Like in luaVoteScript, each moved vote object is swapped
+// with a random position of the list. The objects are only moved once, so
+// later calls return the same order.
+//
+// KEYS[1] == state key
+// KEYS[2] == voted key
+// KEYS[3] == ballots key
+// KEYS[4] == revote key
+// KEYS[5] == legacy vote data
+// ARGV[1] == random seed
+//
+// Returns nil if the poll does not exist.
+// Returns a list with the vote objects, the user ids and the state before the
+// call on success.
+const luaStopScript = `
+local state = redis.call("GET",KEYS[1])
+if not state then
+	return false
+end
+
+redis.call("SET",KEYS[1],2)
+
+local moved = redis.call("HVALS",KEYS[4])
+local legacy = redis.call("HGETALL",KEYS[5])
+for i = 1, #legacy, 2 do
+	redis.call("SADD",KEYS[2],legacy[i])
+	table.insert(moved,legacy[i+1])
+end
+
+if #moved > 0 then
+	math.randomseed(tonumber(ARGV[1]))
+end
+
+for _, object in ipairs(moved) do
+	local length = redis.call("RPUSH",KEYS[3],object)
+	local position = math.random(length) - 1
+	if position ~= length - 1 then
+		local other = redis.call("LINDEX",KEYS[3],position)
+		redis.call("LSET",KEYS[3],position,object)
+		redis.call("LSET",KEYS[3],-1,other)
+	end
+end
+
+redis.call("DEL",KEYS[4],KEYS[5])
+
+return {redis.call("LRANGE",KEYS[3],0,-1), redis.call("SMEMBERS",KEYS[2]), state}`
+
 // Stop ends a poll.
 //
 // It returns all vote objects.
 func (b *Backend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	objects, userIDs, _, err := b.StopChecked(ctx, pollID)
+	return objects, userIDs, err
+}
+
+// StopChecked ends a poll like Stop and tells, if the poll was already stopped
+// before.
+func (b *Backend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
 	conn := b.pool.Get()
 	defer conn.Close()
 
-	vKey := fmt.Sprintf(keyVote, pollID)
 	sKey := fmt.Sprintf(keyState, pollID)
+	votedKey := fmt.Sprintf(keyVoted, pollID)
+	bKey := fmt.Sprintf(keyBallots, pollID)
+	rKey := fmt.Sprintf(keyRevote, pollID)
+	lKey := fmt.Sprintf(keyLegacyVote, pollID)
+
+	seed, err := randomPosition()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("creating random seed: %w", err)
+	}
 
-	log.Debug("SET %s 2 XX", sKey)
-	_, err := redis.String(conn.Do("SET", sKey, "2", "XX"))
+	log.Debug("Redis: lua script stop: '%s' 5 %s %s %s %s %s [seed]", luaStopScript, sKey, votedKey, bKey, rKey, lKey)
+	result, err := redis.Values(b.luaScriptStop.Do(conn, sKey, votedKey, bKey, rKey, lKey, seed))
 	if err != nil {
 		if err == redis.ErrNil {
-			return nil, nil, doesNotExistError{fmt.Errorf("poll does not exist")}
+			return nil, nil, false, doesNotExistError{fmt.Errorf("poll does not exist")}
 		}
-		return nil, nil, fmt.Errorf("set key %s to 2: %w", sKey, err)
+		return nil, nil, false, fmt.Errorf("executing luaStopScript: %w", err)
 	}
 
-	log.Debug("REDIS: HVALS %s", vKey)
-	data, err := redis.StringMap(conn.Do("HGETALL", vKey))
+	if len(result) != 3 {
+		return nil, nil, false, fmt.Errorf("luaStopScript returned %d values, expected 3", len(result))
+	}
+
+	voteObjects, err := redis.ByteSlices(result[0], nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("getting vote objects from %s: %w", vKey, err)
+		return nil, nil, false, fmt.Errorf("reading vote objects: %w", err)
 	}
 
-	userIDs := make([]int, 0, len(data))
-	voteObjects := make([][]byte, 0, len(data))
-	for uid, vote := range data {
-		id, err := strconv.Atoi(uid)
-		if err != nil {
-			return nil, nil, fmt.Errorf("invalid userID %s: %w", uid, err)
-		}
-		userIDs = append(userIDs, id)
-		voteObjects = append(voteObjects, []byte(vote))
+	userIDs, err := redis.Ints(result[1], nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("reading user ids: %w", err)
+	}
+
+	state, err := redis.Int(result[2], nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("reading state: %w", err)
 	}
 
 	sort.Ints(userIDs)
-	return voteObjects, userIDs, nil
+	return voteObjects, userIDs, state == 2, nil
+}
+
+// luaImportScript starts a poll with users that have already voted and their
+// vote objects. It does nothing, if the poll exists.
+//
+// KEYS[1] == state key
+// KEYS[2] == config key
+// KEYS[3] == polls key
+// KEYS[4] == voted key
+// KEYS[5] == ballots key
+// ARGV[1] == pollID
+// ARGV[2] == config
+// ARGV[3] == number of user ids
+// ARGV[4...] == user ids followed by the vote objects
+//
+// Returns 0, if the poll exists.
+const luaImportScript = `
+if redis.call("EXISTS",KEYS[1]) == 1 then
+	return 0
+end
+
+redis.call("SET",KEYS[1],1)
+redis.call("SET",KEYS[2],ARGV[2])
+redis.call("SADD",KEYS[3],ARGV[1])
+
+local count = tonumber(ARGV[3])
+for i = 4, 3 + count do
+	redis.call("SADD",KEYS[4],ARGV[i])
+end
+
+for i = 4 + count, #ARGV do
+	redis.call("RPUSH",KEYS[5],ARGV[i])
+end
+return 1`
+
+// Import starts a poll with the given users and vote objects.
+func (b *Backend) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	shuffled := slices.Clone(objects)
+	if err := shuffle(shuffled); err != nil {
+		return fmt.Errorf("shuffle vote objects: %w", err)
+	}
+
+	sKey := fmt.Sprintf(keyState, pollID)
+	cKey := fmt.Sprintf(keyConfig, pollID)
+	votedKey := fmt.Sprintf(keyVoted, pollID)
+	bKey := fmt.Sprintf(keyBallots, pollID)
+
+	args := []any{sKey, cKey, keyPolls, votedKey, bKey, pollID, config, len(userIDs)}
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+	for _, object := range shuffled {
+		args = append(args, object)
+	}
+
+	log.Debug("Redis: lua script import: '%s' 5 %s %s %s %s %s %d [config] %d [user ids] [vote objects]", luaImportScript, sKey, cKey, keyPolls, votedKey, bKey, pollID, len(userIDs))
+	imported, err := redis.Int(b.luaScriptImport.Do(conn, args...))
+	if err != nil {
+		return fmt.Errorf("executing luaImportScript: %w", err)
+	}
+
+	if imported == 0 {
+		return fmt.Errorf("poll %d already exists", pollID)
+	}
+	return nil
+}
+
+// shuffle brings the vote objects in a random order, so the order does not tell
+// in which order the users have voted.
+func shuffle(objects [][]byte) error {
+	for i := len(objects) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return fmt.Errorf("reading random number: %w", err)
+		}
+		objects[i], objects[j.Int64()] = objects[j.Int64()], objects[i]
+	}
+	return nil
 }
 
 // Clear delete all information from a poll.
@@ -203,11 +531,15 @@ func (b *Backend) Clear(ctx context.Context, pollID int) error {
 	conn := b.pool.Get()
 	defer conn.Close()
 
-	vKey := fmt.Sprintf(keyVote, pollID)
 	sKey := fmt.Sprintf(keyState, pollID)
-
-	log.Debug("REDIS: DEL %s %s", vKey, sKey)
-	if _, err := conn.Do("DEL", vKey, sKey); err != nil {
+	cKey := fmt.Sprintf(keyConfig, pollID)
+	votedKey := fmt.Sprintf(keyVoted, pollID)
+	bKey := fmt.Sprintf(keyBallots, pollID)
+	rKey := fmt.Sprintf(keyRevote, pollID)
+	lKey := fmt.Sprintf(keyLegacyVote, pollID)
+
+	log.Debug("REDIS: DEL %s %s %s %s %s %s", sKey, cKey, votedKey, bKey, rKey, lKey)
+	if _, err := conn.Do("DEL", sKey, cKey, votedKey, bKey, rKey, lKey); err != nil {
 		return fmt.Errorf("removing keys: %w", err)
 	}
 
@@ -223,12 +555,12 @@ func (b *Backend) Clear(ctx context.Context, pollID int) error {
 //
 // KEYS[1] == polls
 //
-// ARGV[1] == state key pattern
-// ARGV[2] == vote data pattern
+// ARGV[1..n] == key patterns
 const luaClearAll = `
 for _, pollID in ipairs(redis.call("SMEMBERS",KEYS[1])) do
-	redis.call("DEL", ARGV[1]..pollID)
-	redis.call("DEL", ARGV[2]..pollID)
+	for _, pattern in ipairs(ARGV) do
+		redis.call("DEL", pattern..pollID)
+	end
 end
 redis.call("DEL", KEYS[1])
 `
@@ -238,11 +570,13 @@ func (b *Backend) ClearAll(ctx context.Context) error {
 	conn := b.pool.Get()
 	defer conn.Close()
 
-	voteKeyPattern := strings.ReplaceAll(keyVote, "%d", "")
-	stateKeyPattern := strings.ReplaceAll(keyState, "%d", "")
+	args := []any{keyPolls}
+	for _, key := range []string{keyState, keyConfig, keyVoted, keyBallots, keyRevote, keyLegacyVote} {
+		args = append(args, strings.ReplaceAll(key, "%d", ""))
+	}
 
-	log.Debug("Redis: lua script clear all: '%s' 2 %s %s", luaClearAll, voteKeyPattern, stateKeyPattern)
-	if _, err := b.luaScriptClearAll.Do(conn, keyPolls, voteKeyPattern, stateKeyPattern); err != nil {
+	log.Debug("Redis: lua script clear all: '%s' 1 %v", luaClearAll, args)
+	if _, err := b.luaScriptClearAll.Do(conn, args...); err != nil {
 		return fmt.Errorf("removing keys: %w", err)
 	}
 
@@ -264,15 +598,23 @@ func (b *Backend) Voted(ctx context.Context) (map[int][]int, error) {
 
 	out := make(map[int][]int, len(pollIDs))
 	for _, pollID := range pollIDs {
-		key := fmt.Sprintf(keyVote, pollID)
+		key := fmt.Sprintf(keyVoted, pollID)
+
+		log.Debug("Redis: SMEMBERS %s", key)
+		userIDs, err := redis.Ints(conn.Do("SMEMBERS", key))
+		if err != nil {
+			return nil, fmt.Errorf("SMEMBERS for key %s: %w", key, err)
+		}
+
+		legacyKey := fmt.Sprintf(keyLegacyVote, pollID)
 
-		log.Debug("Redis: HKEYS %s", key)
-		userIDs, err := redis.Ints(conn.Do("HKEYS", key))
+		log.Debug("Redis: HKEYS %s", legacyKey)
+		legacyUserIDs, err := redis.Ints(conn.Do("HKEYS", legacyKey))
 		if err != nil {
-			return nil, fmt.Errorf("HKEYS for key %s: %w", key, err)
+			return nil, fmt.Errorf("HKEYS for key %s: %w", legacyKey, err)
 		}
 
-		out[pollID] = userIDs
+		out[pollID] = append(userIDs, legacyUserIDs...)
 	}
 
 	return out, nil
@@ -2,10 +2,13 @@ package redis_test
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/OpenSlides/openslides-vote-service/backend/redis"
 	"github.com/OpenSlides/openslides-vote-service/backend/test"
+	redigo "github.com/gomodule/redigo/redis"
 	"github.com/ory/dockertest/v3"
 )
 
@@ -39,3 +42,93 @@ func TestImplementBackendInterface(t *testing.T) {
 
 	test.Backend(t, r)
 }
+
+func TestNoLinkBetweenUserAndVote(t *testing.T) {
+	port, close := startRedis(t)
+	defer close()
+
+	ctx := context.Background()
+	r := redis.New("localhost:" + port)
+	r.Wait(ctx)
+
+	if err := r.Start(ctx, 1, nil); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if err := r.Vote(ctx, 1, 5, []byte("my vote")); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	conn, err := redigo.Dial("tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("connecting to redis: %v", err)
+	}
+	defer conn.Close()
+
+	keys, err := redigo.Strings(conn.Do("KEYS", "*"))
+	if err != nil {
+		t.Fatalf("KEYS returned unexpected error: %v", err)
+	}
+
+	for _, key := range keys {
+		keyType, err := redigo.String(conn.Do("TYPE", key))
+		if err != nil {
+			t.Fatalf("TYPE %s returned unexpected error: %v", key, err)
+		}
+
+		if keyType == "hash" {
+			t.Errorf("Key %s has type hash. It could link a user to a vote", key)
+		}
+	}
+
+	userIDs, err := redigo.Ints(conn.Do("SMEMBERS", "vote_voted_1"))
+	if err != nil {
+		t.Fatalf("SMEMBERS returned unexpected error: %v", err)
+	}
+
+	if len(userIDs) != 1 || userIDs[0] != 5 {
+		t.Errorf("vote_voted_1 contains %v, expected [5]", userIDs)
+	}
+
+	ballots, err := redigo.Strings(conn.Do("LRANGE", "vote_ballots_1", 0, -1))
+	if err != nil {
+		t.Fatalf("LRANGE returned unexpected error: %v", err)
+	}
+
+	if len(ballots) != 1 || ballots[0] != "my vote" {
+		t.Errorf("vote_ballots_1 contains %v, expected [my vote]", ballots)
+	}
+}
+
+func TestStopKeepsOrder(t *testing.T) {
+	port, close := startRedis(t)
+	defer close()
+
+	ctx := context.Background()
+	r := redis.New("localhost:" + port)
+	r.Wait(ctx)
+
+	if err := r.Start(ctx, 1, nil); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	for userID := 1; userID <= 20; userID++ {
+		if err := r.Revote(ctx, 1, userID, []byte(fmt.Sprintf("vote %d", userID))); err != nil {
+			t.Fatalf("Revote returned unexpected error: %v", err)
+		}
+	}
+
+	first, _, err := r.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	second, _, err := r.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Second Stop returned unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Second Stop returned the vote objects in another order")
+	}
+}
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS poll(
+    id INTEGER PRIMARY KEY,
+    stopped BOOLEAN NOT NULL,
+
+    -- config is the poll config from the time the poll was started.
+    config BLOB
+);
+
+-- voted contains the ids of the users, that have voted. The table has no
+-- rowid, so the rows are saved in the order of the primary key and not in the
+-- sequence in which the users have voted.
+CREATE TABLE IF NOT EXISTS voted(
+    poll_id INTEGER NOT NULL REFERENCES poll(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (poll_id, user_id)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS objects(
+    -- id is a random number created by the application. It makes it
+    -- impossible to see the sequence in which the users have voted.
+    id INTEGER PRIMARY KEY,
+
+    poll_id INTEGER NOT NULL REFERENCES poll(id) ON DELETE CASCADE,
+
+    -- user_id is only set for polls that allow to change the vote. It is
+    -- removed when the poll is stopped.
+    user_id INTEGER,
+
+    -- The vote object.
+    vote BLOB
+);
+
+CREATE INDEX IF NOT EXISTS objects_poll_id ON objects(poll_id);
@@ -0,0 +1,414 @@
+// Package sqlite implements the vote.Backend interface with a sqlite database.
+//
+// It is meant for single instance deployments, that should not lose the votes
+// on a restart, but do not want to run a postgres server.
+//
+// The vote objects are saved with a random id and returned in the order of
+// this id. The ids of the users, that have voted, are saved in a table ordered
+// by the user id. So the database does not tell in which order the users have
+// voted.
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	_ "embed" // Needed for file embedding
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/OpenSlides/openslides-vote-service/log"
+	_ "modernc.org/sqlite" // Registers the sqlite driver.
+)
+
+//go:embed schema.sql
+var schema string
+
+// Backend holds the state of the backend.
+//
+// Has to be initializes with New().
+type Backend struct {
+	db *sql.DB
+}
+
+// New opens the sqlite database at the given file. The file is created, if it
+// does not exist.
+func New(file string) (*Backend, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(FULL)")
+	params.Add("_pragma", "foreign_keys(1)")
+
+	// The path is escaped, so characters like ? or # are part of the file
+	// name.
+	dsn := url.URL{Scheme: "file", Path: file, RawQuery: params.Encode()}
+
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+
+	// sqlite only allows one writer at the time. With only one connection,
+	// the transactions wait for each other in go and not with busy loops in
+	// sqlite.
+	db.SetMaxOpenConns(1)
+
+	return &Backend{db: db}, nil
+}
+
+func (b *Backend) String() string {
+	return "sqlite"
+}
+
+// Migrate creates the database schema.
+func (b *Backend) Migrate(ctx context.Context) error {
+	if _, err := b.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+// Start starts a poll.
+func (b *Backend) Start(ctx context.Context, pollID int, config []byte) error {
+	query := "INSERT INTO poll (id, stopped, config) VALUES (?, false, ?) ON CONFLICT DO NOTHING;"
+	log.Debug("SQL: `%s` (values: %d, [config])", query, pollID)
+	if _, err := b.db.ExecContext(ctx, query, pollID, config); err != nil {
+		return fmt.Errorf("insert poll: %w", err)
+	}
+	return nil
+}
+
+// Config returns the config of a poll.
+func (b *Backend) Config(ctx context.Context, pollID int) ([]byte, error) {
+	query := "SELECT config FROM poll WHERE id = ?;"
+	log.Debug("SQL: `%s` (values: %d)", query, pollID)
+
+	var config []byte
+	if err := b.db.QueryRowContext(ctx, query, pollID).Scan(&config); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, doesNotExistError{fmt.Errorf("unknown poll")}
+		}
+		return nil, fmt.Errorf("fetching poll config: %w", err)
+	}
+
+	return config, nil
+}
+
+// Vote adds a vote to a poll.
+func (b *Backend) Vote(ctx context.Context, pollID int, userID int, object []byte) error {
+	return b.vote(ctx, pollID, userID, object, false)
+}
+
+// Revote adds a vote to a poll. If the user has already voted, the old vote is
+// replaced.
+//
+// The user id is saved with the vote object until the poll is stopped.
+func (b *Backend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	return b.vote(ctx, pollID, userID, object, true)
+}
+
+// vote saves the vote in one transaction.
+//
+// If revote is true, an existing vote of the user is replaced.
+func (b *Backend) vote(ctx context.Context, pollID int, userID int, object []byte, revote bool) error {
+	return b.transaction(ctx, func(tx *sql.Tx) error {
+		query := "SELECT stopped FROM poll WHERE id = ?;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+
+		var stopped bool
+		if err := tx.QueryRowContext(ctx, query, pollID).Scan(&stopped); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return doesNotExistError{fmt.Errorf("unknown poll")}
+			}
+			return fmt.Errorf("fetching poll data: %w", err)
+		}
+
+		if stopped {
+			return stoppedError{fmt.Errorf("poll is stopped")}
+		}
+
+		query = "INSERT OR IGNORE INTO voted (poll_id, user_id) VALUES (?, ?);"
+		log.Debug("SQL: `%s` (values: %d, [userID])", query, pollID)
+		result, err := tx.ExecContext(ctx, query, pollID, userID)
+		if err != nil {
+			return fmt.Errorf("writing user id: %w", err)
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("checking inserted user id: %w", err)
+		}
+
+		if inserted == 0 {
+			if !revote {
+				return doubleVoteError{fmt.Errorf("User has already voted")}
+			}
+
+			query = "DELETE FROM objects WHERE poll_id = ? AND user_id = ?;"
+			log.Debug("SQL: `%s` (values: %d, [userID])", query, pollID)
+			if _, err := tx.ExecContext(ctx, query, pollID, userID); err != nil {
+				return fmt.Errorf("removing old vote: %w", err)
+			}
+		}
+
+		// The user id is only saved for revotes. It is needed to find the
+		// vote object, if the user votes again.
+		var objectUserID *int
+		if revote {
+			objectUserID = &userID
+		}
+
+		return insertObject(ctx, tx, pollID, objectUserID, object)
+	})
+}
+
+// insertObject saves a vote object with a random id.
+func insertObject(ctx context.Context, tx *sql.Tx, pollID int, userID *int, object []byte) error {
+	// The random id can already exist. In this case, a new id is created.
+	query := "INSERT OR IGNORE INTO objects (id, poll_id, user_id, vote) VALUES (?, ?, ?, ?);"
+	for {
+		objectID, err := randomID()
+		if err != nil {
+			return fmt.Errorf("creating id for vote object: %w", err)
+		}
+
+		log.Debug("SQL: `%s` (values: [id], %d, [userID], [vote])", query, pollID)
+		result, err := tx.ExecContext(ctx, query, objectID, pollID, userID, object)
+		if err != nil {
+			return fmt.Errorf("writing vote: %w", err)
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("checking inserted vote: %w", err)
+		}
+
+		if inserted == 1 {
+			return nil
+		}
+	}
+}
+
+// Import starts a poll with the given users and vote objects.
+func (b *Backend) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	return b.transaction(ctx, func(tx *sql.Tx) error {
+		query := "INSERT INTO poll (id, stopped, config) VALUES (?, false, ?);"
+		log.Debug("SQL: `%s` (values: %d, [config])", query, pollID)
+		if _, err := tx.ExecContext(ctx, query, pollID, config); err != nil {
+			return fmt.Errorf("insert poll: %w", err)
+		}
+
+		query = "INSERT INTO voted (poll_id, user_id) VALUES (?, ?);"
+		log.Debug("SQL: `%s` (values: %d, [userID]) for %d users", query, pollID, len(userIDs))
+		for _, userID := range userIDs {
+			if _, err := tx.ExecContext(ctx, query, pollID, userID); err != nil {
+				return fmt.Errorf("writing user id: %w", err)
+			}
+		}
+
+		for _, object := range objects {
+			if err := insertObject(ctx, tx, pollID, nil, object); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Stop ends a poll and returns all vote objects and users who have voted.
+func (b *Backend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	objects, userIDs, _, err := b.StopChecked(ctx, pollID)
+	return objects, userIDs, err
+}
+
+// StopChecked ends a poll like Stop and tells, if the poll was already stopped
+// before.
+func (b *Backend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
+	var objects [][]byte
+	var userIDs []int
+	var stoppedBefore bool
+	err := b.transaction(ctx, func(tx *sql.Tx) error {
+		query := "SELECT stopped FROM poll WHERE id = ?;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+		if err := tx.QueryRowContext(ctx, query, pollID).Scan(&stoppedBefore); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return doesNotExistError{fmt.Errorf("Poll does not exist")}
+			}
+			return fmt.Errorf("fetching poll stopped: %w", err)
+		}
+
+		query = "UPDATE poll SET stopped = true WHERE id = ?;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+		if _, err := tx.ExecContext(ctx, query, pollID); err != nil {
+			return fmt.Errorf("setting poll %d to stopped: %w", pollID, err)
+		}
+
+		// Remove the link between users and votes from revotes.
+		query = "UPDATE objects SET user_id = NULL WHERE poll_id = ? AND user_id IS NOT NULL;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+		if _, err := tx.ExecContext(ctx, query, pollID); err != nil {
+			return fmt.Errorf("removing user ids from vote objects: %w", err)
+		}
+
+		// The objects are ordered by their random id. So the order does not
+		// tell in which order the users have voted.
+		query = "SELECT vote FROM objects WHERE poll_id = ? ORDER BY id;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+		var err error
+		objects, err = queryList[[]byte](ctx, tx, query, pollID)
+		if err != nil {
+			return fmt.Errorf("fetching vote objects: %w", err)
+		}
+
+		query = "SELECT user_id FROM voted WHERE poll_id = ? ORDER BY user_id;"
+		log.Debug("SQL: `%s` (values: %d)", query, pollID)
+		userIDs, err = queryList[int](ctx, tx, query, pollID)
+		if err != nil {
+			return fmt.Errorf("fetching user ids: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return objects, userIDs, stoppedBefore, nil
+}
+
+// Clear removes all data about a poll from the database.
+func (b *Backend) Clear(ctx context.Context, pollID int) error {
+	query := "DELETE FROM poll WHERE id = ?;"
+	log.Debug("SQL: `%s` (values: %d)", query, pollID)
+	if _, err := b.db.ExecContext(ctx, query, pollID); err != nil {
+		return fmt.Errorf("deleting data of poll %d: %w", pollID, err)
+	}
+	return nil
+}
+
+// ClearAll removes all vote related data from the database.
+func (b *Backend) ClearAll(ctx context.Context) error {
+	query := "DELETE FROM poll;"
+	log.Debug("SQL: `%s`", query)
+	if _, err := b.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("deleting all polls: %w", err)
+	}
+	return nil
+}
+
+// Voted returns for all polls the userIDs, that have voted.
+func (b *Backend) Voted(ctx context.Context) (map[int][]int, error) {
+	query := `
+	SELECT poll.id, voted.user_id
+	FROM poll
+	LEFT JOIN voted ON voted.poll_id = poll.id
+	ORDER BY poll.id, voted.user_id;
+	`
+	log.Debug("SQL: `%s`", query)
+
+	rows, err := b.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("fetching voted users: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int][]int)
+	for rows.Next() {
+		var pollID int
+		var userID sql.NullInt64
+		if err := rows.Scan(&pollID, &userID); err != nil {
+			return nil, fmt.Errorf("parsing row: %w", err)
+		}
+
+		if !userID.Valid {
+			out[pollID] = []int{}
+			continue
+		}
+		out[pollID] = append(out[pollID], int(userID.Int64))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("parsing query rows: %w", err)
+	}
+
+	return out, nil
+}
+
+// transaction runs the function in a transaction. The transaction is committed,
+// if the function does not return an error.
+func (b *Backend) transaction(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// queryList returns the values of a query with one column.
+func queryList[T any](ctx context.Context, tx *sql.Tx, query string, args ...any) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sending query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var value T
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("parsing row: %w", err)
+		}
+		out = append(out, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("parsing query rows: %w", err)
+	}
+
+	return out, nil
+}
+
+// randomID returns a random positive id for a vote object.
+func randomID() (int64, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, fmt.Errorf("reading random bytes: %w", err)
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:]) >> 1), nil
+}
+
+type doesNotExistError struct {
+	error
+}
+
+func (doesNotExistError) DoesNotExist() {}
+
+type doubleVoteError struct {
+	error
+}
+
+func (doubleVoteError) DoubleVote() {}
+
+type stoppedError struct {
+	error
+}
+
+func (stoppedError) Stopped() {}
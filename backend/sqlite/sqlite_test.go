@@ -0,0 +1,99 @@
+package sqlite_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenSlides/openslides-vote-service/backend/sqlite"
+	"github.com/OpenSlides/openslides-vote-service/backend/test"
+)
+
+func TestImplementBackendInterface(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "vote.db"))
+	if err != nil {
+		t.Fatalf("Creating sqlite backend returned: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Creating db schema: %v", err)
+	}
+
+	test.Backend(t, s)
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "vote.db")
+
+	s, err := sqlite.New(file)
+	if err != nil {
+		t.Fatalf("Creating sqlite backend returned: %v", err)
+	}
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Creating db schema: %v", err)
+	}
+
+	if err := s.Start(ctx, 1, nil); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if err := s.Vote(ctx, 1, 5, []byte("my vote")); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+
+	s, err = sqlite.New(file)
+	if err != nil {
+		t.Fatalf("Reopening sqlite backend returned: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Migrating existing db: %v", err)
+	}
+
+	objects, userIDs, err := s.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if len(objects) != 1 || string(objects[0]) != "my vote" {
+		t.Errorf("Got objects %q, expected [my vote]", objects)
+	}
+
+	if len(userIDs) != 1 || userIDs[0] != 5 {
+		t.Errorf("Got user ids %v, expected [5]", userIDs)
+	}
+}
+
+func TestSpecialCharactersInPath(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "my votes?#%20")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Creating directory: %v", err)
+	}
+	file := filepath.Join(dir, "vote.db")
+
+	s, err := sqlite.New(file)
+	if err != nil {
+		t.Fatalf("Creating sqlite backend returned: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Creating db schema: %v", err)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Database file was not created at %s: %v", file, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"sync"
 	"testing"
 
@@ -22,14 +23,14 @@ func Backend(t *testing.T, backend vote.Backend) {
 	pollID := 1
 	t.Run("Start", func(t *testing.T) {
 		t.Run("Start unknown poll", func(t *testing.T) {
-			if err := backend.Start(ctx, pollID); err != nil {
+			if err := backend.Start(ctx, pollID, nil); err != nil {
 				t.Errorf("Start an unknown poll returned error: %v", err)
 			}
 		})
 
 		t.Run("Start started poll", func(t *testing.T) {
-			backend.Start(ctx, pollID)
-			if err := backend.Start(ctx, pollID); err != nil {
+			backend.Start(ctx, pollID, nil)
+			if err := backend.Start(ctx, pollID, nil); err != nil {
 				t.Errorf("Start a started poll returned error: %v", err)
 			}
 		})
@@ -39,7 +40,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 				t.Fatalf("Stop returned: %v", err)
 			}
 
-			if err := backend.Start(ctx, pollID); err != nil {
+			if err := backend.Start(ctx, pollID, nil); err != nil {
 				t.Errorf("Start a stopped poll returned error: %v", err)
 			}
 
@@ -63,7 +64,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 		pollID++
 		t.Run("empty poll", func(t *testing.T) {
-			if err := backend.Start(ctx, pollID); err != nil {
+			if err := backend.Start(ctx, pollID, nil); err != nil {
 				t.Fatalf("Start returned unexpected error: %v", err)
 			}
 
@@ -78,6 +79,92 @@ func Backend(t *testing.T, backend vote.Backend) {
 		})
 	})
 
+	pollID++
+	t.Run("Config", func(t *testing.T) {
+		t.Run("poll unknown", func(t *testing.T) {
+			_, err := backend.Config(ctx, 404)
+
+			var errDoesNotExist interface{ DoesNotExist() }
+			if !errors.As(err, &errDoesNotExist) {
+				t.Fatalf("Config of a unknown poll has to return an error with a method DoesNotExist(), got: %v", err)
+			}
+		})
+
+		t.Run("config from start", func(t *testing.T) {
+			if err := backend.Start(ctx, pollID, []byte("my config")); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			config, err := backend.Config(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Config returned unexpected error: %v", err)
+			}
+
+			if string(config) != "my config" {
+				t.Errorf("Got config `%s`, expected `my config`", config)
+			}
+		})
+
+		t.Run("second start does not change the config", func(t *testing.T) {
+			if err := backend.Start(ctx, pollID, []byte("other config")); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			config, err := backend.Config(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Config returned unexpected error: %v", err)
+			}
+
+			if string(config) != "my config" {
+				t.Errorf("Got config `%s`, expected `my config`", config)
+			}
+		})
+
+		t.Run("config after stop", func(t *testing.T) {
+			if _, _, err := backend.Stop(ctx, pollID); err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			config, err := backend.Config(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Config returned unexpected error: %v", err)
+			}
+
+			if string(config) != "my config" {
+				t.Errorf("Got config `%s`, expected `my config`", config)
+			}
+		})
+
+		pollID++
+		t.Run("start without config", func(t *testing.T) {
+			if err := backend.Start(ctx, pollID, nil); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			config, err := backend.Config(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Config returned unexpected error: %v", err)
+			}
+
+			if len(config) != 0 {
+				t.Errorf("Got config `%s`, expected an empty config", config)
+			}
+		})
+
+		t.Run("clear removes the config", func(t *testing.T) {
+			if err := backend.Clear(ctx, pollID); err != nil {
+				t.Fatalf("Clear returned unexpected error: %v", err)
+			}
+
+			_, err := backend.Config(ctx, pollID)
+
+			var errDoesNotExist interface{ DoesNotExist() }
+			if !errors.As(err, &errDoesNotExist) {
+				t.Fatalf("Config of a cleared poll has to return an error with a method DoesNotExist(), got: %v", err)
+			}
+		})
+	})
+
 	pollID++
 	t.Run("Vote", func(t *testing.T) {
 		t.Run("on notstarted poll", func(t *testing.T) {
@@ -90,7 +177,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 		})
 
 		t.Run("successfull", func(t *testing.T) {
-			backend.Start(ctx, pollID)
+			backend.Start(ctx, pollID, nil)
 
 			if err := backend.Vote(ctx, pollID, 5, []byte("my vote")); err != nil {
 				t.Fatalf("Vote returned unexpected error: %v", err)
@@ -120,7 +207,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 		pollID++
 		t.Run("two times", func(t *testing.T) {
-			backend.Start(ctx, pollID)
+			backend.Start(ctx, pollID, nil)
 
 			if err := backend.Vote(ctx, pollID, 5, []byte("my vote")); err != nil {
 				t.Fatalf("Vote returned unexpected error: %v", err)
@@ -140,7 +227,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 		pollID++
 		t.Run("on stopped vote", func(t *testing.T) {
-			backend.Start(ctx, pollID)
+			backend.Start(ctx, pollID, nil)
 
 			if _, _, err := backend.Stop(ctx, pollID); err != nil {
 				t.Fatalf("Stop returned unexpected error: %v", err)
@@ -159,9 +246,123 @@ func Backend(t *testing.T, backend vote.Backend) {
 		})
 	})
 
+	pollID++
+	t.Run("Stop does not return the voting order", func(t *testing.T) {
+		backend.Start(ctx, pollID, nil)
+
+		// The chance, that 20 objects are returned in the order they where
+		// saved or in the reverse order by chance is 2/20! (about 1e-18).
+		count := 20
+		for i := 0; i < count; i++ {
+			if err := backend.Vote(ctx, pollID, i+1, []byte(strconv.Itoa(i))); err != nil {
+				t.Fatalf("Vote returned unexpected error: %v", err)
+			}
+		}
+
+		data, _, err := backend.Stop(ctx, pollID)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(data) != count {
+			t.Fatalf("Found %d vote objects, expected %d", len(data), count)
+		}
+
+		inOrder := true
+		inReverseOrder := true
+		for i, d := range data {
+			if string(d) != strconv.Itoa(i) {
+				inOrder = false
+			}
+			if string(d) != strconv.Itoa(count-1-i) {
+				inReverseOrder = false
+			}
+		}
+
+		if inOrder || inReverseOrder {
+			t.Errorf("Stop returned the vote objects in the order they where saved: %q", data)
+		}
+	})
+
+	pollID++
+	t.Run("Revote", func(t *testing.T) {
+		t.Run("on notstarted poll", func(t *testing.T) {
+			err := backend.Revote(ctx, pollID, 5, []byte("my vote"))
+
+			var errDoesNotExist interface{ DoesNotExist() }
+			if !errors.As(err, &errDoesNotExist) {
+				t.Fatalf("Revote on a not started poll has to return an error with a method DoesNotExist(), got: %v", err)
+			}
+		})
+
+		t.Run("two times", func(t *testing.T) {
+			backend.Start(ctx, pollID, nil)
+
+			if err := backend.Revote(ctx, pollID, 5, []byte("my vote")); err != nil {
+				t.Fatalf("Revote returned unexpected error: %v", err)
+			}
+
+			if err := backend.Revote(ctx, pollID, 6, []byte("other vote")); err != nil {
+				t.Fatalf("Revote returned unexpected error: %v", err)
+			}
+
+			if err := backend.Revote(ctx, pollID, 5, []byte("my second vote")); err != nil {
+				t.Fatalf("Second revote returned unexpected error: %v", err)
+			}
+
+			voted, err := backend.Voted(ctx)
+			if err != nil {
+				t.Fatalf("Voted returned unexpected error: %v", err)
+			}
+
+			sort.Ints(voted[pollID])
+			if !reflect.DeepEqual(voted[pollID], []int{5, 6}) {
+				t.Errorf("Voted returned %v, expected [5 6]", voted[pollID])
+			}
+
+			data, userIDs, err := backend.Stop(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			got := make([]string, len(data))
+			for i, d := range data {
+				got[i] = string(d)
+			}
+			sort.Strings(got)
+
+			if expect := []string{"my second vote", "other vote"}; !reflect.DeepEqual(got, expect) {
+				t.Errorf("Found vote objects %q, expected %q", got, expect)
+			}
+
+			sort.Ints(userIDs)
+			if !reflect.DeepEqual(userIDs, []int{5, 6}) {
+				t.Errorf("Got userIDs %v, expected [5 6]", userIDs)
+			}
+		})
+
+		t.Run("on stopped vote", func(t *testing.T) {
+			err := backend.Revote(ctx, pollID, 5, []byte("my third vote"))
+
+			var errStopped interface{ Stopped() }
+			if !errors.As(err, &errStopped) {
+				t.Fatalf("Revote has to return a error with method Stopped. Got: %v", err)
+			}
+
+			data, _, err := backend.Stop(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			if len(data) != 2 {
+				t.Errorf("Found %d vote objects after revote on stopped poll, expected 2", len(data))
+			}
+		})
+	})
+
 	pollID++
 	t.Run("Clear removes vote data", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 
 		if err := backend.Clear(ctx, pollID); err != nil {
@@ -185,14 +386,14 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 	pollID++
 	t.Run("Clear removes voted users", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 
 		if err := backend.Clear(ctx, pollID); err != nil {
 			t.Fatalf("Clear returned unexpected error: %v", err)
 		}
 
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 
 		// Vote on the same poll with the same user id
 		if err := backend.Vote(ctx, pollID, 5, []byte("my vote")); err != nil {
@@ -202,7 +403,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 	pollID++
 	t.Run("ClearAll removes vote data", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 
 		if err := backend.ClearAll(ctx); err != nil {
@@ -226,14 +427,14 @@ func Backend(t *testing.T, backend vote.Backend) {
 
 	pollID++
 	t.Run("ClearAll removes voted users", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 
 		if err := backend.ClearAll(ctx); err != nil {
 			t.Fatalf("ClearAll returned unexpected error: %v", err)
 		}
 
-		if err := backend.Start(ctx, pollID); err != nil {
+		if err := backend.Start(ctx, pollID, nil); err != nil {
 			t.Fatalf("Start after clearAll returned unexpected error: %v", err)
 		}
 
@@ -246,7 +447,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 	backend.ClearAll(ctx)
 	pollID++
 	t.Run("Voted", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 
 		got, err := backend.Voted(ctx)
@@ -263,7 +464,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 	backend.ClearAll(ctx)
 	pollID++
 	t.Run("Voted for many users", func(t *testing.T) {
-		backend.Start(ctx, pollID)
+		backend.Start(ctx, pollID, nil)
 		backend.Vote(ctx, pollID, 5, []byte("my vote"))
 		backend.Vote(ctx, pollID, 6, []byte("my vote"))
 
@@ -282,7 +483,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 	t.Run("Concurrency", func(t *testing.T) {
 		t.Run("Many Votes", func(t *testing.T) {
 			count := 100
-			backend.Start(ctx, pollID)
+			backend.Start(ctx, pollID, nil)
 
 			var wg sync.WaitGroup
 			for i := 0; i < count; i++ {
@@ -318,6 +519,38 @@ func Backend(t *testing.T, backend vote.Backend) {
 			}
 		})
 
+		pollID++
+		t.Run("Many Revotes", func(t *testing.T) {
+			count := 50
+			backend.Start(ctx, pollID, nil)
+
+			var wg sync.WaitGroup
+			for i := 0; i < count; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					if err := backend.Revote(ctx, pollID, 1, []byte("vote")); err != nil {
+						t.Errorf("Revote returned undexpected error: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			data, userIDs, err := backend.Stop(ctx, pollID)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			if len(data) != 1 {
+				t.Errorf("Found %d vote objects, expected 1", len(data))
+			}
+
+			if len(userIDs) != 1 {
+				t.Errorf("Found %d userIDs, expected 1", len(userIDs))
+			}
+		})
+
 		pollID++
 		t.Run("Many starts and stops", func(t *testing.T) {
 			starts := 50
@@ -329,7 +562,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 				go func() {
 					defer wg.Done()
 
-					if err := backend.Start(ctx, pollID); err != nil {
+					if err := backend.Start(ctx, pollID, nil); err != nil {
 						t.Errorf("Start returned undexpected error: %v", err)
 					}
 				}()
@@ -358,7 +591,7 @@ func Backend(t *testing.T, backend vote.Backend) {
 			stopsCount := 50
 			votesCount := 50
 
-			backend.Start(ctx, pollID)
+			backend.Start(ctx, pollID, nil)
 
 			expectedObjects := make([][][]byte, stopsCount)
 			expectedUserIDs := make([][]int, stopsCount)
@@ -421,4 +654,95 @@ func Backend(t *testing.T, backend vote.Backend) {
 			}
 		})
 	})
+
+	if stopper, ok := backend.(vote.StopChecker); ok {
+		pollID++
+		t.Run("StopChecked", func(t *testing.T) {
+			if err := backend.Start(ctx, pollID, nil); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			if err := backend.Vote(ctx, pollID, 5, []byte("my vote")); err != nil {
+				t.Fatalf("Vote returned unexpected error: %v", err)
+			}
+
+			objects, userIDs, stoppedBefore, err := stopper.StopChecked(ctx, pollID)
+			if err != nil {
+				t.Fatalf("StopChecked returned unexpected error: %v", err)
+			}
+
+			if stoppedBefore {
+				t.Errorf("First StopChecked reported, that the poll was stopped before")
+			}
+
+			if len(objects) != 1 || !reflect.DeepEqual(userIDs, []int{5}) {
+				t.Errorf("StopChecked returned (%q, %v), expected one object from user 5", objects, userIDs)
+			}
+
+			if _, _, stoppedBefore, err := stopper.StopChecked(ctx, pollID); err != nil || !stoppedBefore {
+				t.Errorf("Second StopChecked returned (%t, %v), expected the poll to be stopped before", stoppedBefore, err)
+			}
+
+			_, _, _, err = stopper.StopChecked(ctx, 404)
+			var errDoesNotExist interface{ DoesNotExist() }
+			if !errors.As(err, &errDoesNotExist) {
+				t.Errorf("StopChecked on an unknown poll returned %v, expected DoesNotExist()", err)
+			}
+		})
+	}
+
+	importer, ok := backend.(vote.Importer)
+	if !ok {
+		return
+	}
+
+	pollID++
+	t.Run("Import", func(t *testing.T) {
+		objects := [][]byte{[]byte("vote1"), []byte("vote2")}
+		if err := importer.Import(ctx, pollID, []byte("config"), []int{5, 6}, objects); err != nil {
+			t.Fatalf("Import returned unexpected error: %v", err)
+		}
+
+		config, err := backend.Config(ctx, pollID)
+		if err != nil {
+			t.Fatalf("Config returned unexpected error: %v", err)
+		}
+
+		if string(config) != "config" {
+			t.Errorf("Got config %q, expected config", config)
+		}
+
+		err = backend.Vote(ctx, pollID, 5, []byte("vote3"))
+		var errDoubleVote interface{ DoubleVote() }
+		if !errors.As(err, &errDoubleVote) {
+			t.Errorf("Vote of an imported user returned %v, expected a double vote error", err)
+		}
+
+		if err := backend.Vote(ctx, pollID, 7, []byte("vote3")); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		if err := importer.Import(ctx, pollID, []byte("other config"), nil, nil); err == nil {
+			t.Errorf("Import of an existing poll returned no error")
+		}
+
+		gotObjects, userIDs, err := backend.Stop(ctx, pollID)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		got := make([]string, len(gotObjects))
+		for i, object := range gotObjects {
+			got[i] = string(object)
+		}
+		sort.Strings(got)
+
+		if expect := []string{"vote1", "vote2", "vote3"}; !reflect.DeepEqual(got, expect) {
+			t.Errorf("Stop returned objects %v, expected %v", got, expect)
+		}
+
+		if expect := []int{5, 6, 7}; !reflect.DeepEqual(userIDs, expect) {
+			t.Errorf("Stop returned user ids %v, expected %v", userIDs, expect)
+		}
+	})
 }
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	golog "log"
@@ -31,6 +34,20 @@ var cli struct {
 		UseHTTPS bool   `help:"Use https to connect to the service" short:"s"`
 		Insecure bool   `help:"Accept invalid cert" short:"k"`
 	} `cmd:"" help:"Runs a health check."`
+	Verify struct {
+		File      string `arg:"" help:"File with the result of a stop request." type:"existingfile"`
+		Root      string `help:"Published merkle root of the poll."`
+		PublicKey string `help:"Base64 encoded public key of the service to check the signature."`
+	} `cmd:"" help:"Verifies an exported poll result."`
+	EncryptionKey struct {
+		Threshold   int  `help:"Number of trustees needed to decrypt the votes." required:""`
+		Trustees    int  `help:"Number of trustees." required:""`
+		Homomorphic bool `help:"Create a key for a homomorphic poll."`
+	} `cmd:"" help:"Creates a key for an encrypted poll and splits it between the trustees."`
+	PartialDecryption struct {
+		File  string `arg:"" help:"File with the aggregate of a stopped homomorphic poll." type:"existingfile"`
+		Share string `help:"Share of the trustee." required:""`
+	} `cmd:"" help:"Creates the partial decryption of a trustee for a homomorphic poll."`
 }
 
 func main() {
@@ -57,6 +74,25 @@ func main() {
 			handleError(err)
 			os.Exit(1)
 		}
+
+	case "verify <file>":
+		if err := verify(cli.Verify.File, cli.Verify.Root, cli.Verify.PublicKey); err != nil {
+			handleError(err)
+			os.Exit(1)
+		}
+		fmt.Println("The result is valid.")
+
+	case "encryption-key":
+		if err := encryptionKey(cli.EncryptionKey.Threshold, cli.EncryptionKey.Trustees, cli.EncryptionKey.Homomorphic); err != nil {
+			handleError(err)
+			os.Exit(1)
+		}
+
+	case "partial-decryption <file>":
+		if err := partialDecryption(cli.PartialDecryption.File, cli.PartialDecryption.Share); err != nil {
+			handleError(err)
+			os.Exit(1)
+		}
 	}
 }
 
@@ -91,13 +127,97 @@ func buildDocu() error {
 	return nil
 }
 
+func verify(file, root, rawPublicKey string) error {
+	var publicKey ed25519.PublicKey
+	if rawPublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(rawPublicKey)
+		if err != nil {
+			return fmt.Errorf("decoding public key: %w", err)
+		}
+
+		if len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("public key has %d bytes, expected %d", len(key), ed25519.PublicKeySize)
+		}
+		publicKey = key
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("open result file: %w", err)
+	}
+	defer f.Close()
+
+	if err := vote.VerifyResult(f, root, publicKey); err != nil {
+		return fmt.Errorf("verify result: %w", err)
+	}
+	return nil
+}
+
+func encryptionKey(threshold, trustees int, homomorphic bool) error {
+	newKey := vote.NewEncryptionKey
+	if homomorphic {
+		newKey = vote.NewHomomorphicKey
+	}
+
+	publicKey, shares, err := newKey(threshold, trustees)
+	if err != nil {
+		return fmt.Errorf("creating encryption key: %w", err)
+	}
+
+	out := struct {
+		EncryptionKey []byte   `json:"encryption_key"`
+		Shares        []string `json:"shares"`
+	}{
+		publicKey,
+		shares,
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return fmt.Errorf("encoding key: %w", err)
+	}
+	return nil
+}
+
+func partialDecryption(file, share string) error {
+	aggregate, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading aggregate file: %w", err)
+	}
+
+	partial, err := vote.PartialDecrypt(share, aggregate)
+	if err != nil {
+		return fmt.Errorf("creating partial decryption: %w", err)
+	}
+
+	fmt.Println(string(partial))
+	return nil
+}
+
 // initService initializes all packages needed for the vote service.
 //
 // Returns a the service as callable.
 func initService(lookup environment.Environmenter) (func(context.Context) error, error) {
 	var backgroundTasks []func(context.Context, func(error))
 
-	httpServer := http.New(lookup)
+	// Key to sign the poll results.
+	signingKey, err := vote.SigningKey(lookup)
+	if err != nil {
+		return nil, fmt.Errorf("init signing key: %w", err)
+	}
+
+	httpServer := http.New(lookup, signingKey)
+
+	anonymityThreshold, err := vote.AnonymityThreshold(lookup)
+	if err != nil {
+		return nil, fmt.Errorf("init anonymity threshold: %w", err)
+	}
+
+	failover, err := vote.FailoverEnabled(lookup)
+	if err != nil {
+		return nil, fmt.Errorf("init failover: %w", err)
+	}
 
 	// Redis as message bus for datastore and logout events.
 	messageBus := messageBusRedis.New(lookup)
@@ -115,23 +235,32 @@ func initService(lookup environment.Environmenter) (func(context.Context) error,
 	}
 	backgroundTasks = append(backgroundTasks, authBackground)
 
-	fastBackendStarter, longBackendStarter, singleInstance, err := backend.Build(lookup)
+	backendBuilders, singleInstance, err := backend.Build(lookup)
 	if err != nil {
 		return nil, fmt.Errorf("init vote backend: %w", err)
 	}
 
 	service := func(ctx context.Context) error {
-		fastBackend, err := fastBackendStarter(ctx)
-		if err != nil {
-			return fmt.Errorf("start fast backend: %w", err)
+		backends := make(map[string]vote.Backend, len(backendBuilders))
+		for name, build := range backendBuilders {
+			b, err := build(ctx)
+			if err != nil {
+				return fmt.Errorf("start %s backend: %w", name, err)
+			}
+			backends[name] = b
 		}
 
-		longBackend, err := longBackendStarter(ctx)
-		if err != nil {
-			return fmt.Errorf("start long backend: %w", err)
+		options := []vote.Option{vote.WithAnonymityThreshold(anonymityThreshold)}
+		if failover {
+			options = append(options, vote.WithFailover())
+		}
+		for name, b := range backends {
+			if name != "fast" && name != "long" {
+				options = append(options, vote.WithBackend(name, b))
+			}
 		}
 
-		voteService, voteBackground, err := vote.New(ctx, fastBackend, longBackend, database, singleInstance)
+		voteService, voteBackground, err := vote.New(ctx, backends["fast"], backends["long"], database, singleInstance, options...)
 		if err != nil {
 			return fmt.Errorf("starting service: %w", err)
 		}
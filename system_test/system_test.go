@@ -63,7 +63,7 @@ func TestStartVoteStopClear(t *testing.T) {
 		t.Fatalf("Stop poll: %v", err)
 	}
 
-	expectBody := `{"votes":[{"request_user_id":1,"vote_user_id":1,"value":"Y","weight":"1.000000"}],"user_ids":[1]}`
+	expectBody := `{"votes":[{"request_user_id":1,"vote_user_id":1,"value":"Y","weight":"1.000000"}],"user_ids":[1],"tally":{"ballots":1,"invalid":0,"votescast":"1.000000","votesvalid":"1.000000","global":{"Y":"1.000000"}},"config_changed":false,"turnout":{"entitled":1,"entitled_weight":"1.000000","voted":1,"voted_weight":"1.000000"},"merkle":{"root":"965dc75916659fd4d16760b97b055b96684ecea11b2e5a767f2d6434d8f01f22","proofs":[{"path":[]}]}}`
 	if strings.TrimSpace(string(stopBody)) != expectBody {
 		t.Fatalf("Got != expect\n%s\n%s", stopBody, expectBody)
 	}
@@ -0,0 +1,43 @@
+package vote
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
+)
+
+var envAnonymityThreshold = environment.NewVariable("VOTE_ANONYMITY_THRESHOLD", "0", "Minimum number of voters in a poll that is not named, so that the vote objects are published when the poll is stopped. With less voters, only the tally is returned. 0 disables the check.")
+
+// AnonymityThreshold reads the minimum number of voters from the environment,
+// that are needed to publish the vote objects of a poll that is not named.
+func AnonymityThreshold(lookup environment.Environmenter) (int, error) {
+	raw := envAnonymityThreshold.Value(lookup)
+	threshold, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s. Expected int, got %s", envAnonymityThreshold.Key, raw)
+	}
+
+	if threshold < 0 {
+		return 0, fmt.Errorf("invalid value for %s. Expected a positive number, got %d", envAnonymityThreshold.Key, threshold)
+	}
+
+	return threshold, nil
+}
+
+// WithAnonymityThreshold sets the minimum number of voters in a poll that is
+// not named, so that Stop returns the vote objects.
+//
+// If less users have voted, a single vote object could be linked to its voter.
+// In this case, Stop only returns the aggregated result.
+func WithAnonymityThreshold(threshold int) Option {
+	return func(v *Vote) {
+		v.anonymityThreshold = threshold
+	}
+}
+
+// withholdBallots returns true, if the vote objects of the poll must not be
+// published, because too few users have voted.
+func (v *Vote) withholdBallots(poll pollConfig, voterCount int) bool {
+	return poll.ptype != "named" && voterCount < v.anonymityThreshold
+}
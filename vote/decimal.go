@@ -0,0 +1,169 @@
+package vote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimalPlaces is the number of digits after the decimal point of a
+// DecimalField in the datastore.
+const decimalPlaces = 6
+
+// decimalFactor is 10^decimalPlaces.
+const decimalFactor = 1_000_000
+
+// errDecimalOverflow is returned, if a value does not fit into a Decimal.
+var errDecimalOverflow = errors.New("decimal overflow")
+
+// Decimal is a fixed point number with six digits after the decimal point.
+//
+// It is used to sum vote weights without rounding errors. The value is saved
+// as the number of millionths.
+type Decimal int64
+
+// ParseDecimal parses a string like "1.000000" to a Decimal.
+//
+// It accepts at most six digits after the decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty decimal")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("invalid decimal")
+	}
+
+	if len(fracPart) > decimalPlaces {
+		return 0, fmt.Errorf("decimal %s has more then %d decimal places", s, decimalPlaces)
+	}
+
+	var value int64
+	if intPart != "" {
+		i, err := strconv.ParseUint(intPart, 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("parsing integer part of %s: %w", s, err)
+		}
+
+		if i > math.MaxInt64/decimalFactor {
+			return 0, fmt.Errorf("decimal %s is too big: %w", s, errDecimalOverflow)
+		}
+		value = int64(i) * decimalFactor
+	}
+
+	if fracPart != "" {
+		fracPart += strings.Repeat("0", decimalPlaces-len(fracPart))
+		f, err := strconv.ParseUint(fracPart, 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("parsing fraction part of %s: %w", s, err)
+		}
+		if value > math.MaxInt64-int64(f) {
+			return 0, fmt.Errorf("decimal %s is too big: %w", s, errDecimalOverflow)
+		}
+		value += int64(f)
+	}
+
+	if negative {
+		value = -value
+	}
+	return Decimal(value), nil
+}
+
+// DecimalFromInt converts an integer to a Decimal.
+func DecimalFromInt(i int) Decimal {
+	return Decimal(int64(i) * decimalFactor)
+}
+
+// checkedAdd returns d + other or an error, if the sum does not fit into a
+// Decimal.
+func (d Decimal) checkedAdd(other Decimal) (Decimal, error) {
+	if (other > 0 && d > math.MaxInt64-other) || (other < 0 && d < math.MinInt64-other) {
+		return 0, fmt.Errorf("adding %s and %s: %w", d, other, errDecimalOverflow)
+	}
+	return d + other, nil
+}
+
+// checkedMul returns d * factor or an error, if the product does not fit into
+// a Decimal.
+func (d Decimal) checkedMul(factor int) (Decimal, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(factor)))
+	if !product.IsInt64() {
+		return 0, fmt.Errorf("multiplying %s with %d: %w", d, factor, errDecimalOverflow)
+	}
+	return Decimal(product.Int64()), nil
+}
+
+// Div divides the decimal by another decimal.
+//
+// The result is rounded half away from zero to six decimal places. Dividing by
+// zero returns zero. If the quotient does not fit into a Decimal, an error is
+// returned.
+func (d Decimal) Div(divisor Decimal) (Decimal, error) {
+	if divisor == 0 {
+		return 0, nil
+	}
+
+	num := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(decimalFactor))
+	den := big.NewInt(int64(divisor))
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Round half away from zero.
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign() == den.Sign() {
+			quo.Add(quo, big.NewInt(1))
+		} else {
+			quo.Sub(quo, big.NewInt(1))
+		}
+	}
+
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("dividing %s by %s: %w", d, divisor, errDecimalOverflow)
+	}
+	return Decimal(quo.Int64()), nil
+}
+
+// String returns the decimal with six digits after the decimal point.
+func (d Decimal) String() string {
+	sign := ""
+	v := int64(d)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, v/decimalFactor, v%decimalFactor)
+}
+
+// MarshalJSON encodes the decimal as string like the datastore does.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a decimal from a json string.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("decoding decimal as string: %w", err)
+	}
+
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,412 @@
+package vote
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+)
+
+// homomorphicShare is the share of a trustee of the private key of a
+// homomorphic poll.
+//
+// The private key x is split with a random polynomial f over Z_q with f(0) = x.
+// The share of the trustee with the index i is f(i).
+type homomorphicShare struct {
+	threshold int
+	index     int
+	value     *big.Int
+}
+
+// splitHomomorphicKey splits the private key of a homomorphic poll into one
+// share for each trustee.
+//
+// Each share is the base64 encoded threshold, the index and the value of the
+// share as 256 bytes big endian.
+func splitHomomorphicKey(x *big.Int, threshold, trustees int) ([]string, error) {
+	if threshold < 1 || threshold > trustees {
+		return nil, fmt.Errorf("threshold has to be between 1 and %d, got %d", trustees, threshold)
+	}
+
+	if trustees > 255 {
+		return nil, fmt.Errorf("can not create more than 255 shares, got %d", trustees)
+	}
+
+	coefficients := make([]*big.Int, threshold)
+	coefficients[0] = x
+	for i := 1; i < threshold; i++ {
+		c, err := rand.Int(rand.Reader, groupQ)
+		if err != nil {
+			return nil, fmt.Errorf("reading random coefficient: %w", err)
+		}
+		coefficients[i] = c
+	}
+
+	shares := make([]string, trustees)
+	for i := range shares {
+		index := big.NewInt(int64(i + 1))
+
+		// Horner's method.
+		value := new(big.Int)
+		for j := len(coefficients) - 1; j >= 0; j-- {
+			value.Mul(value, index).Add(value, coefficients[j]).Mod(value, groupQ)
+		}
+
+		raw := append([]byte{byte(threshold), byte(i + 1)}, encodeElement(value)...)
+		shares[i] = base64.StdEncoding.EncodeToString(raw)
+	}
+	return shares, nil
+}
+
+// parseHomomorphicShare decodes a share created by splitHomomorphicKey.
+func parseHomomorphicShare(encoded string) (homomorphicShare, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return homomorphicShare{}, fmt.Errorf("decoding share: %w", err)
+	}
+
+	if len(raw) != 2+groupElementSize || raw[0] == 0 || raw[1] == 0 {
+		return homomorphicShare{}, fmt.Errorf("invalid share")
+	}
+
+	value := new(big.Int).SetBytes(raw[2:])
+	if value.Cmp(groupQ) >= 0 {
+		return homomorphicShare{}, fmt.Errorf("invalid share")
+	}
+
+	return homomorphicShare{threshold: int(raw[0]), index: int(raw[1]), value: value}, nil
+}
+
+// homomorphicAggregate is the product of the ciphertexts of all valid ballots
+// of a homomorphic poll. Each ciphertext is raised to the vote weight of its
+// ballot.
+type homomorphicAggregate struct {
+	PollID int `json:"poll_id"`
+
+	// Options contains for each option one ciphertext for each answer.
+	Options map[int][]homomorphicCiphertext `json:"options"`
+}
+
+// partialDecryption is the part of a trustee to decrypt the aggregate of a
+// homomorphic poll.
+//
+// For each ciphertext (a, b) of the aggregate, it contains d = a^xi, where xi
+// is the share of the trustee, with a proof, that d and the verification key
+// g^xi use the same exponent.
+type partialDecryption struct {
+	Threshold       int                       `json:"threshold"`
+	Index           int                       `json:"index"`
+	VerificationKey []byte                    `json:"verification_key"`
+	Options         map[int][]decryptionShare `json:"options"`
+}
+
+// decryptionShare is the partial decryption of one ciphertext with a
+// Chaum-Pedersen proof.
+type decryptionShare struct {
+	D []byte `json:"d"`
+	C []byte `json:"c"`
+	S []byte `json:"s"`
+}
+
+// PartialDecrypt creates the partial decryption of a trustee for the aggregate
+// of a homomorphic poll.
+//
+// The aggregate is the json returned by the aggregate request. The private key
+// of the poll is never created. The service combines the partial decryptions
+// of threshold trustees to decrypt the totals.
+func PartialDecrypt(encodedShare string, rawAggregate []byte) (json.RawMessage, error) {
+	share, err := parseHomomorphicShare(encodedShare)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggregate homomorphicAggregate
+	if err := json.Unmarshal(rawAggregate, &aggregate); err != nil {
+		return nil, fmt.Errorf("decoding aggregate: %w", err)
+	}
+
+	verificationKey := new(big.Int).Exp(groupG, share.value, groupP)
+	partial := partialDecryption{
+		Threshold:       share.threshold,
+		Index:           share.index,
+		VerificationKey: encodeElement(verificationKey),
+		Options:         make(map[int][]decryptionShare, len(aggregate.Options)),
+	}
+
+	for optionID, ciphertexts := range aggregate.Options {
+		for i, c := range ciphertexts {
+			a := new(big.Int).SetBytes(c.A)
+			if !validElement(a) {
+				return nil, fmt.Errorf("option %d has an invalid ciphertext", optionID)
+			}
+
+			d := new(big.Int).Exp(a, share.value, groupP)
+			proof, err := proveDecryption(decryptionContext(aggregate.PollID, optionID, i), verificationKey, a, d, share.value)
+			if err != nil {
+				return nil, fmt.Errorf("creating proof: %w", err)
+			}
+
+			partial.Options[optionID] = append(partial.Options[optionID], proof)
+		}
+	}
+
+	return json.Marshal(partial)
+}
+
+// proveDecryption creates a proof, that log_g(h) = log_a(d) = x.
+func proveDecryption(context []byte, h, a, d, x *big.Int) (decryptionShare, error) {
+	w, err := randomExponent()
+	if err != nil {
+		return decryptionShare{}, err
+	}
+
+	t1 := new(big.Int).Exp(groupG, w, groupP)
+	t2 := new(big.Int).Exp(a, w, groupP)
+	c := challenge(context, h, a, d, t1, t2)
+
+	s := new(big.Int).Mul(c, x)
+	s.Add(s, w).Mod(s, groupQ)
+
+	return decryptionShare{D: encodeElement(d), C: c.Bytes(), S: s.Bytes()}, nil
+}
+
+// verifyDecryption checks a proof created by proveDecryption.
+func verifyDecryption(context []byte, h, a *big.Int, share decryptionShare) bool {
+	d := new(big.Int).SetBytes(share.D)
+	c := new(big.Int).SetBytes(share.C)
+	s := new(big.Int).SetBytes(share.S)
+	if !validElement(d) || c.Cmp(groupQ) >= 0 || s.Cmp(groupQ) >= 0 {
+		return false
+	}
+
+	t1 := commitment(groupG, h, c, s)
+	t2 := commitment(a, d, c, s)
+	return challenge(context, h, a, d, t1, t2).Cmp(c) == 0
+}
+
+// decryptionContext binds a proof of a partial decryption to the poll, the
+// option and the answer.
+func decryptionContext(pollID, optionID, index int) []byte {
+	return fmt.Appendf(nil, "openslides-vote-decryption/%d/%d/%d", pollID, optionID, index)
+}
+
+// verifyPartialDecryption checks, that a partial decryption has a valid proof
+// for each ciphertext of the aggregate.
+//
+// It can not check, that the verification key belongs to a share of the poll.
+// This is checked, when the partial decryptions are combined.
+func verifyPartialDecryption(aggregate homomorphicAggregate, partial partialDecryption) error {
+	if partial.Threshold < 1 || partial.Index < 1 || partial.Index > 255 {
+		return fmt.Errorf("invalid threshold or index")
+	}
+
+	h := new(big.Int).SetBytes(partial.VerificationKey)
+	if !validElement(h) {
+		return fmt.Errorf("invalid verification key")
+	}
+
+	if len(partial.Options) != len(aggregate.Options) {
+		return fmt.Errorf("expected a partial decryption for each of the %d options, got %d", len(aggregate.Options), len(partial.Options))
+	}
+
+	for optionID, ciphertexts := range aggregate.Options {
+		shares := partial.Options[optionID]
+		if len(shares) != len(ciphertexts) {
+			return fmt.Errorf("option %d needs %d partial decryptions", optionID, len(ciphertexts))
+		}
+
+		for i, c := range ciphertexts {
+			a := new(big.Int).SetBytes(c.A)
+			if !verifyDecryption(decryptionContext(aggregate.PollID, optionID, i), h, a, shares[i]) {
+				return fmt.Errorf("invalid proof for answer %d of option %d", i, optionID)
+			}
+		}
+	}
+	return nil
+}
+
+// combineDecryptions combines the partial decryptions of threshold trustees
+// with Lagrange interpolation in the exponent. It returns a^x for each
+// ciphertext of the aggregate, where x is the private key of the poll.
+//
+// The partial decryptions are not trusted. They are combined, until the
+// verification keys of the combination interpolate to the public key of the
+// poll.
+func combineDecryptions(poll pollConfig, aggregate homomorphicAggregate, partials []partialDecryption) (map[int][]*big.Int, error) {
+	if len(partials) == 0 {
+		return nil, MessageError(ErrInvalid, "Poll %d is stopped. The trustees have to send their partial decryptions of the aggregate", poll.id)
+	}
+
+	byThreshold := make(map[int][]partialDecryption)
+	for _, partial := range partials {
+		byThreshold[partial.Threshold] = append(byThreshold[partial.Threshold], partial)
+	}
+
+	thresholds := slices.Sorted(maps.Keys(byThreshold))
+	publicKey := new(big.Int).SetBytes(poll.encryptionKey)
+
+	var enough bool
+	var tries int
+	for _, threshold := range thresholds {
+		candidates := byThreshold[threshold]
+		if len(candidates) < threshold {
+			continue
+		}
+		enough = true
+
+		var found []partialDecryption
+		var coefficients []*big.Int
+		combinations(candidates, threshold, func(combination []partialDecryption) bool {
+			tries++
+			if tries > maxShareCombinations {
+				return false
+			}
+
+			lambdas, ok := lagrangeCoefficients(combination)
+			if !ok {
+				return true
+			}
+
+			h := big.NewInt(1)
+			for i, partial := range combination {
+				hi := new(big.Int).Exp(new(big.Int).SetBytes(partial.VerificationKey), lambdas[i], groupP)
+				h.Mul(h, hi).Mod(h, groupP)
+			}
+
+			if h.Cmp(publicKey) != 0 {
+				return true
+			}
+
+			found = slices.Clone(combination)
+			coefficients = lambdas
+			return false
+		})
+
+		if found == nil {
+			continue
+		}
+
+		combined := make(map[int][]*big.Int, len(aggregate.Options))
+		for optionID, ciphertexts := range aggregate.Options {
+			combined[optionID] = make([]*big.Int, len(ciphertexts))
+			for i := range ciphertexts {
+				value := big.NewInt(1)
+				for j, partial := range found {
+					d := new(big.Int).SetBytes(partial.Options[optionID][i].D)
+					value.Mul(value, d.Exp(d, coefficients[j], groupP)).Mod(value, groupP)
+				}
+				combined[optionID][i] = value
+			}
+		}
+		return combined, nil
+	}
+
+	if !enough {
+		return nil, MessageError(ErrInvalid, "Poll %d is stopped. It needs %d partial decryptions to be decrypted, got %d", poll.id, thresholds[0], len(byThreshold[thresholds[0]]))
+	}
+	return nil, MessageError(ErrInvalid, "The partial decryptions do not belong to poll %d", poll.id)
+}
+
+// lagrangeCoefficients returns the Lagrange coefficients at 0 for the indexes
+// of the partial decryptions. It returns false, if an index is used twice.
+func lagrangeCoefficients(partials []partialDecryption) ([]*big.Int, bool) {
+	lambdas := make([]*big.Int, len(partials))
+	for i, pi := range partials {
+		num := big.NewInt(1)
+		den := big.NewInt(1)
+		for j, pj := range partials {
+			if i == j {
+				continue
+			}
+
+			if pi.Index == pj.Index {
+				return nil, false
+			}
+
+			num.Mul(num, big.NewInt(int64(pj.Index))).Mod(num, groupQ)
+			den.Mul(den, big.NewInt(int64(pj.Index-pi.Index))).Mod(den, groupQ)
+		}
+
+		lambdas[i] = num.Mul(num, den.ModInverse(den, groupQ)).Mod(num, groupQ)
+	}
+	return lambdas, true
+}
+
+// Aggregate returns the aggregate of a stopped homomorphic poll. The trustees
+// need it to create their partial decryptions.
+//
+// The aggregate is created by the stop request and only saved in the memory of
+// this instance.
+func (v *Vote) Aggregate(ctx context.Context, pollID int) (json.RawMessage, error) {
+	v.sharesMu.Lock()
+	aggregate, ok := v.aggregates[pollID]
+	v.sharesMu.Unlock()
+
+	if !ok {
+		return nil, MessageError(ErrInvalid, "Poll %d is not a stopped homomorphic poll", pollID)
+	}
+
+	return json.Marshal(aggregate)
+}
+
+// AddDecryption saves the partial decryption of a trustee for a stopped
+// homomorphic poll. When enough partial decryptions are saved, the stop
+// request returns the result.
+//
+// Sending the same partial decryption twice is ok.
+func (v *Vote) AddDecryption(ctx context.Context, pollID int, raw []byte) error {
+	var partial partialDecryption
+	if err := json.Unmarshal(raw, &partial); err != nil {
+		return MessageError(ErrInvalid, "decoding partial decryption: %v", err)
+	}
+
+	v.sharesMu.Lock()
+	defer v.sharesMu.Unlock()
+
+	aggregate, ok := v.aggregates[pollID]
+	if !ok {
+		return MessageError(ErrInvalid, "Poll %d is not a stopped homomorphic poll", pollID)
+	}
+
+	if err := verifyPartialDecryption(aggregate, partial); err != nil {
+		return MessageError(ErrInvalid, "Invalid partial decryption: %v", err)
+	}
+
+	for _, saved := range v.decryptions[pollID] {
+		if saved.Threshold == partial.Threshold && saved.Index == partial.Index && bytes.Equal(saved.VerificationKey, partial.VerificationKey) {
+			return nil
+		}
+	}
+
+	v.decryptions[pollID] = append(v.decryptions[pollID], partial)
+	return nil
+}
+
+// saveAggregate saves the aggregate of a stopped homomorphic poll and returns
+// the partial decryptions for it.
+//
+// If there was another aggregate for the poll, its partial decryptions are
+// removed.
+func (v *Vote) saveAggregate(aggregate homomorphicAggregate) []partialDecryption {
+	v.sharesMu.Lock()
+	defer v.sharesMu.Unlock()
+
+	if saved, ok := v.aggregates[aggregate.PollID]; ok && !saved.equal(aggregate) {
+		delete(v.decryptions, aggregate.PollID)
+	}
+
+	v.aggregates[aggregate.PollID] = aggregate
+	return slices.Clone(v.decryptions[aggregate.PollID])
+}
+
+// equal returns true, if both aggregates have the same ciphertexts.
+func (a homomorphicAggregate) equal(other homomorphicAggregate) bool {
+	b1, err1 := json.Marshal(a)
+	b2, err2 := json.Marshal(other)
+	return err1 == nil && err2 == nil && bytes.Equal(b1, b2)
+}
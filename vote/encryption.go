@@ -0,0 +1,461 @@
+package vote
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
+)
+
+// encryptedPollType is the poll type of polls, where the clients encrypt their
+// ballots.
+const encryptedPollType = "encrypted"
+
+// Encrypted sets the public key of an encrypted poll. It is required for polls
+// with the type `encrypted`.
+func Encrypted(publicKey []byte) StartOption {
+	return func(p *pollConfig) {
+		p.encryptionKey = publicKey
+	}
+}
+
+// NewEncryptionKey creates a key pair for an encrypted poll.
+//
+// It returns the public key and the private key split into one share for each
+// trustee. Each threshold shares can decrypt the votes of the poll.
+func NewEncryptionKey(threshold, trustees int) ([]byte, []string, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	shares, err := splitTrusteeShares(key.Bytes(), threshold, trustees)
+	if err != nil {
+		return nil, nil, fmt.Errorf("splitting private key: %w", err)
+	}
+
+	return key.PublicKey().Bytes(), shares, nil
+}
+
+// EncryptBallot encrypts the value of a ballot with the public key of a poll.
+//
+// The result is the base64 encoded ephemeral public key, the nonce and the
+// AES-GCM encrypted value. The poll id is the associated data, so the value can
+// not be used for another poll with the same key.
+func EncryptBallot(publicKey []byte, pollID int, value []byte) (string, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("generating ephemeral key: %w", err)
+	}
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return "", fmt.Errorf("calculating shared secret: %w", err)
+	}
+
+	aead, err := ballotCipher(shared, ephemeral.PublicKey().Bytes(), publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("reading nonce: %w", err)
+	}
+
+	ciphertext := append(ephemeral.PublicKey().Bytes(), nonce...)
+	ciphertext = aead.Seal(ciphertext, nonce, value, ballotAssociatedData(pollID))
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptBallot decrypts a value created by EncryptBallot.
+func decryptBallot(key *ecdh.PrivateKey, pollID int, encrypted string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decoding ciphertext: %w", err)
+	}
+
+	if len(ciphertext) < minCiphertextSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(ciphertext[:32])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("calculating shared secret: %w", err)
+	}
+
+	aead, err := ballotCipher(shared, ciphertext[:32], key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := ciphertext[32 : 32+aead.NonceSize()]
+	value, err := aead.Open(nil, nonce, ciphertext[32+aead.NonceSize():], ballotAssociatedData(pollID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %w", err)
+	}
+	return value, nil
+}
+
+// ballotAssociatedData binds an encrypted value to a poll.
+func ballotAssociatedData(pollID int) []byte {
+	return fmt.Appendf(nil, "openslides-vote-encrypted/%d", pollID)
+}
+
+// minCiphertextSize is the size of the ephemeral key, the nonce and the tag of
+// an encrypted ballot.
+const minCiphertextSize = 32 + 12 + 16
+
+// ballotCipher creates the AES-GCM cipher from the shared secret and both public
+// keys.
+func ballotCipher(shared, ephemeralKey, publicKey []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write(shared)
+	h.Write(ephemeralKey)
+	h.Write(publicKey)
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("creating aes cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// validateCiphertext checks, that the value of a ballot for an encrypted poll
+// looks like an encrypted value. The content can only be validated, when the
+// poll is stopped.
+func validateCiphertext(v ballotValue) string {
+	if v.Type() != ballotValueString {
+		return "Your vote has to be the encrypted value as string"
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(v.str)
+	if err != nil || len(ciphertext) < minCiphertextSize {
+		return "Your vote is not a valid encrypted value"
+	}
+	return ""
+}
+
+// trusteeShare is a share of the private key of an encrypted poll.
+type trusteeShare struct {
+	threshold int
+	share     []byte
+}
+
+// splitTrusteeShares splits a private key into one share for each trustee.
+//
+// Each share is the base64 encoded threshold followed by the shamir share.
+func splitTrusteeShares(secret []byte, threshold, trustees int) ([]string, error) {
+	if threshold > 255 {
+		return nil, fmt.Errorf("threshold has to be smaller than 256, got %d", threshold)
+	}
+
+	rawShares, err := shamirSplit(secret, threshold, trustees)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]string, len(rawShares))
+	for i, share := range rawShares {
+		shares[i] = base64.StdEncoding.EncodeToString(append([]byte{byte(threshold)}, share...))
+	}
+	return shares, nil
+}
+
+// parseTrusteeShare decodes a share created by splitTrusteeShares.
+func parseTrusteeShare(encoded string) (trusteeShare, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return trusteeShare{}, fmt.Errorf("decoding share: %w", err)
+	}
+
+	if len(raw) < 3 || raw[0] == 0 || raw[1] == 0 {
+		return trusteeShare{}, fmt.Errorf("invalid share")
+	}
+
+	return trusteeShare{threshold: int(raw[0]), share: raw[1:]}, nil
+}
+
+// maxShareCombinations is the maximum number of combinations of trustee shares,
+// that are tried to recreate the private key of a poll.
+const maxShareCombinations = 10_000
+
+// combineTrusteeShares recreates the private key of an encrypted poll.
+//
+// Anyone with access to the share endpoint could send a share. So the shares
+// are not trusted, but combined until the key belongs to the public key of the
+// poll.
+func combineTrusteeShares(poll pollConfig, shares []trusteeShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, MessageError(ErrInvalid, "The trustees have not sent their shares to decrypt poll %d", poll.id)
+	}
+
+	byThreshold := make(map[int][][]byte)
+	for _, share := range shares {
+		byThreshold[share.threshold] = append(byThreshold[share.threshold], share.share)
+	}
+
+	thresholds := slices.Sorted(maps.Keys(byThreshold))
+
+	var enough bool
+	var tries int
+	for _, threshold := range thresholds {
+		rawShares := byThreshold[threshold]
+		if threshold < 1 || len(rawShares) < threshold {
+			continue
+		}
+		enough = true
+
+		var secret []byte
+		combinations(rawShares, threshold, func(combination [][]byte) bool {
+			tries++
+			if tries > maxShareCombinations {
+				return false
+			}
+
+			combined, err := shamirCombine(combination)
+			if err != nil || !poll.matchesKey(combined) {
+				return true
+			}
+
+			secret = combined
+			return false
+		})
+
+		if secret != nil {
+			return secret, nil
+		}
+	}
+
+	if !enough {
+		return nil, MessageError(ErrInvalid, "Poll %d needs %d trustee shares to be decrypted, got %d", poll.id, thresholds[0], len(byThreshold[thresholds[0]]))
+	}
+	return nil, MessageError(ErrInvalid, "The trustee shares do not belong to poll %d", poll.id)
+}
+
+// combinations calls fn with each combination of k items, until fn returns
+// false.
+func combinations[T any](items []T, k int, fn func([]T) bool) {
+	combination := make([]T, k)
+
+	var walk func(start, depth int) bool
+	walk = func(start, depth int) bool {
+		if depth == k {
+			return fn(combination)
+		}
+
+		for i := start; i <= len(items)-(k-depth); i++ {
+			combination[depth] = items[i]
+			if !walk(i+1, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	walk(0, 0)
+}
+
+// matchesKey returns true, if the private key belongs to the public key of the
+// poll.
+func (p pollConfig) matchesKey(secret []byte) bool {
+	key, err := ecdh.X25519().NewPrivateKey(secret)
+	return err == nil && bytes.Equal(key.PublicKey().Bytes(), p.encryptionKey)
+}
+
+// InvalidBallot is a vote object of an encrypted poll, that could not be
+// decrypted or that is invalid after the decryption.
+type InvalidBallot struct {
+	// Object is the vote object as it was saved in the backend.
+	Object json.RawMessage `json:"object"`
+
+	// Reason tells, why the ballot is invalid.
+	Reason string `json:"reason"`
+}
+
+// decryptBallots decrypts the vote objects of an encrypted poll with the
+// private key, that was combined from the shares of the trustees.
+//
+// It returns the decrypted vote objects, that are valid for the poll, and the
+// invalid vote objects.
+func decryptBallots(poll pollConfig, secret []byte, objects [][]byte) ([][]byte, []InvalidBallot, error) {
+	key, err := ecdh.X25519().NewPrivateKey(secret)
+	if err != nil {
+		return nil, nil, MessageError(ErrInvalid, "The trustee shares do not belong to poll %d", poll.id)
+	}
+
+	var valid [][]byte
+	var invalid []InvalidBallot
+	for _, object := range objects {
+		decrypted, reason := decryptObject(poll, key, object)
+		if reason != "" {
+			invalid = append(invalid, InvalidBallot{Object: object, Reason: reason})
+			continue
+		}
+		valid = append(valid, decrypted)
+	}
+
+	return valid, invalid, nil
+}
+
+// decryptObject decrypts and validates one vote object. If the object is
+// invalid, the reason is returned.
+func decryptObject(poll pollConfig, key *ecdh.PrivateKey, object []byte) ([]byte, string) {
+	var data voteObject
+	if err := json.Unmarshal(object, &data); err != nil {
+		return nil, "Vote object can not be decoded"
+	}
+
+	var encrypted string
+	if err := json.Unmarshal(data.Value, &encrypted); err != nil {
+		return nil, "Value is not encrypted"
+	}
+
+	plain, err := decryptBallot(key, poll.id, encrypted)
+	if err != nil {
+		return nil, fmt.Sprintf("Value can not be decrypted: %v", err)
+	}
+
+	var value ballotValue
+	if err := json.Unmarshal(plain, &value); err != nil {
+		return nil, "Decrypted value has a wrong format"
+	}
+
+	if validation := validate(poll, value); validation != "" {
+		return nil, validation
+	}
+
+	data.Value = value.original
+	decrypted, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Sprintf("Encoding decrypted vote object: %v", err)
+	}
+	return decrypted, ""
+}
+
+// validateEncryptionKey checks, that encrypted polls have a valid public key
+// and other polls have no key.
+func (p pollConfig) validateEncryptionKey() error {
+	if p.ptype != encryptedPollType {
+		if p.encryptionKey != nil || p.homomorphic {
+			return MessageError(ErrInvalid, "Only polls with the type %s can have an encryption key", encryptedPollType)
+		}
+		return nil
+	}
+
+	if p.encryptionKey == nil {
+		return MessageError(ErrInvalid, "Polls with the type %s need an encryption key", encryptedPollType)
+	}
+
+	if p.homomorphic {
+		return p.validateHomomorphicKey()
+	}
+
+	if _, err := ecdh.X25519().NewPublicKey(p.encryptionKey); err != nil {
+		return MessageError(ErrInvalid, "Invalid encryption key: %v", err)
+	}
+	return nil
+}
+
+// EncryptionKey returns the public key of an encrypted poll. The clients have
+// to encrypt the values of their ballots with this key.
+func (v *Vote) EncryptionKey(ctx context.Context, pollID int) ([]byte, error) {
+	ds := dsfetch.New(v.flow)
+	poll, err := loadPoll(ctx, ds, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("loading poll: %w", err)
+	}
+
+	frozen, err := v.frozenConfig(ctx, poll)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if errors.As(err, &errNotExist) {
+			return nil, MessageError(ErrNotExists, "Poll %d is not started", pollID)
+		}
+		return nil, fmt.Errorf("loading poll config from backend: %w", err)
+	}
+
+	if frozen.ptype != encryptedPollType {
+		return nil, MessageError(ErrInvalid, "Poll %d is not encrypted", pollID)
+	}
+
+	return frozen.encryptionKey, nil
+}
+
+// pollShares returns the trustee shares of a poll.
+func (v *Vote) pollShares(pollID int) []trusteeShare {
+	v.sharesMu.Lock()
+	defer v.sharesMu.Unlock()
+	return slices.Clone(v.shares[pollID])
+}
+
+// AddShare saves the share of a trustee for an encrypted poll. When enough
+// shares are saved, the votes are decrypted with the stop request.
+//
+// The shares are only saved in the memory of this instance. So encrypted polls
+// can only be started, if the service runs as a single instance. Sending the
+// same share twice is ok.
+//
+// The share can only be checked against the key of the poll, when enough
+// shares are saved. So all shares are saved and the stop request uses the
+// shares, that belong to the key.
+func (v *Vote) AddShare(ctx context.Context, pollID int, encodedShare string) error {
+	share, err := parseTrusteeShare(encodedShare)
+	if err != nil {
+		return WrapError(ErrInvalid, err)
+	}
+
+	ds := dsfetch.New(v.flow)
+	poll, err := loadPoll(ctx, ds, pollID)
+	if err != nil {
+		return fmt.Errorf("loading poll: %w", err)
+	}
+
+	if poll.ptype != encryptedPollType {
+		return MessageError(ErrInvalid, "Poll %d is not encrypted", pollID)
+	}
+
+	// The shares of homomorphic polls never leave the trustees. They send
+	// partial decryptions instead.
+	if frozen, err := v.frozenConfig(ctx, poll); err == nil && frozen.homomorphic {
+		return MessageError(ErrInvalid, "Poll %d is homomorphic. Send a partial decryption instead of the share", pollID)
+	}
+
+	v.sharesMu.Lock()
+	defer v.sharesMu.Unlock()
+
+	for _, saved := range v.shares[pollID] {
+		if saved.threshold == share.threshold && bytes.Equal(saved.share, share.share) {
+			return nil
+		}
+	}
+
+	v.shares[pollID] = append(v.shares[pollID], share)
+	return nil
+}
@@ -0,0 +1,297 @@
+package vote
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Fraction is a rational number like 2/3.
+type Fraction struct {
+	Num int64
+	Den int64
+}
+
+// ParseFraction parses a string like "2/3".
+func ParseFraction(s string) (Fraction, error) {
+	rawNum, rawDen, ok := strings.Cut(s, "/")
+	if !ok {
+		return Fraction{}, fmt.Errorf("fraction %s has to be in the form n/d", s)
+	}
+
+	num, err := strconv.ParseInt(rawNum, 10, 64)
+	if err != nil {
+		return Fraction{}, fmt.Errorf("invalid numerator %s: %w", rawNum, err)
+	}
+
+	den, err := strconv.ParseInt(rawDen, 10, 64)
+	if err != nil {
+		return Fraction{}, fmt.Errorf("invalid denominator %s: %w", rawDen, err)
+	}
+
+	if num < 0 || den <= 0 || num > den {
+		return Fraction{}, fmt.Errorf("fraction %s has to be between 0 and 1", s)
+	}
+
+	return Fraction{Num: num, Den: den}, nil
+}
+
+// reached returns true, if value is at least the fraction of base. If strict
+// is true, value has to be more then the fraction of base.
+func (f Fraction) reached(value, base Decimal, strict bool) bool {
+	left := new(big.Int).Mul(big.NewInt(int64(value)), big.NewInt(f.Den))
+	right := new(big.Int).Mul(big.NewInt(int64(base)), big.NewInt(f.Num))
+
+	cmp := left.Cmp(right)
+	if strict {
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// Base is the value a majority or a quorum is calculated from.
+type Base string
+
+// The possible values for a Base.
+const (
+	// BaseCast is the weighted sum of all vote objects.
+	BaseCast Base = "cast"
+
+	// BaseValid is the weighted sum of all valid vote objects.
+	BaseValid Base = "valid"
+
+	// BaseEntitled is the number of entitled users. As a base for a majority,
+	// it is the same as BaseEntitledWeight, because the answers are weighted.
+	BaseEntitled Base = "entitled"
+
+	// BaseEntitledWeight is the sum of the vote weights of all entitled users.
+	BaseEntitledWeight Base = "entitled_weight"
+)
+
+// The possible majorities. Any fraction like "3/4" is also a valid majority.
+const (
+	// MajoritySimple requires more yes then no votes.
+	MajoritySimple = "simple"
+
+	// MajorityAbsolute requires more then half of the base.
+	MajorityAbsolute = "absolute"
+
+	// MajorityTwoThirds requires at least two thirds of the base.
+	MajorityTwoThirds = "two_thirds"
+)
+
+// MajorityRule describes when an option passes.
+type MajorityRule struct {
+	// Majority is one of the Majority-constants or a fraction like "3/4". A
+	// custom fraction requires at least this fraction of the base.
+	Majority string
+
+	// Base is the base for the majority. The default is BaseCast.
+	Base Base
+
+	// Quorum is the fraction of the entitled users that have to vote. If it is
+	// nil, no quorum is required.
+	Quorum *Fraction
+
+	// QuorumBase is BaseEntitled or BaseEntitledWeight. With BaseEntitled, the
+	// users are counted. With BaseEntitledWeight, their vote weights are
+	// summed. The default is BaseEntitled.
+	QuorumBase Base
+}
+
+// ParseMajorityRule creates a MajorityRule from strings.
+//
+// quorum can be empty or a fraction like "1/2".
+func ParseMajorityRule(majority, base, quorum, quorumBase string) (MajorityRule, error) {
+	rule := MajorityRule{
+		Majority:   majority,
+		Base:       Base(base),
+		QuorumBase: Base(quorumBase),
+	}
+
+	switch majority {
+	case MajoritySimple, MajorityAbsolute, MajorityTwoThirds:
+	default:
+		if _, err := ParseFraction(majority); err != nil {
+			return MajorityRule{}, fmt.Errorf("invalid majority: %w", err)
+		}
+	}
+
+	switch rule.Base {
+	case "":
+		rule.Base = BaseCast
+	case BaseCast, BaseValid, BaseEntitled, BaseEntitledWeight:
+	default:
+		return MajorityRule{}, fmt.Errorf("unknown base %s", base)
+	}
+
+	switch rule.QuorumBase {
+	case "":
+		rule.QuorumBase = BaseEntitled
+	case BaseEntitled, BaseEntitledWeight:
+	default:
+		return MajorityRule{}, fmt.Errorf("unknown quorum base %s", quorumBase)
+	}
+
+	if quorum != "" {
+		q, err := ParseFraction(quorum)
+		if err != nil {
+			return MajorityRule{}, fmt.Errorf("invalid quorum: %w", err)
+		}
+		rule.Quorum = &q
+	}
+
+	return rule, nil
+}
+
+// Evaluation is the result of a MajorityRule applied to a StopResult.
+type Evaluation struct {
+	// QuorumReached is true, if enough entitled users have voted or if no
+	// quorum is required.
+	QuorumReached bool `json:"quorum_reached"`
+
+	// Base is the value of the base of the majority.
+	Base Decimal `json:"base"`
+
+	// Options contains the evaluation of each option.
+	Options map[int]OptionEvaluation `json:"options"`
+
+	// Global is the evaluation of the global answers. It is only set, if the
+	// poll has global answers.
+	Global *OptionEvaluation `json:"global,omitempty"`
+}
+
+// OptionEvaluation is the evaluation of one option.
+type OptionEvaluation struct {
+	Yes Decimal `json:"yes"`
+	No  Decimal `json:"no"`
+
+	// Passed is true, if the option has the required majority and the quorum
+	// is reached.
+	Passed bool `json:"passed"`
+}
+
+// Evaluate applies a majority rule to the result of a poll.
+//
+// Each option is evaluated by its weighted yes and no votes. For the methods YN
+// and YNA, these are the answers Y and N on the option. For the method Y, the
+// amounts on the option are the yes votes and the global answer N is a no vote
+// for each option. For the method N, the amounts on the option are the no votes
+// and the global answer Y is a yes vote for each option. The other methods
+// have no yes votes and can not be evaluated.
+//
+// The bases BaseEntitled and BaseEntitledWeight and the quorum require the
+// turnout of the poll.
+func Evaluate(result StopResult, rule MajorityRule) (Evaluation, error) {
+	switch result.Method {
+	case "Y", "N", "YN", "YNA":
+	default:
+		return Evaluation{}, MessageError(ErrInvalid, "Polls with the method %s can not be evaluated with a majority rule", result.Method)
+	}
+
+	base, err := baseValue(result, rule.Base)
+	if err != nil {
+		return Evaluation{}, err
+	}
+
+	quorumReached := true
+	if rule.Quorum != nil {
+		if result.Turnout == nil {
+			return Evaluation{}, MessageError(ErrInvalid, "A quorum requires the entitled users of the poll")
+		}
+
+		participation := DecimalFromInt(result.Turnout.Voted)
+		quorumBase := DecimalFromInt(result.Turnout.Entitled)
+		if rule.QuorumBase == BaseEntitledWeight {
+			participation = result.Turnout.VotedWeight
+			quorumBase = result.Turnout.EntitledWeight
+		}
+
+		quorumReached = rule.Quorum.reached(participation, quorumBase, false)
+	}
+
+	evaluation := Evaluation{
+		QuorumReached: quorumReached,
+		Base:          base,
+		Options:       make(map[int]OptionEvaluation, len(result.Tally.Options)),
+	}
+
+	for optionID, answers := range result.Tally.Options {
+		yes := answers["Y"]
+		no := answers["N"]
+		switch result.Method {
+		case "Y":
+			no = result.Tally.Global["N"]
+		case "N":
+			yes = result.Tally.Global["Y"]
+		}
+
+		option, err := evaluateAnswers(yes, no, rule, base, quorumReached)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		evaluation.Options[optionID] = option
+	}
+
+	if len(result.Tally.Global) > 0 {
+		global, err := evaluateAnswers(result.Tally.Global["Y"], result.Tally.Global["N"], rule, base, quorumReached)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		evaluation.Global = &global
+	}
+
+	return evaluation, nil
+}
+
+// evaluateAnswers evaluates the weighted yes and no votes of an option or of
+// the global answers.
+func evaluateAnswers(yes, no Decimal, rule MajorityRule, base Decimal, quorumReached bool) (OptionEvaluation, error) {
+	var majority bool
+	switch rule.Majority {
+	case MajoritySimple:
+		majority = yes > no
+	case MajorityAbsolute:
+		majority = Fraction{1, 2}.reached(yes, base, true)
+	case MajorityTwoThirds:
+		majority = Fraction{2, 3}.reached(yes, base, false)
+	default:
+		fraction, err := ParseFraction(rule.Majority)
+		if err != nil {
+			return OptionEvaluation{}, WrapError(ErrInvalid, fmt.Errorf("invalid majority: %w", err))
+		}
+		majority = fraction.reached(yes, base, false)
+	}
+
+	return OptionEvaluation{
+		Yes:    yes,
+		No:     no,
+		Passed: majority && quorumReached,
+	}, nil
+}
+
+// baseValue returns the value for a base.
+//
+// The answers are weighted. So the bases BaseEntitled and BaseEntitledWeight
+// both return the weight of the entitled users. Otherwise, the weighted yes
+// votes would be compared with a number of users.
+func baseValue(result StopResult, base Base) (Decimal, error) {
+	switch base {
+	case BaseCast, "":
+		return result.Tally.VotesCast, nil
+
+	case BaseValid:
+		return result.Tally.VotesValid, nil
+
+	case BaseEntitled, BaseEntitledWeight:
+		if result.Turnout == nil {
+			return 0, MessageError(ErrInvalid, "The base %s requires the entitled users of the poll", base)
+		}
+
+		return result.Turnout.EntitledWeight, nil
+
+	default:
+		return 0, MessageError(ErrInvalid, "Unknown base %s", base)
+	}
+}
@@ -0,0 +1,377 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
+	"github.com/OpenSlides/openslides-vote-service/log"
+)
+
+var envFailover = environment.NewVariable("VOTE_FAILOVER", "false", "If true, fast polls use the long backend while the fast backend is not reachable.")
+
+// failoverThreshold is the number of failed pings until the fast backend is
+// seen as unhealthy.
+const failoverThreshold = 3
+
+// failoverInterval is the time between two pings to the fast backend.
+var failoverInterval = time.Second
+
+// migrateTimeout is the time to migrate one poll, when the fast backend is
+// not reachable.
+const migrateTimeout = 5 * time.Second
+
+// Pinger is a Backend that can check its connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// FailoverEnabled reads from the environment, if the failover of the fast
+// backend is enabled.
+func FailoverEnabled(lookup environment.Environmenter) (bool, error) {
+	raw := envFailover.Value(lookup)
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s. Expected bool, got %s", envFailover.Key, raw)
+	}
+	return enabled, nil
+}
+
+// WithFailover enables the failover of the fast backend.
+//
+// The fast backend is checked every second. If it is not reachable, new fast
+// polls are started in the long backend. Running fast polls are migrated to
+// the long backend, if the fast backend can still stop them. When the fast
+// backend is reachable again, new fast polls use it again. Polls that were
+// started in the long backend stay there. See reconcileFailover for polls,
+// that were started in both backends.
+//
+// If the fast backend is not reachable, when the service starts, the service
+// starts in the degraded state.
+//
+// The fast backend has to implement the Pinger interface. Otherwise this
+// option does nothing.
+func WithFailover() Option {
+	return func(v *Vote) {
+		v.failover = true
+	}
+}
+
+// Degraded returns true, if the fast backend is not reachable and fast polls
+// use the long backend.
+func (v *Vote) Degraded() bool {
+	return v.degraded.Load()
+}
+
+// watchFastBackend checks the fast backend until the context is canceled.
+func (v *Vote) watchFastBackend(ctx context.Context, errorHandler func(error)) {
+	pinger, ok := v.backends["fast"].(Pinger)
+	if !ok {
+		return
+	}
+
+	var failures int
+	ticker := time.NewTicker(failoverInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+		err := pinger.Ping(pingCtx)
+		cancel()
+
+		if err == nil {
+			failures = 0
+			if v.degraded.Load() {
+				log.Info("Fast backend is reachable again")
+				v.recoverFastBackend(ctx, errorHandler)
+			}
+			continue
+		}
+
+		failures++
+		if failures < failoverThreshold || v.degraded.Load() {
+			continue
+		}
+
+		log.Info("Fast backend is not reachable. Using the long backend for fast polls: %v", err)
+		v.degraded.Store(true)
+
+		if err := v.migrateFastPolls(ctx); err != nil {
+			errorHandler(fmt.Errorf("migrating fast polls: %w", err))
+		}
+	}
+}
+
+// pingFastBackend checks the fast backend, when the service starts. If it is
+// not reachable, the service starts in the degraded state.
+func (v *Vote) pingFastBackend(ctx context.Context) {
+	pinger, ok := v.backends["fast"].(Pinger)
+	if !ok {
+		return
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if err := pinger.Ping(pingCtx); err != nil {
+		log.Info("Fast backend is not reachable. Using the long backend for fast polls: %v", err)
+		v.degraded.Store(true)
+	}
+}
+
+// recoverFastBackend is called, when the fast backend is reachable again.
+//
+// The state is only healthy again, after the polls, that were started in both
+// backends, are reconciled. Until then, new polls are started in the long
+// backend.
+func (v *Vote) recoverFastBackend(ctx context.Context, errorHandler func(error)) {
+	if err := v.reconcileFailover(ctx, errorHandler); err != nil {
+		errorHandler(fmt.Errorf("reconciling polls of the fast backend: %w", err))
+		return
+	}
+
+	v.degraded.Store(false)
+
+	if err := v.loadVoted(ctx); err != nil {
+		errorHandler(fmt.Errorf("loading voted users after recovery: %w", err))
+	}
+}
+
+// migrateFastPolls tries to migrate all known running polls of the fast
+// backend to the long backend.
+//
+// Polls that can not be migrated, for example because the fast backend does
+// not answer at all, stay in the fast backend. Their votes can not be read, so
+// the polls can not be used, until the fast backend is reachable again. Each
+// migration has a timeout, so a fast backend, that does not answer, does not
+// block other requests.
+func (v *Vote) migrateFastPolls(ctx context.Context) error {
+	v.votedMu.Lock()
+	pollIDs := make([]int, 0, len(v.voted))
+	for pollID := range v.voted {
+		pollIDs = append(pollIDs, pollID)
+	}
+	v.votedMu.Unlock()
+
+	ds := dsfetch.New(v.flow)
+	for _, pollID := range pollIDs {
+		poll, err := loadPoll(ctx, ds, pollID)
+		if err != nil {
+			return fmt.Errorf("loading poll %d: %w", pollID, err)
+		}
+
+		if v.backendName(poll) != "fast" || poll.state != "started" {
+			continue
+		}
+
+		migrateCtx, cancel := context.WithTimeout(ctx, migrateTimeout)
+		err = v.Migrate(migrateCtx, pollID, "long")
+		cancel()
+
+		if err != nil {
+			log.Info("Can not migrate poll %d to the long backend: %v", pollID, err)
+		}
+	}
+	return nil
+}
+
+// startInLong starts a fast poll in the long backend, if the fast backend is
+// not reachable. It returns false, if the poll has to be started normally.
+//
+// If the fast backend reports, that the poll does not exist, the poll is
+// started in the long backend. If the fast backend can not tell, the poll is
+// also started in the long backend, unless this instance knows votes of the
+// poll. In this case, the poll could have been started in the fast backend
+// before, and is reconciled, when the fast backend is reachable again.
+func (v *Vote) startInLong(ctx context.Context, poll pollConfig) (bool, error) {
+	if !v.degraded.Load() || v.backendName(poll) != "fast" {
+		return false, nil
+	}
+
+	_, err := v.backends["fast"].Config(ctx, poll.id)
+	if err == nil {
+		// The poll was started in the fast backend before it became
+		// unreachable.
+		return false, nil
+	}
+
+	var errNotExist interface{ DoesNotExist() }
+	unknown := !errors.As(err, &errNotExist)
+	if unknown {
+		v.votedMu.Lock()
+		_, known := v.voted[poll.id]
+		v.votedMu.Unlock()
+
+		if known {
+			return false, fmt.Errorf("checking poll in the unreachable fast backend: %w", err)
+		}
+		log.Info("Can not check poll %d in the fast backend. Starting it in the long backend: %v", poll.id, err)
+	}
+
+	poll.migratedFrom = "fast"
+	config, err := json.Marshal(poll)
+	if err != nil {
+		return false, fmt.Errorf("encoding poll config: %w", err)
+	}
+
+	if err := v.backends["long"].Start(ctx, poll.id, config); err != nil {
+		return false, fmt.Errorf("starting poll in the long backend: %w", err)
+	}
+
+	v.migratedMu.Lock()
+	v.migrated[poll.id] = "long"
+	if unknown {
+		v.failedOver[poll.id] = true
+	}
+	v.migratedMu.Unlock()
+
+	return true, nil
+}
+
+// reconcileFailover removes the copies in the fast backend of the polls, that
+// were started in the long backend, while the fast backend could not tell, if
+// it knows the poll.
+//
+// The poll in the long backend is the one, that is used. If the fast backend
+// has a copy of the poll, the copy is stopped, so no vote is saved there
+// anymore. Other instances, that still use the copy, find the poll in the long
+// backend, when the copy tells them, that it is stopped.
+//
+// If the copy has votes, they are added to the poll in the long backend. This
+// is only possible, if no user has voted in both backends and the poll does
+// not allow to change the vote. Otherwise, the votes of the copy are not
+// counted and an error is reported.
+func (v *Vote) reconcileFailover(ctx context.Context, errorHandler func(error)) error {
+	v.migratedMu.Lock()
+	pollIDs := slices.Sorted(maps.Keys(v.failedOver))
+	v.migratedMu.Unlock()
+
+	for _, pollID := range pollIDs {
+		if err := v.reconcilePoll(ctx, pollID); err != nil {
+			var errNotExist interface{ DoesNotExist() }
+			if !errors.As(err, &errNotExist) && !errors.Is(err, ErrInvalid) {
+				return fmt.Errorf("poll %d: %w", pollID, err)
+			}
+			errorHandler(fmt.Errorf("poll %d: %w", pollID, err))
+		}
+
+		v.migratedMu.Lock()
+		delete(v.failedOver, pollID)
+		v.migratedMu.Unlock()
+	}
+	return nil
+}
+
+// reconcilePoll stops the copy of a poll in the fast backend and adds its
+// votes to the poll in the long backend.
+func (v *Vote) reconcilePoll(ctx context.Context, pollID int) error {
+	fast, ok := v.backends["fast"].(StopChecker)
+	if !ok {
+		return MessageError(ErrInvalid, "The fast backend can not tell, if the poll was started there")
+	}
+
+	fastObjects, fastUserIDs, _, err := fast.StopChecked(ctx, pollID)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if errors.As(err, &errNotExist) {
+			// The usual case. The poll was never started in the fast
+			// backend.
+			return nil
+		}
+		return fmt.Errorf("stopping the copy in the fast backend: %w", err)
+	}
+
+	if len(fastUserIDs) == 0 {
+		return nil
+	}
+
+	long := v.backends["long"]
+	importer, isImporter := long.(Importer)
+	stopper, isStopper := long.(StopChecker)
+	if !isImporter || !isStopper {
+		return MessageError(ErrInvalid, "The copy in the fast backend has %d votes, that are not counted. The long backend can not import them", len(fastUserIDs))
+	}
+
+	rawConfig, err := long.Config(ctx, pollID)
+	if err != nil {
+		return fmt.Errorf("fetching config from the long backend: %w", err)
+	}
+
+	var config pollConfig
+	if err := json.Unmarshal(rawConfig, &config); err != nil {
+		return fmt.Errorf("decoding poll config: %w", err)
+	}
+
+	if config.revotable {
+		return MessageError(ErrInvalid, "The copy in the fast backend has %d votes, that are not counted. Polls that allow to change the vote can not be merged", len(fastUserIDs))
+	}
+
+	voted, err := long.Voted(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching voted users from the long backend: %w", err)
+	}
+
+	if both := intersect(fastUserIDs, voted[pollID]); len(both) > 0 {
+		return MessageError(ErrInvalid, "The copy in the fast backend has %d votes, that are not counted. The users %v have voted in both backends", len(fastUserIDs), both)
+	}
+
+	// Votes on this instance wait, until the votes are merged.
+	v.migrateMu.Lock()
+	defer v.migrateMu.Unlock()
+
+	longObjects, longUserIDs, stoppedBefore, err := stopper.StopChecked(ctx, pollID)
+	if err != nil {
+		return fmt.Errorf("stopping poll in the long backend: %w", err)
+	}
+
+	if stoppedBefore {
+		return MessageError(ErrInvalid, "The copy in the fast backend has %d votes, that are not counted. The poll was already stopped", len(fastUserIDs))
+	}
+
+	objects := longObjects
+	userIDs := longUserIDs
+	both := intersect(fastUserIDs, longUserIDs)
+	if len(both) == 0 {
+		objects = append(slices.Clone(longObjects), fastObjects...)
+		userIDs = append(slices.Clone(longUserIDs), fastUserIDs...)
+	}
+
+	if err := long.Clear(ctx, pollID); err != nil {
+		return fmt.Errorf("clearing poll in the long backend: %w", err)
+	}
+
+	if err := importer.Import(ctx, pollID, rawConfig, userIDs, objects); err != nil {
+		return fmt.Errorf("importing merged poll into the long backend: %w", err)
+	}
+
+	if len(both) > 0 {
+		return MessageError(ErrInvalid, "The copy in the fast backend has %d votes, that are not counted. The users %v have voted in both backends", len(fastUserIDs), both)
+	}
+
+	log.Info("Poll %d: added %d votes from the fast backend to the long backend", pollID, len(fastUserIDs))
+	return nil
+}
+
+// intersect returns the ids, that are in a and b.
+func intersect(a, b []int) []int {
+	var both []int
+	for _, id := range a {
+		if slices.Contains(b, id) {
+			both = append(both, id)
+		}
+	}
+	return both
+}
@@ -0,0 +1,549 @@
+package vote
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Homomorphic polls use exponential ElGamal in the 2048-bit MODP group from
+// RFC 3526. The generator 4 creates the subgroup of the quadratic residues with
+// the prime order q = (p-1)/2.
+var (
+	groupP = mustParseHex(`
+		FFFFFFFF FFFFFFFF C90FDAA2 2168C234 C4C6628B 80DC1CD1 29024E08 8A67CC74
+		020BBEA6 3B139B22 514A0879 8E3404DD EF9519B3 CD3A431B 302B0A6D F25F1437
+		4FE1356D 6D51C245 E485B576 625E7EC6 F44C42E9 A637ED6B 0BFF5CB6 F406B7ED
+		EE386BFB 5A899FA5 AE9F2411 7C4B1FE6 49286651 ECE45B3D C2007CB8 A163BF05
+		98DA4836 1C55D39A 69163FA8 FD24CF5F 83655D23 DCA3AD96 1C62F356 208552BB
+		9ED52907 7096966D 670C354E 4ABC9804 F1746C08 CA18217C 32905E46 2E36CE3B
+		E39E772C 180E8603 9B2783A2 EC07A28F B5C55DF0 6F4C52C9 DE2BCBF6 95581718
+		3995497C EA956AE5 15D22618 98FA0510 15728E5A 8AACAA68 FFFFFFFF FFFFFFFF`)
+	groupQ = new(big.Int).Rsh(groupP, 1)
+	groupG = big.NewInt(4)
+)
+
+// groupElementSize is the size of an encoded group element in bytes.
+const groupElementSize = 256
+
+func mustParseHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(strings.Join(strings.Fields(s), ""), 16)
+	if !ok {
+		panic(fmt.Sprintf("invalid hex number: %s", s))
+	}
+	return n
+}
+
+// Homomorphic lets the service count the votes of an encrypted poll without
+// decrypting a single ballot. It can only be used for the methods YN and YNA
+// and needs a key created with NewHomomorphicKey.
+func Homomorphic() StartOption {
+	return func(p *pollConfig) {
+		p.homomorphic = true
+	}
+}
+
+// NewHomomorphicKey creates an ElGamal key pair for a homomorphic poll.
+//
+// It returns the public key and the private key split into one share for each
+// trustee. The private key itself is not returned. Each threshold trustees can
+// decrypt the result of the poll together with PartialDecrypt.
+func NewHomomorphicKey(threshold, trustees int) ([]byte, []string, error) {
+	x, err := randomExponent()
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating private key: %w", err)
+	}
+
+	shares, err := splitHomomorphicKey(x, threshold, trustees)
+	if err != nil {
+		return nil, nil, fmt.Errorf("splitting private key: %w", err)
+	}
+
+	publicKey := new(big.Int).Exp(groupG, x, groupP)
+	return publicKey.FillBytes(make([]byte, groupElementSize)), shares, nil
+}
+
+// homomorphicCiphertext is an exponential ElGamal ciphertext (g^r, g^m * h^r).
+type homomorphicCiphertext struct {
+	A []byte `json:"a"`
+	B []byte `json:"b"`
+}
+
+// bitProof is a disjunctive Chaum-Pedersen proof, that a ciphertext encrypts 0
+// or 1.
+type bitProof struct {
+	C0 []byte `json:"c0"`
+	C1 []byte `json:"c1"`
+	S0 []byte `json:"s0"`
+	S1 []byte `json:"s1"`
+}
+
+// sumProof is a Chaum-Pedersen proof, that the product of the ciphertexts of
+// an option encrypts 1.
+type sumProof struct {
+	C []byte `json:"c"`
+	S []byte `json:"s"`
+}
+
+// homomorphicOption is the encrypted answer for one option. It contains one
+// ciphertext for each possible answer. Exactly one of them encrypts 1, all
+// others encrypt 0.
+type homomorphicOption struct {
+	Ciphertexts []homomorphicCiphertext `json:"ciphertexts"`
+	BitProofs   []bitProof              `json:"bit_proofs"`
+	SumProof    sumProof                `json:"sum_proof"`
+}
+
+// homomorphicAnswers returns the possible answers of a poll method in the order
+// of the ciphertexts.
+func homomorphicAnswers(method string) []string {
+	switch method {
+	case "YN":
+		return []string{"Y", "N"}
+	case "YNA":
+		return []string{"Y", "N", "A"}
+	default:
+		return nil
+	}
+}
+
+// validateHomomorphicKey checks, that the key of a homomorphic poll is an
+// element of the group and the method is supported.
+func (p pollConfig) validateHomomorphicKey() error {
+	if homomorphicAnswers(p.method) == nil {
+		return MessageError(ErrInvalid, "Homomorphic polls are only possible for the methods YN and YNA")
+	}
+
+	if len(p.encryptionKey) != groupElementSize || !validElement(new(big.Int).SetBytes(p.encryptionKey)) {
+		return MessageError(ErrInvalid, "Invalid encryption key for a homomorphic poll")
+	}
+	return nil
+}
+
+// EncryptHomomorphic creates the value of a ballot for a homomorphic poll.
+//
+// The choices contain the answer for each option of the poll.
+func EncryptHomomorphic(publicKey []byte, pollID int, method string, choices map[int]string) (json.RawMessage, error) {
+	answers := homomorphicAnswers(method)
+	if answers == nil {
+		return nil, fmt.Errorf("method %s is not supported", method)
+	}
+
+	h := new(big.Int).SetBytes(publicKey)
+	value := make(map[int]homomorphicOption, len(choices))
+	for optionID, choice := range choices {
+		index := slices.Index(answers, choice)
+		if index == -1 {
+			return nil, fmt.Errorf("invalid answer %s for option %d", choice, optionID)
+		}
+
+		option, err := encryptOption(h, proofContext(pollID, optionID, h), len(answers), index)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting option %d: %w", optionID, err)
+		}
+		value[optionID] = option
+	}
+
+	return json.Marshal(value)
+}
+
+// encryptOption encrypts 1 for the choice and 0 for all other answers.
+func encryptOption(h *big.Int, context []byte, answers, choice int) (homomorphicOption, error) {
+	var option homomorphicOption
+	sumA := big.NewInt(1)
+	sumB := big.NewInt(1)
+	sumR := new(big.Int)
+	for i := 0; i < answers; i++ {
+		m := 0
+		if i == choice {
+			m = 1
+		}
+
+		r, err := randomExponent()
+		if err != nil {
+			return homomorphicOption{}, err
+		}
+
+		a := new(big.Int).Exp(groupG, r, groupP)
+		b := new(big.Int).Exp(h, r, groupP)
+		if m == 1 {
+			b.Mul(b, groupG).Mod(b, groupP)
+		}
+
+		proof, err := proveBit(bitContext(context, i), h, a, b, r, m)
+		if err != nil {
+			return homomorphicOption{}, err
+		}
+
+		option.Ciphertexts = append(option.Ciphertexts, homomorphicCiphertext{A: encodeElement(a), B: encodeElement(b)})
+		option.BitProofs = append(option.BitProofs, proof)
+
+		sumA.Mul(sumA, a).Mod(sumA, groupP)
+		sumB.Mul(sumB, b).Mod(sumB, groupP)
+		sumR.Add(sumR, r).Mod(sumR, groupQ)
+	}
+
+	proof, err := proveSum(context, h, sumA, sumB, sumR)
+	if err != nil {
+		return homomorphicOption{}, err
+	}
+	option.SumProof = proof
+
+	return option, nil
+}
+
+// validateHomomorphic checks the proofs of a ballot for a homomorphic poll.
+//
+// It returns an empty string, if the ballot encrypts exactly one answer for
+// each option of the poll.
+func validateHomomorphic(poll pollConfig, raw json.RawMessage) string {
+	value, err := decodeHomomorphic(poll, raw)
+	if err != nil {
+		return fmt.Sprintf("Your vote is not a valid encrypted vote: %v", err)
+	}
+
+	h := new(big.Int).SetBytes(poll.encryptionKey)
+	for _, optionID := range poll.options {
+		if err := verifyOption(h, proofContext(poll.id, optionID, h), value[optionID]); err != nil {
+			return fmt.Sprintf("Invalid proof for option %d: %v", optionID, err)
+		}
+	}
+	return ""
+}
+
+// decodeHomomorphic decodes the value of a ballot for a homomorphic poll and
+// checks its structure.
+func decodeHomomorphic(poll pollConfig, raw json.RawMessage) (map[int]homomorphicOption, error) {
+	var value map[int]homomorphicOption
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("decoding value: %w", err)
+	}
+
+	if len(value) != len(poll.options) {
+		return nil, fmt.Errorf("expected an answer for each of the %d options, got %d", len(poll.options), len(value))
+	}
+
+	answers := len(homomorphicAnswers(poll.method))
+	for _, optionID := range poll.options {
+		option, ok := value[optionID]
+		if !ok {
+			return nil, fmt.Errorf("option %d is missing", optionID)
+		}
+
+		if len(option.Ciphertexts) != answers || len(option.BitProofs) != answers {
+			return nil, fmt.Errorf("option %d needs %d ciphertexts and proofs", optionID, answers)
+		}
+
+		for _, c := range option.Ciphertexts {
+			if !validElement(new(big.Int).SetBytes(c.A)) || !validElement(new(big.Int).SetBytes(c.B)) {
+				return nil, fmt.Errorf("option %d has an invalid ciphertext", optionID)
+			}
+		}
+	}
+
+	return value, nil
+}
+
+// verifyOption checks the bit proofs of all ciphertexts of an option and the
+// proof, that exactly one ciphertext encrypts 1.
+func verifyOption(h *big.Int, context []byte, option homomorphicOption) error {
+	sumA := big.NewInt(1)
+	sumB := big.NewInt(1)
+	for i, c := range option.Ciphertexts {
+		a := new(big.Int).SetBytes(c.A)
+		b := new(big.Int).SetBytes(c.B)
+
+		if !verifyBit(bitContext(context, i), h, a, b, option.BitProofs[i]) {
+			return fmt.Errorf("ciphertext %d does not encrypt 0 or 1", i)
+		}
+
+		sumA.Mul(sumA, a).Mod(sumA, groupP)
+		sumB.Mul(sumB, b).Mod(sumB, groupP)
+	}
+
+	if !verifySum(context, h, sumA, sumB, option.SumProof) {
+		return fmt.Errorf("the ciphertexts do not encrypt exactly one answer")
+	}
+	return nil
+}
+
+// proveBit creates a proof, that (a, b) encrypts m, where m is 0 or 1.
+//
+// The proof for the other value is simulated.
+func proveBit(context []byte, h, a, b, r *big.Int, m int) (bitProof, error) {
+	var c, s, commitA, commitB [2]*big.Int
+
+	other := 1 - m
+	var err error
+	if c[other], err = randomExponent(); err != nil {
+		return bitProof{}, err
+	}
+	if s[other], err = randomExponent(); err != nil {
+		return bitProof{}, err
+	}
+	commitA[other], commitB[other] = bitCommitments(h, a, b, other, c[other], s[other])
+
+	w, err := randomExponent()
+	if err != nil {
+		return bitProof{}, err
+	}
+	commitA[m] = new(big.Int).Exp(groupG, w, groupP)
+	commitB[m] = new(big.Int).Exp(h, w, groupP)
+
+	total := challenge(context, a, b, commitA[0], commitB[0], commitA[1], commitB[1])
+	c[m] = new(big.Int).Sub(total, c[other])
+	c[m].Mod(c[m], groupQ)
+
+	s[m] = new(big.Int).Mul(c[m], r)
+	s[m].Add(s[m], w).Mod(s[m], groupQ)
+
+	return bitProof{C0: c[0].Bytes(), C1: c[1].Bytes(), S0: s[0].Bytes(), S1: s[1].Bytes()}, nil
+}
+
+// verifyBit checks a proof created by proveBit.
+func verifyBit(context []byte, h, a, b *big.Int, proof bitProof) bool {
+	var commitA, commitB [2]*big.Int
+	c := [2]*big.Int{new(big.Int).SetBytes(proof.C0), new(big.Int).SetBytes(proof.C1)}
+	s := [2]*big.Int{new(big.Int).SetBytes(proof.S0), new(big.Int).SetBytes(proof.S1)}
+	for i := range c {
+		if c[i].Cmp(groupQ) >= 0 || s[i].Cmp(groupQ) >= 0 {
+			return false
+		}
+		commitA[i], commitB[i] = bitCommitments(h, a, b, i, c[i], s[i])
+	}
+
+	total := challenge(context, a, b, commitA[0], commitB[0], commitA[1], commitB[1])
+	sum := new(big.Int).Add(c[0], c[1])
+	return sum.Mod(sum, groupQ).Cmp(total) == 0
+}
+
+// bitCommitments calculates the commitments of a proof for the value m from
+// the challenge and the response:
+//
+// A = g^s * a^-c and B = h^s * (b / g^m)^-c
+func bitCommitments(h, a, b *big.Int, m int, c, s *big.Int) (*big.Int, *big.Int) {
+	bm := new(big.Int).Set(b)
+	if m == 1 {
+		bm.Mul(bm, new(big.Int).ModInverse(groupG, groupP)).Mod(bm, groupP)
+	}
+
+	return commitment(groupG, a, c, s), commitment(h, bm, c, s)
+}
+
+// commitment calculates base^s * value^-c.
+func commitment(base, value, c, s *big.Int) *big.Int {
+	result := new(big.Int).Exp(base, s, groupP)
+	inverse := new(big.Int).Exp(value, c, groupP)
+	inverse.ModInverse(inverse, groupP)
+	return result.Mul(result, inverse).Mod(result, groupP)
+}
+
+// proveSum creates a proof, that (a, b) encrypts 1 with the randomness r.
+func proveSum(context []byte, h, a, b, r *big.Int) (sumProof, error) {
+	w, err := randomExponent()
+	if err != nil {
+		return sumProof{}, err
+	}
+
+	t1 := new(big.Int).Exp(groupG, w, groupP)
+	t2 := new(big.Int).Exp(h, w, groupP)
+	c := challenge(context, a, b, t1, t2)
+
+	s := new(big.Int).Mul(c, r)
+	s.Add(s, w).Mod(s, groupQ)
+
+	return sumProof{C: c.Bytes(), S: s.Bytes()}, nil
+}
+
+// verifySum checks a proof created by proveSum.
+func verifySum(context []byte, h, a, b *big.Int, proof sumProof) bool {
+	c := new(big.Int).SetBytes(proof.C)
+	s := new(big.Int).SetBytes(proof.S)
+	if c.Cmp(groupQ) >= 0 || s.Cmp(groupQ) >= 0 {
+		return false
+	}
+
+	b1 := new(big.Int).Mul(b, new(big.Int).ModInverse(groupG, groupP))
+	b1.Mod(b1, groupP)
+
+	t1 := commitment(groupG, a, c, s)
+	t2 := commitment(h, b1, c, s)
+	return challenge(context, a, b, t1, t2).Cmp(c) == 0
+}
+
+// proofContext binds the proofs to the poll, the option and the key, so a
+// proof can not be copied to another poll or option.
+func proofContext(pollID, optionID int, h *big.Int) []byte {
+	context := fmt.Appendf(nil, "openslides-vote-homomorphic/%d/%d/", pollID, optionID)
+	return append(context, encodeElement(h)...)
+}
+
+// bitContext binds a bit proof to the position of the ciphertext.
+func bitContext(context []byte, index int) []byte {
+	return binary.BigEndian.AppendUint32(bytes.Clone(context), uint32(index))
+}
+
+// challenge creates the Fiat-Shamir challenge from the context and the values.
+func challenge(context []byte, values ...*big.Int) *big.Int {
+	h := sha256.New()
+	h.Write(context)
+	for _, v := range values {
+		h.Write(encodeElement(v))
+	}
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, groupQ)
+}
+
+// randomExponent returns a random number between 1 and q-1.
+func randomExponent() (*big.Int, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Sub(groupQ, big.NewInt(1)))
+	if err != nil {
+		return nil, fmt.Errorf("reading random number: %w", err)
+	}
+	return n.Add(n, big.NewInt(1)), nil
+}
+
+// validElement checks, that x is an element of the subgroup.
+func validElement(x *big.Int) bool {
+	return x.Sign() > 0 && x.Cmp(groupP) < 0 && big.Jacobi(x, groupP) == 1
+}
+
+func encodeElement(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, groupElementSize))
+}
+
+// aggregateBallots multiplies the ciphertexts of all ballots of a homomorphic
+// poll and counts the ballots.
+//
+// Each ciphertext is raised to the vote weight, so the aggregate encrypts the
+// weighted sums of the answers.
+func aggregateBallots(poll pollConfig, objects [][]byte) (Tally, homomorphicAggregate, error) {
+	answers := homomorphicAnswers(poll.method)
+	type total struct{ a, b *big.Int }
+	totals := make(map[int][]total, len(poll.options))
+	for _, optionID := range poll.options {
+		totals[optionID] = make([]total, len(answers))
+		for i := range answers {
+			totals[optionID][i] = total{big.NewInt(1), big.NewInt(1)}
+		}
+	}
+
+	var t Tally
+	for _, object := range objects {
+		t.Ballots++
+
+		var data voteObject
+		if err := json.Unmarshal(object, &data); err != nil {
+			t.Invalid++
+			continue
+		}
+
+		weight, err := ParseDecimal(data.Weight)
+		if err != nil || weight < 0 {
+			t.Invalid++
+			continue
+		}
+		t.VotesCast = t.sum(t.VotesCast, weight)
+
+		value, err := decodeHomomorphic(poll, data.Value)
+		if err != nil {
+			t.Invalid++
+			continue
+		}
+		t.VotesValid = t.sum(t.VotesValid, weight)
+
+		exponent := big.NewInt(int64(weight))
+		for optionID, option := range value {
+			for i, c := range option.Ciphertexts {
+				a := new(big.Int).Exp(new(big.Int).SetBytes(c.A), exponent, groupP)
+				b := new(big.Int).Exp(new(big.Int).SetBytes(c.B), exponent, groupP)
+				totals[optionID][i].a.Mul(totals[optionID][i].a, a).Mod(totals[optionID][i].a, groupP)
+				totals[optionID][i].b.Mul(totals[optionID][i].b, b).Mod(totals[optionID][i].b, groupP)
+			}
+		}
+	}
+
+	if t.err != nil {
+		return Tally{}, homomorphicAggregate{}, fmt.Errorf("summing votes: %w", t.err)
+	}
+
+	aggregate := homomorphicAggregate{
+		PollID:  poll.id,
+		Options: make(map[int][]homomorphicCiphertext, len(poll.options)),
+	}
+	for optionID, ciphertexts := range totals {
+		for _, c := range ciphertexts {
+			aggregate.Options[optionID] = append(aggregate.Options[optionID], homomorphicCiphertext{A: encodeElement(c.a), B: encodeElement(c.b)})
+		}
+	}
+
+	return t, aggregate, nil
+}
+
+// decryptAggregate adds the decrypted totals to the tally.
+//
+// The argument combined contains a^x for each ciphertext of the aggregate. It
+// is created from the partial decryptions of the trustees, so the private key x
+// is never known to the service.
+func decryptAggregate(poll pollConfig, t Tally, aggregate homomorphicAggregate, combined map[int][]*big.Int) (Tally, error) {
+	answers := homomorphicAnswers(poll.method)
+	for _, optionID := range poll.options {
+		t.addOption(optionID, "", 0)
+		for i, answer := range answers {
+			// g^m = b / a^x
+			m := new(big.Int).ModInverse(combined[optionID][i], groupP)
+			m.Mul(m, new(big.Int).SetBytes(aggregate.Options[optionID][i].B)).Mod(m, groupP)
+
+			sum, err := discreteLog(m, int64(t.VotesValid))
+			if err != nil {
+				return Tally{}, fmt.Errorf("decrypting total of answer %s for option %d: %w", answer, optionID, err)
+			}
+			t.addOption(optionID, answer, Decimal(sum))
+		}
+	}
+
+	return t, nil
+}
+
+// discreteLog finds m with g^m = value and 0 <= m <= maximum with the baby-step
+// giant-step algorithm.
+func discreteLog(value *big.Int, maximum int64) (int64, error) {
+	steps := int64(1)
+	for steps*steps <= maximum {
+		steps++
+	}
+
+	// The baby steps are saved by the first bytes of the element. Possible
+	// collisions are checked when a match is found.
+	baby := make(map[uint64][]int64, steps)
+	element := big.NewInt(1)
+	for j := int64(0); j < steps; j++ {
+		key := binary.BigEndian.Uint64(encodeElement(element)[groupElementSize-8:])
+		baby[key] = append(baby[key], j)
+		element.Mul(element, groupG).Mod(element, groupP)
+	}
+
+	// giant is g^-steps.
+	giant := new(big.Int).Exp(groupG, big.NewInt(steps), groupP)
+	giant.ModInverse(giant, groupP)
+
+	gamma := new(big.Int).Set(value)
+	for i := int64(0); i <= steps; i++ {
+		key := binary.BigEndian.Uint64(encodeElement(gamma)[groupElementSize-8:])
+		for _, j := range baby[key] {
+			m := i*steps + j
+			if new(big.Int).Exp(groupG, big.NewInt(m), groupP).Cmp(value) == 0 {
+				return m, nil
+			}
+		}
+		gamma.Mul(gamma, giant).Mod(gamma, groupP)
+	}
+
+	return 0, fmt.Errorf("value is not between 0 and %d", maximum)
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,12 +25,17 @@ var envVotePort = environment.NewVariable("VOTE_PORT", "9013", "Port on which th
 type Server struct {
 	Addr string
 	lst  net.Listener
+
+	signingKey ed25519.PrivateKey
 }
 
 // New initializes a new Server.
-func New(lookup environment.Environmenter) Server {
+//
+// If signingKey is not nil, the results of the stop requests are signed.
+func New(lookup environment.Environmenter, signingKey ed25519.PrivateKey) Server {
 	return Server{
-		Addr: ":" + envVotePort.Value(lookup),
+		Addr:       ":" + envVotePort.Value(lookup),
+		signingKey: signingKey,
 	}
 }
 
@@ -53,7 +60,7 @@ func (s *Server) Run(ctx context.Context, auth authenticater, service *vote.Vote
 		return ticker.C, ticker.Stop
 	}
 
-	mux := registerHandlers(service, auth, ticketProvider)
+	mux := registerHandlers(service, auth, ticketProvider, s.signingKey)
 
 	srv := &http.Server{
 		Handler:     mux,
@@ -92,7 +99,15 @@ type voteService interface {
 	clearAller
 	voteCounter
 	voter
+	validater
 	haveIvoteder
+	schemaer
+	encryptionKeyer
+	shareAdder
+	aggregater
+	decryptionAdder
+	migrater
+	healther
 }
 
 type authenticater interface {
@@ -100,7 +115,7 @@ type authenticater interface {
 	FromContext(context.Context) int
 }
 
-func registerHandlers(service voteService, auth authenticater, ticketProvider func() (<-chan time.Time, func())) *http.ServeMux {
+func registerHandlers(service voteService, auth authenticater, ticketProvider func() (<-chan time.Time, func()), signingKey ed25519.PrivateKey) *http.ServeMux {
 	const (
 		internal = "/internal/vote"
 		external = "/system/vote"
@@ -109,19 +124,27 @@ func registerHandlers(service voteService, auth authenticater, ticketProvider fu
 	mux := http.NewServeMux()
 
 	mux.Handle(internal+"/start", handleInternal(handleStart(service)))
-	mux.Handle(internal+"/stop", handleInternal(handleStop(service)))
+	mux.Handle(internal+"/stop", handleInternal(handleStop(service, signingKey)))
 	mux.Handle(internal+"/clear", handleInternal(handleClear(service)))
 	mux.Handle(internal+"/clear_all", handleInternal(handleClearAll(service)))
 	mux.Handle(internal+"/vote_count", handleInternal(handleVoteCount(service, ticketProvider)))
+	mux.Handle(internal+"/share", handleInternal(handleShare(service)))
+	mux.Handle(internal+"/aggregate", handleInternal(handleAggregate(service)))
+	mux.Handle(internal+"/decryption", handleInternal(handleDecryption(service)))
+	mux.Handle(internal+"/migrate", handleInternal(handleMigrate(service)))
 	mux.Handle(external+"", handleExternal(handleVote(service, auth)))
+	mux.Handle(external+"/validate", handleExternal(handleValidate(service, auth)))
 	mux.Handle(external+"/voted", handleExternal(handleVoted(service, auth)))
-	mux.Handle(external+"/health", handleExternal(handleHealth()))
+	mux.Handle(external+"/schema", handleExternal(handleSchema(service, auth)))
+	mux.Handle(external+"/encryption_key", handleExternal(handleEncryptionKey(service, auth)))
+	mux.Handle(external+"/health", handleExternal(handleHealth(service)))
+	mux.Handle(external+"/public_key", handleExternal(handlePublicKey(signingKey)))
 
 	return mux
 }
 
 type starter interface {
-	Start(ctx context.Context, pollID int) error
+	Start(ctx context.Context, pollID int, options ...vote.StartOption) error
 }
 
 func handleStart(start starter) HandlerFunc {
@@ -134,7 +157,58 @@ func handleStart(start starter) HandlerFunc {
 			return vote.WrapError(vote.ErrInvalid, err)
 		}
 
-		return start.Start(r.Context(), id)
+		var options []vote.StartOption
+		if rawRevotable := r.URL.Query().Get("revotable"); rawRevotable != "" {
+			revotable, err := strconv.ParseBool(rawRevotable)
+			if err != nil {
+				return vote.MessageError(vote.ErrInvalid, "revotable invalid. Expected bool, got %s", rawRevotable)
+			}
+
+			if revotable {
+				options = append(options, vote.Revotable())
+			}
+		}
+
+		if rawKey := r.URL.Query().Get("encryption_key"); rawKey != "" {
+			key, err := base64.StdEncoding.DecodeString(rawKey)
+			if err != nil {
+				return vote.MessageError(vote.ErrInvalid, "encryption_key invalid. Expected base64: %v", err)
+			}
+
+			options = append(options, vote.Encrypted(key))
+		}
+
+		if rawHomomorphic := r.URL.Query().Get("homomorphic"); rawHomomorphic != "" {
+			homomorphic, err := strconv.ParseBool(rawHomomorphic)
+			if err != nil {
+				return vote.MessageError(vote.ErrInvalid, "homomorphic invalid. Expected bool, got %s", rawHomomorphic)
+			}
+
+			if homomorphic {
+				options = append(options, vote.Homomorphic())
+			}
+		}
+
+		rawMinScore := r.URL.Query().Get("min_score")
+		rawMaxScore := r.URL.Query().Get("max_score")
+		if rawMinScore != "" || rawMaxScore != "" {
+			var minScore int
+			if rawMinScore != "" {
+				minScore, err = strconv.Atoi(rawMinScore)
+				if err != nil {
+					return vote.MessageError(vote.ErrInvalid, "min_score invalid. Expected int, got %s", rawMinScore)
+				}
+			}
+
+			maxScore, err := strconv.Atoi(rawMaxScore)
+			if err != nil {
+				return vote.MessageError(vote.ErrInvalid, "max_score invalid. Expected int, got %s", rawMaxScore)
+			}
+
+			options = append(options, vote.ScoreRange(minScore, maxScore))
+		}
+
+		return start.Start(r.Context(), id, options...)
 	}
 }
 
@@ -144,7 +218,7 @@ type stopper interface {
 	Stop(ctx context.Context, pollID int) (vote.StopResult, error)
 }
 
-func handleStop(stop stopper) HandlerFunc {
+func handleStop(stop stopper, signingKey ed25519.PrivateKey) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		log.Info("Receiving stop request")
 		w.Header().Set("Content-Type", "application/json")
@@ -154,11 +228,34 @@ func handleStop(stop stopper) HandlerFunc {
 			return vote.WrapError(vote.ErrInvalid, err)
 		}
 
+		query := r.URL.Query()
+		var rule *vote.MajorityRule
+		if majority := query.Get("majority"); majority != "" {
+			parsed, err := vote.ParseMajorityRule(majority, query.Get("base"), query.Get("quorum"), query.Get("quorum_base"))
+			if err != nil {
+				return vote.WrapError(vote.ErrInvalid, err)
+			}
+			rule = &parsed
+		}
+
 		result, err := stop.Stop(r.Context(), id)
 		if err != nil {
 			return err
 		}
 
+		// The poll is already stopped. So an evaluation, that is not possible
+		// with this result, is returned as message instead of an error.
+		var evaluation *vote.Evaluation
+		var evaluationError string
+		if rule != nil {
+			e, err := vote.Evaluate(result, *rule)
+			if err != nil {
+				evaluationError = err.Error()
+			} else {
+				evaluation = &e
+			}
+		}
+
 		// Convert vote objects to json.RawMessage
 		encodableObjects := make([]json.RawMessage, len(result.Votes))
 		for i := range result.Votes {
@@ -169,12 +266,52 @@ func handleStop(stop stopper) HandlerFunc {
 			result.UserIDs = []int{}
 		}
 
+		var merkle *vote.Merkle
+		if result.Merkle.Root != "" {
+			merkle = &result.Merkle
+		}
+
 		out := struct {
-			Votes []json.RawMessage `json:"votes"`
-			Users []int             `json:"user_ids"`
+			Votes         []json.RawMessage    `json:"votes"`
+			Users         []int                `json:"user_ids"`
+			Tally         vote.Tally           `json:"tally"`
+			ConfigChanged bool                 `json:"config_changed"`
+			Turnout       *vote.Turnout        `json:"turnout,omitempty"`
+			Evaluation    *vote.Evaluation     `json:"evaluation,omitempty"`
+			EvalError     string               `json:"evaluation_error,omitempty"`
+			Receipts      []string             `json:"receipts,omitempty"`
+			Merkle        *vote.Merkle         `json:"merkle,omitempty"`
+			Signature     *vote.Signature      `json:"signature,omitempty"`
+			Invalid       []vote.InvalidBallot `json:"invalid_ballots,omitempty"`
+			Withheld      bool                 `json:"ballots_withheld,omitempty"`
 		}{
 			encodableObjects,
 			result.UserIDs,
+			result.Tally,
+			result.ConfigChanged,
+			result.Turnout,
+			evaluation,
+			evaluationError,
+			result.Receipts,
+			merkle,
+			nil,
+			result.InvalidBallots,
+			result.BallotsWithheld,
+		}
+
+		// The signature is created over the encoded response without the
+		// signature.
+		if signingKey != nil {
+			encoded, err := json.Marshal(out)
+			if err != nil {
+				return fmt.Errorf("encoding result to sign: %w", err)
+			}
+
+			signature, err := vote.SignResult(signingKey, id, encoded, time.Now())
+			if err != nil {
+				return fmt.Errorf("signing result: %w", err)
+			}
+			out.Signature = &signature
 		}
 
 		if err := json.NewEncoder(w).Encode(out); err != nil {
@@ -184,6 +321,80 @@ func handleStop(stop stopper) HandlerFunc {
 	}
 }
 
+type shareAdder interface {
+	AddShare(ctx context.Context, pollID int, share string) error
+}
+
+func handleShare(service shareAdder) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving share request")
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		var body struct {
+			Share string `json:"share"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return vote.MessageError(vote.ErrInvalid, "decoding payload: %v", err)
+		}
+
+		return service.AddShare(r.Context(), id, body.Share)
+	}
+}
+
+type aggregater interface {
+	Aggregate(ctx context.Context, pollID int) (json.RawMessage, error)
+}
+
+func handleAggregate(service aggregater) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving aggregate request")
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		aggregate, err := service.Aggregate(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(aggregate); err != nil {
+			return fmt.Errorf("sending aggregate: %w", err)
+		}
+		return nil
+	}
+}
+
+type decryptionAdder interface {
+	AddDecryption(ctx context.Context, pollID int, partial []byte) error
+}
+
+func handleDecryption(service decryptionAdder) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving decryption request")
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("reading body: %w", err)
+		}
+
+		return service.AddDecryption(r.Context(), id, body)
+	}
+}
+
 type clearer interface {
 	Clear(ctx context.Context, pollID int) error
 }
@@ -202,6 +413,29 @@ func handleClear(clear clearer) HandlerFunc {
 	}
 }
 
+type migrater interface {
+	Migrate(ctx context.Context, pollID int, backend string) error
+}
+
+func handleMigrate(migrate migrater) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving migrate request")
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		backend := r.URL.Query().Get("backend")
+		if backend == "" {
+			return vote.MessageError(vote.ErrInvalid, "Query argument backend is required")
+		}
+
+		return migrate.Migrate(r.Context(), id, backend)
+	}
+}
+
 type clearAller interface {
 	ClearAll(ctx context.Context) error
 }
@@ -216,7 +450,7 @@ func handleClearAll(clear clearAller) HandlerFunc {
 }
 
 type voter interface {
-	Vote(ctx context.Context, pollID, requestUser int, r io.Reader) error
+	Vote(ctx context.Context, pollID, requestUser int, r io.Reader) (string, error)
 }
 
 func handleVote(service voter, auth authenticater) HandlerFunc {
@@ -239,7 +473,63 @@ func handleVote(service voter, auth authenticater) HandlerFunc {
 			return vote.WrapError(vote.ErrInvalid, err)
 		}
 
-		return service.Vote(ctx, id, uid, r.Body)
+		receipt, err := service.Vote(ctx, id, uid, r.Body)
+		if err != nil {
+			return err
+		}
+
+		if receipt == "" {
+			return nil
+		}
+
+		out := struct {
+			Receipt string `json:"receipt"`
+		}{
+			receipt,
+		}
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			return fmt.Errorf("encoding and sending receipt: %w", err)
+		}
+
+		return nil
+	}
+}
+
+type validater interface {
+	Validate(ctx context.Context, pollID, requestUser int, r io.Reader) (json.RawMessage, error)
+}
+
+func handleValidate(service validater, auth authenticater) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving validate request")
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, err := auth.Authenticate(w, r)
+		if err != nil {
+			return err
+		}
+
+		uid := auth.FromContext(ctx)
+		if uid == 0 {
+			return statusCode(401, vote.MessageError(vote.ErrNotAllowed, "Anonymous user can not vote"))
+		}
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		object, err := service.Validate(ctx, id, uid, r.Body)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(object); err != nil {
+			return fmt.Errorf("sending vote object: %w", err)
+		}
+
+		return nil
 	}
 }
 
@@ -280,6 +570,43 @@ func handleVoted(voted haveIvoteder, auth authenticater) HandlerFunc {
 	}
 }
 
+type schemaer interface {
+	Schema(ctx context.Context, pollID int) (map[string]any, error)
+}
+
+func handleSchema(schema schemaer, auth authenticater) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving schema request")
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, err := auth.Authenticate(w, r)
+		if err != nil {
+			return err
+		}
+
+		uid := auth.FromContext(ctx)
+		if uid == 0 {
+			return statusCode(401, vote.MessageError(vote.ErrNotAllowed, "Anonymous user can not vote"))
+		}
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		s, err := schema.Schema(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			return fmt.Errorf("encoding and sending schema: %w", err)
+		}
+
+		return nil
+	}
+}
+
 type voteCounter interface {
 	VoteCount(ctx context.Context) map[int]int
 }
@@ -344,15 +671,90 @@ func handleVoteCount(voteCounter voteCounter, eventer func() (<-chan time.Time,
 	}
 }
 
-func handleHealth() HandlerFunc {
+type healther interface {
+	Degraded() bool
+}
+
+func handleHealth(service healther) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Set("Content-Type", "application/json")
 
+		if service.Degraded() {
+			fmt.Fprintf(w, `{"healthy":true,"degraded":true}`)
+			return nil
+		}
+
 		fmt.Fprintf(w, `{"healthy":true}`)
 		return nil
 	}
 }
 
+type encryptionKeyer interface {
+	EncryptionKey(ctx context.Context, pollID int) ([]byte, error)
+}
+
+func handleEncryptionKey(service encryptionKeyer, auth authenticater) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving encryption key request")
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, err := auth.Authenticate(w, r)
+		if err != nil {
+			return err
+		}
+
+		uid := auth.FromContext(ctx)
+		if uid == 0 {
+			return statusCode(401, vote.MessageError(vote.ErrNotAllowed, "Anonymous user can not vote"))
+		}
+
+		id, err := pollID(r)
+		if err != nil {
+			return vote.WrapError(vote.ErrInvalid, err)
+		}
+
+		key, err := service.EncryptionKey(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		out := struct {
+			EncryptionKey []byte `json:"encryption_key"`
+		}{
+			key,
+		}
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			return fmt.Errorf("encoding and sending encryption key: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func handlePublicKey(signingKey ed25519.PrivateKey) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log.Info("Receiving public key request")
+		w.Header().Set("Content-Type", "application/json")
+
+		if signingKey == nil {
+			return vote.MessageError(vote.ErrNotExists, "The service has no signing key")
+		}
+
+		out := struct {
+			PublicKey []byte `json:"public_key"`
+		}{
+			signingKey.Public().(ed25519.PublicKey),
+		}
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			return fmt.Errorf("encoding and sending public key: %w", err)
+		}
+
+		return nil
+	}
+}
+
 // HealthClient sends a http request to a server to fetch the health status.
 func HealthClient(ctx context.Context, useHTTPS bool, host, port string, insecure bool) error {
 	proto := "http"
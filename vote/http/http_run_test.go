@@ -46,7 +46,7 @@ func TestRun(t *testing.T) {
 	backend := memory.New()
 	ds := dsmock.NewFlow(nil)
 	service, _, _ := vote.New(ctx, backend, backend, ds, true)
-	httpServer := votehttp.New(environment.ForTests(map[string]string{"VOTE_PORT": "0"}))
+	httpServer := votehttp.New(environment.ForTests(map[string]string{"VOTE_PORT": "0"}), nil)
 
 	if err := httpServer.StartListener(); err != nil {
 		t.Fatalf("start listening: %v", err)
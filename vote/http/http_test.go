@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"io"
@@ -17,11 +18,13 @@ import (
 
 type starterStub struct {
 	id        int
+	options   int
 	expectErr error
 }
 
-func (c *starterStub) Start(ctx context.Context, pollID int) error {
+func (c *starterStub) Start(ctx context.Context, pollID int, options ...vote.StartOption) error {
 	c.id = pollID
+	c.options = len(options)
 	return c.expectErr
 }
 
@@ -60,6 +63,89 @@ func TestHandleStart(t *testing.T) {
 		if starter.id != 1 {
 			t.Errorf("Start was called with id %d, expected 1", starter.id)
 		}
+
+		if starter.options != 0 {
+			t.Errorf("Start was called with %d options, expected 0", starter.options)
+		}
+	})
+
+	t.Run("Revotable", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&revotable=true", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		if starter.options != 1 {
+			t.Errorf("Start was called with %d options, expected 1", starter.options)
+		}
+	})
+
+	t.Run("Invalid revotable", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&revotable=maybe", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Score range", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&min_score=-2&max_score=2", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		if starter.options != 1 {
+			t.Errorf("Start was called with %d options, expected 1", starter.options)
+		}
+	})
+
+	t.Run("Invalid score range", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&min_score=1", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Encryption key", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&encryption_key=a2V5", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		if starter.options != 1 {
+			t.Errorf("Start was called with %d options, expected 1", starter.options)
+		}
+	})
+
+	t.Run("Homomorphic", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&encryption_key=a2V5&homomorphic=true", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		if starter.options != 2 {
+			t.Errorf("Start was called with %d options, expected 2", starter.options)
+		}
+	})
+
+	t.Run("Invalid encryption key", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&encryption_key=not-base64!", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
 	})
 
 	t.Run("Exist error", func(t *testing.T) {
@@ -120,6 +206,7 @@ type stopperStub struct {
 
 	expectedVotes   [][]byte
 	expectedUserIDs []int
+	expectedTally   vote.Tally
 }
 
 func (s *stopperStub) Stop(ctx context.Context, pollID int) (vote.StopResult, error) {
@@ -132,6 +219,8 @@ func (s *stopperStub) Stop(ctx context.Context, pollID int) (vote.StopResult, er
 	return vote.StopResult{
 		Votes:   s.expectedVotes,
 		UserIDs: s.expectedUserIDs,
+		Tally:   s.expectedTally,
+		Method:  "YN",
 	}, nil
 }
 
@@ -139,7 +228,7 @@ func TestHandleStop(t *testing.T) {
 	stopper := &stopperStub{}
 
 	url := "/vote/stop"
-	mux := handleInternal(handleStop(stopper))
+	mux := handleInternal(handleStop(stopper, nil))
 
 	t.Run("No id", func(t *testing.T) {
 		resp := httptest.NewRecorder()
@@ -164,12 +253,114 @@ func TestHandleStop(t *testing.T) {
 			t.Errorf("Stopper was called with id %d, expected 1", stopper.id)
 		}
 
-		expect := `{"votes":["some values"],"user_ids":[]}`
+		expect := `{"votes":["some values"],"user_ids":[],"tally":{"ballots":0,"invalid":0,"votescast":"0.000000","votesvalid":"0.000000"},"config_changed":false}`
 		if trimed := strings.TrimSpace(resp.Body.String()); trimed != expect {
 			t.Errorf("Got body:\n`%s`, expected:\n`%s`", trimed, expect)
 		}
 	})
 
+	t.Run("With majority", func(t *testing.T) {
+		stopper.expectedVotes = nil
+		stopper.expectedTally = vote.Tally{
+			VotesCast: vote.DecimalFromInt(3),
+			Options:   map[int]map[string]vote.Decimal{1: {"Y": vote.DecimalFromInt(2)}},
+		}
+		defer func() { stopper.expectedTally = vote.Tally{} }()
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&majority=absolute", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Fatalf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		var body struct {
+			Evaluation vote.Evaluation `json:"evaluation"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding resp body: %v", err)
+		}
+
+		if !body.Evaluation.Options[1].Passed {
+			t.Errorf("Option 1 did not pass: %v", body.Evaluation)
+		}
+	})
+
+	t.Run("With majority, that can not be evaluated", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&majority=absolute&quorum=1/2", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Fatalf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		var body struct {
+			Evaluation      *vote.Evaluation `json:"evaluation"`
+			EvaluationError string           `json:"evaluation_error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding resp body: %v", err)
+		}
+
+		if body.Evaluation != nil || body.EvaluationError == "" {
+			t.Errorf("Got evaluation %v and error %q, expected only an error", body.Evaluation, body.EvaluationError)
+		}
+	})
+
+	t.Run("Signed", func(t *testing.T) {
+		stopper.expectedVotes = [][]byte{[]byte(`{"value":"Y"}`)}
+		stopper.expectedUserIDs = []int{1}
+		defer func() {
+			stopper.expectedVotes = nil
+			stopper.expectedUserIDs = nil
+		}()
+
+		key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+		signedMux := handleInternal(handleStop(stopper, key))
+
+		resp := httptest.NewRecorder()
+		signedMux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Fatalf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		raw := resp.Body.Bytes()
+		var body struct {
+			Signature *vote.Signature `json:"signature"`
+		}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Fatalf("decoding resp body: %v", err)
+		}
+
+		if body.Signature == nil {
+			t.Fatalf("Response contains no signature")
+		}
+
+		if body.Signature.PollID != 1 {
+			t.Errorf("Signature has poll id %d, expected 1", body.Signature.PollID)
+		}
+
+		if err := body.Signature.Verify(key.Public().(ed25519.PublicKey), raw); err != nil {
+			t.Errorf("Signature is invalid: %v", err)
+		}
+	})
+
+	t.Run("Invalid majority", func(t *testing.T) {
+		stopper.id = 0
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&majority=most", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+
+		if stopper.id != 0 {
+			t.Errorf("The poll was stopped with an invalid majority")
+		}
+	})
+
 	t.Run("Not Exist error", func(t *testing.T) {
 		stopper.expectErr = vote.ErrNotExists
 
@@ -256,6 +447,67 @@ func TestHandleClear(t *testing.T) {
 	})
 }
 
+type migraterStub struct {
+	id        int
+	backend   string
+	expectErr error
+}
+
+func (m *migraterStub) Migrate(ctx context.Context, pollID int, backend string) error {
+	m.id = pollID
+	m.backend = backend
+	return m.expectErr
+}
+
+func TestHandleMigrate(t *testing.T) {
+	migrater := &migraterStub{}
+
+	url := "/vote/migrate"
+	mux := handleInternal(handleMigrate(migrater))
+
+	t.Run("No id", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?backend=long", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400 - Bad Request", resp.Result().Status)
+		}
+	})
+
+	t.Run("No backend", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400 - Bad Request", resp.Result().Status)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&backend=long", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		if migrater.id != 1 || migrater.backend != "long" {
+			t.Errorf("Migrater was called with id %d and backend %s, expected 1 and long", migrater.id, migrater.backend)
+		}
+	})
+
+	t.Run("Invalid error", func(t *testing.T) {
+		migrater.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1&backend=long", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
 type clearAllerStub struct {
 	expectErr error
 }
@@ -304,22 +556,27 @@ func TestHandleClearAll(t *testing.T) {
 }
 
 type voterStub struct {
-	id        int
-	user      int
-	body      string
-	expectErr error
+	id            int
+	user          int
+	body          string
+	expectReceipt string
+	expectErr     error
 }
 
-func (v *voterStub) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) error {
+func (v *voterStub) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) (string, error) {
 	v.id = pollID
 	v.user = requestUser
 
 	body, err := io.ReadAll(r)
 	if err != nil {
-		return err
+		return "", err
 	}
 	v.body = string(body)
-	return v.expectErr
+
+	if v.expectErr != nil {
+		return "", v.expectErr
+	}
+	return v.expectReceipt, nil
 }
 
 type AuthError struct{}
@@ -459,6 +716,114 @@ func TestHandleVote(t *testing.T) {
 		if voter.body != "request body" {
 			t.Errorf("Voter was called with body `%s` expected `request body`", voter.body)
 		}
+
+		if got := resp.Body.String(); got != "" {
+			t.Errorf("Got body `%s`, expected an empty body", got)
+		}
+	})
+
+	t.Run("With receipt", func(t *testing.T) {
+		auther.userID = 5
+		voter.expectErr = nil
+		voter.expectReceipt = "my-receipt"
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		expect := `{"receipt":"my-receipt"}` + "\n"
+		if got := resp.Body.String(); got != expect {
+			t.Errorf("Got body `%s`, expected `%s`", got, expect)
+		}
+	})
+}
+
+type validaterStub struct {
+	id           int
+	user         int
+	body         string
+	expectObject string
+	expectErr    error
+}
+
+func (v *validaterStub) Validate(ctx context.Context, pollID, requestUser int, r io.Reader) (json.RawMessage, error) {
+	v.id = pollID
+	v.user = requestUser
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	v.body = string(body)
+
+	if v.expectErr != nil {
+		return nil, v.expectErr
+	}
+	return json.RawMessage(v.expectObject), nil
+}
+
+func TestHandleValidate(t *testing.T) {
+	validater := &validaterStub{}
+	auther := &autherStub{}
+
+	url := "/system/vote/validate"
+	mux := handleExternal(handleValidate(validater, auther))
+
+	t.Run("No id", func(t *testing.T) {
+		auther.userID = 5
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url, strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		auther.userID = 0
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 401 {
+			t.Errorf("Got status %s, expected 401", resp.Result().Status)
+		}
+	})
+
+	t.Run("Invalid vote", func(t *testing.T) {
+		auther.userID = 5
+		validater.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		auther.userID = 5
+		validater.expectErr = nil
+		validater.expectObject = `{"value":"Y","weight":"1.000000"}`
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader("request body")))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if validater.id != 1 || validater.user != 5 || validater.body != "request body" {
+			t.Errorf("Validate was called with id %d, user %d and body `%s`, expected 1, 5 and `request body`", validater.id, validater.user, validater.body)
+		}
+
+		if got := resp.Body.String(); got != validater.expectObject {
+			t.Errorf("Got body `%s`, expected `%s`", got, validater.expectObject)
+		}
 	})
 }
 
@@ -595,6 +960,83 @@ func TestHandleVoted(t *testing.T) {
 	})
 }
 
+type schemaerStub struct {
+	id           int
+	expectSchema map[string]any
+	expectErr    error
+}
+
+func (s *schemaerStub) Schema(ctx context.Context, pollID int) (map[string]any, error) {
+	s.id = pollID
+
+	if s.expectErr != nil {
+		return nil, s.expectErr
+	}
+	return s.expectSchema, nil
+}
+
+func TestHandleSchema(t *testing.T) {
+	schemaer := &schemaerStub{}
+	auther := &autherStub{}
+
+	url := "/system/vote/schema"
+	mux := handleExternal(handleSchema(schemaer, auther))
+
+	t.Run("No id", func(t *testing.T) {
+		auther.userID = 5
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		auther.userID = 0
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 401 {
+			t.Errorf("Got status %s, expected 401", resp.Result().Status)
+		}
+	})
+
+	t.Run("Correct", func(t *testing.T) {
+		auther.userID = 5
+		schemaer.expectSchema = map[string]any{"type": "object"}
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if schemaer.id != 1 {
+			t.Errorf("Schema was called with id %d, expected 1", schemaer.id)
+		}
+
+		expect := `{"type":"object"}` + "\n"
+		if got := resp.Body.String(); got != expect {
+			t.Errorf("Got body `%s`, expected `%s`", got, expect)
+		}
+	})
+
+	t.Run("Schema Error", func(t *testing.T) {
+		auther.userID = 5
+		schemaer.expectErr = vote.ErrNotExists
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
 type voteCounterStub struct {
 	expectCount map[int]int
 }
@@ -740,21 +1182,318 @@ func TestHandleVoteCountSecondData(t *testing.T) {
 	}
 }
 
+type shareAdderStub struct {
+	id        int
+	share     string
+	expectErr error
+}
+
+func (s *shareAdderStub) AddShare(ctx context.Context, pollID int, share string) error {
+	s.id = pollID
+	s.share = share
+	return s.expectErr
+}
+
+func TestHandleShare(t *testing.T) {
+	adder := &shareAdderStub{}
+
+	url := "/vote/share"
+	mux := handleInternal(handleShare(adder))
+
+	t.Run("No id", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url, strings.NewReader(`{"share":"abc"}`)))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader(`not json`)))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader(`{"share":"abc"}`)))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if adder.id != 1 || adder.share != "abc" {
+			t.Errorf("AddShare was called with id %d and share %s, expected 1 and abc", adder.id, adder.share)
+		}
+	})
+
+	t.Run("Invalid share", func(t *testing.T) {
+		adder.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader(`{"share":"abc"}`)))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
+type aggregaterStub struct {
+	id              int
+	expectAggregate json.RawMessage
+	expectErr       error
+}
+
+func (a *aggregaterStub) Aggregate(ctx context.Context, pollID int) (json.RawMessage, error) {
+	a.id = pollID
+	return a.expectAggregate, a.expectErr
+}
+
+func TestHandleAggregate(t *testing.T) {
+	aggregater := &aggregaterStub{}
+
+	url := "/vote/aggregate"
+	mux := handleInternal(handleAggregate(aggregater))
+
+	t.Run("No id", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url, nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		aggregater.expectAggregate = json.RawMessage(`{"poll_id":1,"options":{}}`)
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if aggregater.id != 1 {
+			t.Errorf("Aggregate was called with id %d, expected 1", aggregater.id)
+		}
+
+		if got := resp.Body.String(); got != `{"poll_id":1,"options":{}}` {
+			t.Errorf("Got body %s, expected the aggregate", got)
+		}
+	})
+
+	t.Run("Not stopped", func(t *testing.T) {
+		aggregater.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
+type decryptionAdderStub struct {
+	id        int
+	partial   string
+	expectErr error
+}
+
+func (d *decryptionAdderStub) AddDecryption(ctx context.Context, pollID int, partial []byte) error {
+	d.id = pollID
+	d.partial = string(partial)
+	return d.expectErr
+}
+
+func TestHandleDecryption(t *testing.T) {
+	adder := &decryptionAdderStub{}
+
+	url := "/vote/decryption"
+	mux := handleInternal(handleDecryption(adder))
+
+	t.Run("No id", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url, strings.NewReader(`{"index":1}`)))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader(`{"index":1}`)))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if adder.id != 1 || adder.partial != `{"index":1}` {
+			t.Errorf("AddDecryption was called with id %d and %s, expected 1 and the body", adder.id, adder.partial)
+		}
+	})
+
+	t.Run("Invalid proof", func(t *testing.T) {
+		adder.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("POST", url+"?id=1", strings.NewReader(`{"index":1}`)))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
+type encryptionKeyerStub struct {
+	id        int
+	expectKey []byte
+	expectErr error
+}
+
+func (e *encryptionKeyerStub) EncryptionKey(ctx context.Context, pollID int) ([]byte, error) {
+	e.id = pollID
+	return e.expectKey, e.expectErr
+}
+
+func TestHandleEncryptionKey(t *testing.T) {
+	keyer := &encryptionKeyerStub{}
+	auther := &autherStub{}
+
+	url := "/system/vote/encryption_key"
+	mux := handleExternal(handleEncryptionKey(keyer, auther))
+
+	t.Run("Anonymous", func(t *testing.T) {
+		auther.userID = 0
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 401 {
+			t.Errorf("Got status %s, expected 401", resp.Result().Status)
+		}
+	})
+
+	t.Run("Correct", func(t *testing.T) {
+		auther.userID = 5
+		keyer.expectKey = []byte("key")
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		if keyer.id != 1 {
+			t.Errorf("EncryptionKey was called with id %d, expected 1", keyer.id)
+		}
+
+		expect := `{"encryption_key":"a2V5"}` + "\n"
+		if got := resp.Body.String(); got != expect {
+			t.Errorf("Got body `%s`, expected `%s`", got, expect)
+		}
+	})
+
+	t.Run("Not encrypted", func(t *testing.T) {
+		auther.userID = 5
+		keyer.expectErr = vote.ErrInvalid
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url+"?id=1", nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+}
+
+func TestHandlePublicKey(t *testing.T) {
+	url := "/system/vote/public_key"
+
+	t.Run("No key", func(t *testing.T) {
+		mux := handleExternal(handlePublicKey(nil))
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+
+		if resp.Result().StatusCode != 400 {
+			t.Errorf("Got status %s, expected 400", resp.Result().Status)
+		}
+	})
+
+	t.Run("With key", func(t *testing.T) {
+		key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+		mux := handleExternal(handlePublicKey(key))
+
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Fatalf("Got status %s, expected 200", resp.Result().Status)
+		}
+
+		var body struct {
+			PublicKey []byte `json:"public_key"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding resp body: %v", err)
+		}
+
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(body.PublicKey)) {
+			t.Errorf("Got public key %x, expected %x", body.PublicKey, key.Public())
+		}
+	})
+}
+
+type healtherStub struct {
+	degraded bool
+}
+
+func (h *healtherStub) Degraded() bool {
+	return h.degraded
+}
+
 func TestHandleHealth(t *testing.T) {
 	url := "/system/vote/health"
-	mux := handleHealth()
+	healther := &healtherStub{}
+	mux := handleHealth(healther)
 
-	resp := httptest.NewRecorder()
-	mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+	t.Run("Healthy", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
 
-	if resp.Result().StatusCode != 200 {
-		t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
-	}
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
 
-	expect := `{"healthy":true}`
-	if got := resp.Body.String(); got != expect {
-		t.Errorf("Got body `%s`, expected `%s`", got, expect)
-	}
+		expect := `{"healthy":true}`
+		if got := resp.Body.String(); got != expect {
+			t.Errorf("Got body `%s`, expected `%s`", got, expect)
+		}
+	})
+
+	t.Run("Degraded", func(t *testing.T) {
+		healther.degraded = true
+		resp := httptest.NewRecorder()
+		mux.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+
+		if resp.Result().StatusCode != 200 {
+			t.Errorf("Got status %s, expected 200 - OK", resp.Result().Status)
+		}
+
+		expect := `{"healthy":true,"degraded":true}`
+		if got := resp.Body.String(); got != expect {
+			t.Errorf("Got body `%s`, expected `%s`", got, expect)
+		}
+	})
 }
 
 type onFlush struct {
@@ -0,0 +1,178 @@
+package vote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// Prefixes for the hashes of the merkle tree. They make sure, that a leaf can
+// not be used as an inner node and the other way around.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// Merkle is a merkle tree over the vote objects of a poll.
+//
+// The leaves are the sha256 hashes of the vote objects. They are sorted by
+// their hash, so the tree does not depend on the order of the vote objects.
+type Merkle struct {
+	// Root is the hex encoded root hash of the tree.
+	Root string `json:"root"`
+
+	// Proofs contains the inclusion proof for each vote object in the same
+	// order as the vote objects.
+	Proofs []MerkleProof `json:"proofs"`
+}
+
+// MerkleProof is the proof that a vote object is part of a merkle tree.
+type MerkleProof struct {
+	// Path contains the sibling hashes from the leaf to the root.
+	Path []MerkleStep `json:"path"`
+}
+
+// MerkleStep is one step from a leaf to the root.
+type MerkleStep struct {
+	// Hash is the hex encoded hash of the sibling.
+	Hash string `json:"hash"`
+
+	// Left is true, if the sibling is the left node.
+	Left bool `json:"left"`
+}
+
+// NewMerkle creates the merkle tree for the vote objects.
+func NewMerkle(objects [][]byte) Merkle {
+	type leaf struct {
+		hash  []byte
+		index int
+	}
+
+	leaves := make([]leaf, len(objects))
+	for i, object := range objects {
+		leaves[i] = leaf{hash: merkleLeafHash(object), index: i}
+	}
+
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].hash, leaves[j].hash) < 0
+	})
+
+	// position is the position of each vote object on the current level.
+	position := make([]int, len(objects))
+	level := make([][]byte, len(leaves))
+	for i, l := range leaves {
+		level[i] = l.hash
+		position[l.index] = i
+	}
+
+	proofs := make([]MerkleProof, len(objects))
+	for i := range proofs {
+		proofs[i].Path = []MerkleStep{}
+	}
+
+	if len(level) == 0 {
+		return Merkle{Root: hex.EncodeToString(merkleEmptyHash()), Proofs: proofs}
+	}
+
+	for len(level) > 1 {
+		for i, p := range position {
+			sibling := p ^ 1
+			if sibling >= len(level) {
+				// The last node of a level with an odd number of nodes has no
+				// sibling. It is moved to the next level.
+				continue
+			}
+
+			proofs[i].Path = append(proofs[i].Path, MerkleStep{
+				Hash: hex.EncodeToString(level[sibling]),
+				Left: sibling < p,
+			})
+		}
+
+		level = merkleNextLevel(level)
+		for i := range position {
+			position[i] /= 2
+		}
+	}
+
+	return Merkle{Root: hex.EncodeToString(level[0]), Proofs: proofs}
+}
+
+// MerkleRoot returns the hex encoded root hash of the vote objects.
+func MerkleRoot(objects [][]byte) string {
+	hashes := make([][]byte, len(objects))
+	for i, object := range objects {
+		hashes[i] = merkleLeafHash(object)
+	}
+
+	if len(hashes) == 0 {
+		return hex.EncodeToString(merkleEmptyHash())
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i], hashes[j]) < 0
+	})
+
+	for len(hashes) > 1 {
+		hashes = merkleNextLevel(hashes)
+	}
+	return hex.EncodeToString(hashes[0])
+}
+
+// Verify checks, that the vote object is part of the tree with the given root.
+func (p MerkleProof) Verify(object []byte, root string) error {
+	hash := merkleLeafHash(object)
+	for i, step := range p.Path {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return fmt.Errorf("decoding hash of step %d: %w", i, err)
+		}
+
+		if step.Left {
+			hash = merkleNodeHash(sibling, hash)
+		} else {
+			hash = merkleNodeHash(hash, sibling)
+		}
+	}
+
+	if got := hex.EncodeToString(hash); got != root {
+		return fmt.Errorf("proof leads to root %s, expected %s", got, root)
+	}
+	return nil
+}
+
+// merkleNextLevel calculates the parent nodes of a level.
+func merkleNextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, merkleNodeHash(level[i], level[i+1]))
+	}
+	return next
+}
+
+func merkleLeafHash(object []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(object)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleEmptyHash is the root of a tree without leaves.
+func merkleEmptyHash() []byte {
+	h := sha256.Sum256(nil)
+	return h[:]
+}
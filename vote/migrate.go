@@ -0,0 +1,251 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
+)
+
+// Importer is a Backend that can import a running poll from another backend.
+type Importer interface {
+	// Import starts a poll with the given config, the ids of the users that
+	// have already voted and their vote objects. The vote objects must not be
+	// linked to the user ids.
+	//
+	// The import has to be atomic. If the poll already exists in the backend,
+	// an error is returned and nothing is changed.
+	Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error
+}
+
+// StopChecker is a Backend that tells, if a poll was already stopped before.
+type StopChecker interface {
+	// StopChecked stops a poll like Stop. stoppedBefore is true, if the poll
+	// was already stopped before this call.
+	StopChecked(ctx context.Context, pollID int) (objects [][]byte, userIDs []int, stoppedBefore bool, err error)
+}
+
+// Migrate moves a running poll to another backend.
+//
+// The poll is stopped in its current backend, so no vote can be saved there
+// anymore. Afterwards, the config, the ids of the users that have voted and
+// the vote objects are imported into the other backend. From then on, the
+// poll uses the new backend.
+//
+// If the poll was already stopped in its current backend, it is not migrated.
+// Another instance could have stopped the poll and counted its votes, while
+// the datastore still says, that it is started. If a migration fails after the
+// poll was stopped, the poll has to be stopped with Stop.
+//
+// Polls that allow to change the vote can not be migrated, since the link
+// between the users and their vote objects is removed when the poll is
+// stopped.
+func (v *Vote) Migrate(ctx context.Context, pollID int, backendName string) error {
+	poll, err := loadPoll(ctx, dsfetch.New(v.flow), pollID)
+	if err != nil {
+		return fmt.Errorf("loading poll: %w", err)
+	}
+
+	target, ok := v.backends[backendName]
+	if !ok {
+		return MessageError(ErrInvalid, "Unknown backend %s", backendName)
+	}
+
+	importer, ok := target.(Importer)
+	if !ok {
+		return MessageError(ErrInvalid, "Backend %s can not import polls", backendName)
+	}
+
+	sourceName := v.backendName(poll)
+	source := v.backends[sourceName]
+	if sourceName == backendName || source == target {
+		return MessageError(ErrInvalid, "Poll %d already uses backend %s", pollID, backendName)
+	}
+
+	stopper, ok := source.(StopChecker)
+	if !ok {
+		return MessageError(ErrInvalid, "Polls can not be migrated from backend %s", sourceName)
+	}
+
+	if poll.state != "started" {
+		return MessageError(ErrInvalid, "Only started polls can be migrated")
+	}
+
+	// Votes on this instance wait until the migration is done. Votes on other
+	// instances get a stopped error from the old backend and wait for the poll
+	// in the new backend.
+	v.migrateMu.Lock()
+	defer v.migrateMu.Unlock()
+
+	frozen, err := frozenConfig(ctx, source, poll)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if errors.As(err, &errNotExist) {
+			return MessageError(ErrNotExists, "Poll %d does not exist in the backend", pollID)
+		}
+		return fmt.Errorf("loading poll config from backend: %w", err)
+	}
+
+	if frozen.revotable {
+		return MessageError(ErrInvalid, "Polls that allow to change the vote can not be migrated")
+	}
+
+	migrated, exists, err := migratedFrom(ctx, target, pollID)
+	if err != nil {
+		return fmt.Errorf("checking poll in backend %s: %w", backendName, err)
+	}
+
+	// If the source backend mirrors its polls into the target backend, the
+	// poll exists in the target as a copy. The copy is replaced with the
+	// result from the source backend.
+	mirrored, ok := source.(interface{ Mirror() Backend })
+	mirrorCopy := ok && mirrored.Mirror() == target
+
+	switch {
+	case exists && migrated == sourceName:
+		// An earlier migration imported the poll but could not finish.
+
+	case exists && !mirrorCopy:
+		return MessageError(ErrInvalid, "Poll %d already exists in backend %s", pollID, backendName)
+
+	default:
+		objects, userIDs, stoppedBefore, err := stopper.StopChecked(ctx, pollID)
+		if err != nil {
+			return fmt.Errorf("stopping poll in backend %s: %w", sourceName, err)
+		}
+
+		if stoppedBefore {
+			return MessageError(ErrInvalid, "Poll %d is already stopped in backend %s", pollID, sourceName)
+		}
+
+		if exists {
+			if err := target.Clear(ctx, pollID); err != nil {
+				return fmt.Errorf("removing the mirrored poll from backend %s: %w", backendName, err)
+			}
+		}
+
+		frozen.migratedFrom = sourceName
+		config, err := json.Marshal(frozen)
+		if err != nil {
+			return fmt.Errorf("encoding poll config: %w", err)
+		}
+
+		if err := importer.Import(ctx, pollID, config, userIDs, objects); err != nil {
+			return fmt.Errorf("importing poll into backend %s: %w", backendName, err)
+		}
+	}
+
+	v.migratedMu.Lock()
+	v.migrated[pollID] = backendName
+	v.migratedMu.Unlock()
+
+	return nil
+}
+
+// migrationChecks is the number of checks for a poll, that is migrated by
+// another instance. migrationWait is the time between two checks.
+const migrationChecks = 20
+
+var migrationWait = 50 * time.Millisecond
+
+// waitForMigration is called, when the backend of a poll reports, that the
+// poll is stopped. If the poll is still started in the datastore, another
+// instance could be migrating it. The poll is stopped in the old backend
+// but not yet imported into the new one. So it looks for the migrated poll
+// until it is found, the poll is stopped in the datastore or the time is up.
+func (v *Vote) waitForMigration(ctx context.Context, poll pollConfig) (bool, error) {
+	ds := dsfetch.New(v.flow)
+	for i := 0; i < migrationChecks; i++ {
+		migrated, err := v.discoverMigration(ctx, poll)
+		if err != nil || migrated {
+			return migrated, err
+		}
+
+		current, err := loadPoll(ctx, ds, poll.id)
+		if err != nil {
+			return false, fmt.Errorf("loading poll: %w", err)
+		}
+
+		if current.state != "started" {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(migrationWait):
+		}
+	}
+	return false, nil
+}
+
+// discoverMigration looks for a poll, that was migrated by another instance of
+// the service. It returns true, if the poll was found in another backend.
+func (v *Vote) discoverMigration(ctx context.Context, poll pollConfig) (bool, error) {
+	current := v.backendName(poll)
+	for _, name := range v.backendNames() {
+		if name == current || v.backends[name] == v.backends[current] {
+			continue
+		}
+
+		migrated, _, err := migratedFrom(ctx, v.backends[name], poll.id)
+		if err != nil {
+			return false, fmt.Errorf("checking poll in backend %s: %w", name, err)
+		}
+
+		if migrated != current {
+			continue
+		}
+
+		v.migratedMu.Lock()
+		v.migrated[poll.id] = name
+		v.migratedMu.Unlock()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// followMigrations updates the backend of a poll, if it was migrated one or
+// many times by another instance.
+func (v *Vote) followMigrations(ctx context.Context, poll pollConfig) error {
+	for range v.backends {
+		migrated, err := v.discoverMigration(ctx, poll)
+		if err != nil {
+			return err
+		}
+
+		if !migrated {
+			return nil
+		}
+	}
+	return nil
+}
+
+// migratedFrom returns the name of the backend, from which the poll was
+// migrated into the given backend. It is empty, if the poll was started in the
+// backend. exists is false, if the poll does not exist in the backend.
+func migratedFrom(ctx context.Context, backend Backend, pollID int) (from string, exists bool, err error) {
+	raw, err := backend.Config(ctx, pollID)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if errors.As(err, &errNotExist) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("fetching poll config: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return "", true, nil
+	}
+
+	var config pollConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return "", false, fmt.Errorf("decoding poll config: %w", err)
+	}
+
+	return config.migratedFrom, true, nil
+}
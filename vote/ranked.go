@@ -0,0 +1,148 @@
+package vote
+
+import (
+	"slices"
+)
+
+// RankedResult is the result of an instant runoff count of a ranked poll.
+type RankedResult struct {
+	// Rounds is the protocol of all counting rounds.
+	Rounds []RankedRound `json:"rounds"`
+
+	// Winner is the option id of the option that reached the absolute
+	// majority. It is 0, if there is no winner.
+	Winner int `json:"winner,omitempty"`
+
+	// Tie contains the option ids of the remaining options, if they could not
+	// be separated.
+	Tie []int `json:"tie,omitempty"`
+}
+
+// RankedRound is one round of an instant runoff count.
+type RankedRound struct {
+	// Votes is the weighted sum of the ballots for each remaining option.
+	Votes map[int]Decimal `json:"votes"`
+
+	// Exhausted is the weighted sum of the ballots that do not rank any
+	// remaining option.
+	Exhausted Decimal `json:"exhausted"`
+
+	// Eliminated are the options, that are eliminated after this round. It
+	// contains at most one option.
+	Eliminated []int `json:"eliminated,omitempty"`
+
+	// Tied are the options, that had the fewest votes in this round, if there
+	// were more then one. The eliminated option was chosen by the tie-break.
+	Tied []int `json:"tied,omitempty"`
+}
+
+// rankedBallot is a valid ranking with its vote weight.
+type rankedBallot struct {
+	ranking []int
+	weight  Decimal
+}
+
+// instantRunoff counts ranked ballots.
+//
+// In each round, each ballot counts for its most preferred remaining option.
+// If an option has more then half of the not exhausted votes, it wins. If not,
+// the option with the fewest votes is eliminated. Only one option is
+// eliminated per round. If many options have the fewest votes, the tie is
+// broken by the earlier rounds, starting with the previous one: The option with
+// the fewest votes in the latest round, where the tied options differ, is
+// eliminated. If they never differ, the option with the highest id is
+// eliminated. If all remaining options have the same votes, the count ends with
+// a tie.
+func instantRunoff(options []int, ballots []rankedBallot) RankedResult {
+	remaining := make(map[int]bool, len(options))
+	for _, optionID := range options {
+		remaining[optionID] = true
+	}
+
+	var result RankedResult
+	for len(remaining) > 0 {
+		round := RankedRound{Votes: make(map[int]Decimal, len(remaining))}
+		for optionID := range remaining {
+			round.Votes[optionID] = 0
+		}
+
+		var total Decimal
+		for _, b := range ballots {
+			idx := slices.IndexFunc(b.ranking, func(optionID int) bool { return remaining[optionID] })
+			if idx == -1 {
+				round.Exhausted += b.weight
+				continue
+			}
+
+			round.Votes[b.ranking[idx]] += b.weight
+			total += b.weight
+		}
+
+		if total == 0 {
+			// No ballot ranks any remaining option.
+			result.Rounds = append(result.Rounds, round)
+			return result
+		}
+
+		for optionID, votes := range round.Votes {
+			if votes > total-votes {
+				result.Winner = optionID
+				result.Rounds = append(result.Rounds, round)
+				return result
+			}
+		}
+
+		lowest := lowestOptions(round.Votes)
+		if len(lowest) == len(remaining) {
+			result.Tie = lowest
+			result.Rounds = append(result.Rounds, round)
+			return result
+		}
+
+		eliminated := lowest[0]
+		if len(lowest) > 1 {
+			round.Tied = lowest
+			eliminated = breakTie(lowest, result.Rounds)
+		}
+
+		delete(remaining, eliminated)
+		round.Eliminated = []int{eliminated}
+		result.Rounds = append(result.Rounds, round)
+	}
+
+	return result
+}
+
+// lowestOptions returns the sorted option ids with the fewest votes.
+func lowestOptions(votes map[int]Decimal) []int {
+	var lowest []int
+	var fewest Decimal
+	for optionID, v := range votes {
+		switch {
+		case lowest == nil || v < fewest:
+			fewest = v
+			lowest = []int{optionID}
+		case v == fewest:
+			lowest = append(lowest, optionID)
+		}
+	}
+	slices.Sort(lowest)
+	return lowest
+}
+
+// breakTie returns the option, that is eliminated from the tied options.
+//
+// The earlier rounds are checked from the latest to the first. In each round,
+// only the tied options with the fewest votes stay tied. If only one option is
+// left, it is eliminated. Otherwise the option with the highest id is
+// eliminated.
+func breakTie(tied []int, rounds []RankedRound) int {
+	for i := len(rounds) - 1; i >= 0 && len(tied) > 1; i-- {
+		votes := make(map[int]Decimal, len(tied))
+		for _, optionID := range tied {
+			votes[optionID] = rounds[i].Votes[optionID]
+		}
+		tied = lowestOptions(votes)
+	}
+	return tied[len(tied)-1]
+}
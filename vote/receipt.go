@@ -0,0 +1,42 @@
+package vote
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// receiptSize is the number of random bytes of a receipt.
+const receiptSize = 16
+
+// newReceipt creates a random receipt token.
+func newReceipt() (string, error) {
+	b := make([]byte, receiptSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// receipts returns the sorted receipts of the vote objects.
+//
+// Objects without a receipt, for example from votes of an older version of the
+// service, are skipped.
+func receipts(objects [][]byte) []string {
+	out := []string{}
+	for _, object := range objects {
+		var data struct {
+			Receipt string `json:"receipt"`
+		}
+		if err := json.Unmarshal(object, &data); err != nil || data.Receipt == "" {
+			continue
+		}
+
+		out = append(out, data.Receipt)
+	}
+
+	sort.Strings(out)
+	return out
+}
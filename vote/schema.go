@@ -0,0 +1,241 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
+)
+
+// Schema returns a JSON Schema for the body of a vote request of a poll.
+//
+// If the poll is started, the schema is created from the config from the time
+// the poll was started.
+func (v *Vote) Schema(ctx context.Context, pollID int) (map[string]any, error) {
+	ds := dsfetch.New(v.flow)
+	poll, err := loadPoll(ctx, ds, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("loading poll: %w", err)
+	}
+
+	frozen, err := v.frozenConfig(ctx, poll)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if !errors.As(err, &errNotExist) {
+			return nil, fmt.Errorf("loading poll config from backend: %w", err)
+		}
+		// The poll is not started. Use the config from the datastore.
+		frozen = poll
+	}
+
+	return ballotSchema(frozen), nil
+}
+
+// ballotSchema creates a JSON Schema for a ballot of the poll.
+//
+// Some rules, like the sum of the amounts, can not be expressed as JSON
+// Schema. They are only described in the description.
+func ballotSchema(poll pollConfig) map[string]any {
+	var valueSchemas []any
+	if globals := globalSchema(poll); globals != nil {
+		valueSchemas = append(valueSchemas, globals)
+	}
+
+	if options := optionSchema(poll); options != nil {
+		valueSchemas = append(valueSchemas, options)
+	}
+
+	var value map[string]any
+	switch len(valueSchemas) {
+	case 0:
+		// Nothing can be voted.
+		value = map[string]any{"not": map[string]any{}}
+	case 1:
+		value = valueSchemas[0].(map[string]any)
+	default:
+		value = map[string]any{"oneOf": valueSchemas}
+	}
+
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                fmt.Sprintf("Ballot for poll %d", poll.id),
+		"type":                 "object",
+		"required":             []string{"value"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"user_id": map[string]any{
+				"description": "The user to vote for. Only needed for vote delegation.",
+				"type":        "integer",
+			},
+			"value": value,
+		},
+	}
+
+	if poll.homomorphic {
+		schema["properties"].(map[string]any)["value"] = homomorphicSchema(poll)
+		return schema
+	}
+
+	if poll.ptype == encryptedPollType {
+		// The value can not be validated before it is decrypted. The schema of
+		// the decrypted value is added as definition.
+		schema["properties"].(map[string]any)["value"] = map[string]any{
+			"description":     "The value encrypted with the encryption key of the poll. The decrypted value has to be valid for #/$defs/decrypted_value.",
+			"type":            "string",
+			"contentEncoding": "base64",
+		}
+		schema["$defs"] = map[string]any{"decrypted_value": value}
+	}
+
+	return schema
+}
+
+// globalSchema returns the schema for the enabled global answers or nil, if
+// no global answer is enabled.
+func globalSchema(poll pollConfig) map[string]any {
+	var enabled []string
+	allowed := poll.allowedGlobal()
+	for _, answer := range []string{"Y", "N", "A"} {
+		if allowed[answer] {
+			enabled = append(enabled, answer)
+		}
+	}
+
+	if len(enabled) == 0 {
+		return nil
+	}
+
+	return map[string]any{
+		"description": "Global answer",
+		"enum":        enabled,
+	}
+}
+
+// optionSchema returns the schema for the answers on the options or nil, if
+// the poll has no options or the poll method is unknown.
+func optionSchema(poll pollConfig) map[string]any {
+	if len(poll.options) == 0 {
+		return nil
+	}
+
+	poll = poll.withDefaults()
+
+	optionProperties := func(schema map[string]any) map[string]any {
+		properties := make(map[string]any, len(poll.options))
+		for _, optionID := range poll.options {
+			properties[strconv.Itoa(optionID)] = schema
+		}
+		return properties
+	}
+
+	switch poll.method {
+	case "Y", "N":
+		return map[string]any{
+			"description": fmt.Sprintf("Amount for each option. The sum of all amounts has to be between %d and %d.", poll.minAmount, poll.maxAmount),
+			"type":        "object",
+			"properties": optionProperties(map[string]any{
+				"type":    "integer",
+				"minimum": 0,
+				"maximum": poll.maxVotesPerOption,
+			}),
+			"additionalProperties": false,
+		}
+
+	case "YN", "YNA":
+		answers := []string{"Y", "N"}
+		if poll.method == "YNA" {
+			answers = append(answers, "A")
+		}
+
+		return map[string]any{
+			"description":          "Answer for each option",
+			"type":                 "object",
+			"properties":           optionProperties(map[string]any{"enum": answers}),
+			"additionalProperties": false,
+		}
+
+	case "score":
+		return map[string]any{
+			"description": "Score for each option",
+			"type":        "object",
+			"properties": optionProperties(map[string]any{
+				"type":    "integer",
+				"minimum": poll.minScore,
+				"maximum": poll.maxScore,
+			}),
+			"additionalProperties": false,
+			"minProperties":        1,
+		}
+
+	case "ranked", "schulze":
+		return map[string]any{
+			"description": "Option ids ordered by preference. The first option is the most preferred.",
+			"type":        "array",
+			"items": map[string]any{
+				"enum": poll.options,
+			},
+			"uniqueItems": true,
+			"minItems":    poll.minAmount,
+			"maxItems":    poll.maxAmount,
+		}
+
+	default:
+		return nil
+	}
+}
+
+// homomorphicSchema returns the schema for the value of a homomorphic poll.
+//
+// The proofs can not be expressed as JSON Schema.
+func homomorphicSchema(poll pollConfig) map[string]any {
+	answers := len(homomorphicAnswers(poll.method))
+	bytes := map[string]any{"type": "string", "contentEncoding": "base64"}
+
+	properties := make(map[string]any, len(poll.options))
+	required := make([]string, len(poll.options))
+	for i, optionID := range poll.options {
+		required[i] = strconv.Itoa(optionID)
+		properties[required[i]] = map[string]any{
+			"type":     "object",
+			"required": []string{"ciphertexts", "bit_proofs", "sum_proof"},
+			"properties": map[string]any{
+				"ciphertexts": map[string]any{
+					"type":     "array",
+					"minItems": answers,
+					"maxItems": answers,
+					"items": map[string]any{
+						"type":       "object",
+						"required":   []string{"a", "b"},
+						"properties": map[string]any{"a": bytes, "b": bytes},
+					},
+				},
+				"bit_proofs": map[string]any{
+					"type":     "array",
+					"minItems": answers,
+					"maxItems": answers,
+					"items": map[string]any{
+						"type":       "object",
+						"required":   []string{"c0", "c1", "s0", "s1"},
+						"properties": map[string]any{"c0": bytes, "c1": bytes, "s0": bytes, "s1": bytes},
+					},
+				},
+				"sum_proof": map[string]any{
+					"type":       "object",
+					"required":   []string{"c", "s"},
+					"properties": map[string]any{"c": bytes, "s": bytes},
+				},
+			},
+		}
+	}
+
+	return map[string]any{
+		"description":          fmt.Sprintf("Encrypted answer for each option. Each option has one ElGamal ciphertext for each of the answers %s with proofs, that exactly one of them encrypts 1.", strings.Join(homomorphicAnswers(poll.method), ", ")),
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,100 @@
+package vote
+
+import (
+	"slices"
+)
+
+// SchulzeResult is the result of a pairwise comparison of ranked ballots.
+type SchulzeResult struct {
+	// Pairwise is the preference matrix. Pairwise[a][b] is the weighted sum of
+	// the ballots that prefer option a over option b.
+	Pairwise map[int]map[int]Decimal `json:"pairwise"`
+
+	// Paths contains the strength of the strongest path from each option to
+	// each other option.
+	Paths map[int]map[int]Decimal `json:"paths"`
+
+	// Winners are the option ids of all options that are not beaten by any
+	// other option. If there is more then one winner, it is a tie.
+	Winners []int `json:"winners"`
+}
+
+// schulze evaluates ranked ballots with the Schulze method.
+//
+// A ranked option is preferred over all options that are ranked lower and
+// over all options that are not ranked. Options that are not ranked are
+// equal.
+func schulze(options []int, ballots []rankedBallot) SchulzeResult {
+	options = slices.Clone(options)
+	slices.Sort(options)
+
+	pairwise := newOptionMatrix(options)
+	for _, b := range ballots {
+		preferred := make(map[int]bool, len(b.ranking))
+		for _, optionID := range b.ranking {
+			preferred[optionID] = true
+			for _, other := range options {
+				if !preferred[other] {
+					pairwise[optionID][other] += b.weight
+				}
+			}
+		}
+	}
+
+	paths := newOptionMatrix(options)
+	for _, a := range options {
+		for _, b := range options {
+			if a != b && pairwise[a][b] > pairwise[b][a] {
+				paths[a][b] = pairwise[a][b]
+			}
+		}
+	}
+
+	for _, i := range options {
+		for _, j := range options {
+			if i == j {
+				continue
+			}
+
+			for _, k := range options {
+				if i == k || j == k {
+					continue
+				}
+
+				paths[j][k] = max(paths[j][k], min(paths[j][i], paths[i][k]))
+			}
+		}
+	}
+
+	winners := []int{}
+	for _, a := range options {
+		beaten := slices.ContainsFunc(options, func(b int) bool {
+			return paths[b][a] > paths[a][b]
+		})
+
+		if !beaten {
+			winners = append(winners, a)
+		}
+	}
+
+	return SchulzeResult{
+		Pairwise: pairwise,
+		Paths:    paths,
+		Winners:  winners,
+	}
+}
+
+// newOptionMatrix creates a matrix with a zero value for each pair of
+// different options.
+func newOptionMatrix(options []int) map[int]map[int]Decimal {
+	matrix := make(map[int]map[int]Decimal, len(options))
+	for _, a := range options {
+		matrix[a] = make(map[int]Decimal, len(options)-1)
+		for _, b := range options {
+			if a != b {
+				matrix[a][b] = 0
+			}
+		}
+	}
+	return matrix
+}
@@ -0,0 +1,58 @@
+package vote
+
+// ScoreRange sets the lowest and the highest score, that can be given to an
+// option of a poll with the method score.
+func ScoreRange(minScore, maxScore int) StartOption {
+	return func(p *pollConfig) {
+		p.minScore = minScore
+		p.maxScore = maxScore
+	}
+}
+
+// validateScoreRange checks, that a poll with the method score has a valid
+// score range and that other polls have none.
+func (p pollConfig) validateScoreRange() error {
+	if p.method != "score" {
+		if p.minScore != 0 || p.maxScore != 0 {
+			return MessageError(ErrInvalid, "A score range can only be used for polls with the method score")
+		}
+		return nil
+	}
+
+	if p.minScore >= p.maxScore {
+		return MessageError(ErrInvalid, "Polls with the method score need a score range with min_score < max_score")
+	}
+	return nil
+}
+
+// ScoreResult is the result of one option of a score poll.
+type ScoreResult struct {
+	// Ballots is the number of ballots that scored the option.
+	Ballots int `json:"ballots"`
+
+	// Weight is the sum of the weights of the ballots that scored the option.
+	Weight Decimal `json:"weight"`
+
+	// Sum is the sum of the scores, each multiplied with the weight of its
+	// ballot.
+	Sum Decimal `json:"sum"`
+
+	// Average is the weighted average score of the option.
+	Average Decimal `json:"average"`
+}
+
+// addScore adds the scores of a valid ballot with its weight.
+func (t *Tally) addScore(scores map[int]int, weight Decimal) {
+	if t.Score == nil {
+		t.Score = make(map[int]ScoreResult)
+	}
+
+	for optionID, score := range scores {
+		result := t.Score[optionID]
+		result.Ballots++
+		result.Weight = t.sum(result.Weight, weight)
+		result.Sum = t.sum(result.Sum, t.product(weight, score))
+		result.Average = t.quotient(result.Sum, result.Weight)
+		t.Score[optionID] = result
+	}
+}
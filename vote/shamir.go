@@ -0,0 +1,120 @@
+package vote
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// shamirSplit splits the secret into count shares. Each threshold shares can
+// recreate the secret. Less shares do not tell anything about the secret.
+//
+// Each byte of the secret is split on its own with a random polynomial over
+// GF(2^8). Each share is the x coordinate followed by the y coordinate for each
+// byte of the secret.
+func shamirSplit(secret []byte, threshold, count int) ([][]byte, error) {
+	if threshold < 1 || threshold > count {
+		return nil, fmt.Errorf("threshold has to be between 1 and %d, got %d", count, threshold)
+	}
+
+	if count > 255 {
+		return nil, fmt.Errorf("can not create more than 255 shares, got %d", count)
+	}
+
+	shares := make([][]byte, count)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for i, secretByte := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("reading random coefficients: %w", err)
+		}
+		coefficients[0] = secretByte
+
+		for _, share := range shares {
+			share[i+1] = gfPolynomial(coefficients, share[0])
+		}
+	}
+
+	return shares, nil
+}
+
+// shamirCombine recreates the secret from shares created with shamirSplit.
+//
+// It uses all given shares. If there are less shares than the threshold, the
+// result is a random value.
+func shamirCombine(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no shares")
+	}
+
+	length := len(shares[0])
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != length || length < 2 {
+			return nil, fmt.Errorf("shares have different or invalid lengths")
+		}
+
+		if share[0] == 0 || seen[share[0]] {
+			return nil, fmt.Errorf("invalid or duplicate share %d", share[0])
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, length-1)
+	for i, share := range shares {
+		// Lagrange basis polynomial of the share at x = 0.
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfDiv(other[0], other[0]^share[0]))
+		}
+
+		for k := range secret {
+			secret[k] ^= gfMul(share[k+1], basis)
+		}
+	}
+
+	return secret, nil
+}
+
+// gfPolynomial evaluates the polynomial with the given coefficients at x.
+func gfPolynomial(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// gfMul multiplies two elements of GF(2^8) with the AES polynomial.
+func gfMul(a, b byte) byte {
+	var product byte
+	for b > 0 {
+		if b&1 == 1 {
+			product ^= a
+		}
+
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return product
+}
+
+// gfDiv divides a by b in GF(2^8). b must not be 0.
+func gfDiv(a, b byte) byte {
+	// The inverse of b is b^254.
+	inverse := byte(1)
+	for i := 0; i < 254; i++ {
+		inverse = gfMul(inverse, b)
+	}
+	return gfMul(a, inverse)
+}
@@ -0,0 +1,136 @@
+package vote
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
+)
+
+var envSigningKeyFile = environment.NewVariable("VOTE_SIGNING_KEY_FILE", "/run/secrets/vote_signing_key", "File with the base64 encoded ed25519 seed to sign the results of polls. If the file does not exist, the results are not signed.")
+
+// SigningKey reads the key to sign the results of polls from the environment.
+//
+// Returns nil, if no key is configured.
+func SigningKey(lookup environment.Environmenter) (ed25519.PrivateKey, error) {
+	secret, err := environment.ReadSecretWithDefault(lookup, envSigningKeyFile, "")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+
+	if secret == "" {
+		return nil, nil
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil {
+		return nil, fmt.Errorf("decoding signing key: %w", err)
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key has %d bytes, expected %d", len(seed), ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Signature is the signature of the result of a poll.
+type Signature struct {
+	PollID int `json:"poll_id"`
+
+	// SignedAt is the unix time, when the result was signed.
+	SignedAt int64 `json:"signed_at"`
+
+	// Signature is the base64 encoded ed25519 signature.
+	Signature string `json:"signature"`
+}
+
+// SignResult signs the json encoded result of a poll.
+//
+// result is the encoded stop response without the field signature. All other
+// fields are signed.
+func SignResult(key ed25519.PrivateKey, pollID int, result []byte, now time.Time) (Signature, error) {
+	signedAt := now.Unix()
+	payload, err := signedPayload(pollID, signedAt, result)
+	if err != nil {
+		return Signature{}, fmt.Errorf("creating signed payload: %w", err)
+	}
+
+	return Signature{
+		PollID:    pollID,
+		SignedAt:  signedAt,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}, nil
+}
+
+// Verify checks, that the signature belongs to the json encoded result of a
+// poll. The field signature of the result is ignored.
+func (s Signature) Verify(publicKey ed25519.PublicKey, result []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	payload, err := signedPayload(s.PollID, s.SignedAt, result)
+	if err != nil {
+		return fmt.Errorf("creating signed payload: %w", err)
+	}
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// signedPayload creates the bytes that are signed.
+//
+// It is the compact json of the poll id, the time of the signature and the
+// canonical encoding of the result.
+func signedPayload(pollID int, signedAt int64, result []byte) ([]byte, error) {
+	canonical, err := canonicalResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("encoding result: %w", err)
+	}
+
+	return json.Marshal(struct {
+		PollID   int             `json:"poll_id"`
+		SignedAt int64           `json:"signed_at"`
+		Result   json.RawMessage `json:"result"`
+	}{
+		pollID,
+		signedAt,
+		canonical,
+	})
+}
+
+// canonicalResult returns the result without the field signature as compact
+// json with sorted keys. The numbers are kept as they are written.
+//
+// This makes the signature independent from the formatting of the result and
+// from the order of its keys.
+func canonicalResult(result []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("decoding result: %w", err)
+	}
+
+	if fields == nil {
+		return nil, fmt.Errorf("result has to be a json object")
+	}
+
+	delete(fields, "signature")
+	return json.Marshal(fields)
+}
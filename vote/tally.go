@@ -0,0 +1,206 @@
+package vote
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tally is the aggregated result of all vote objects of a poll.
+//
+// All weights are summed with exact decimal arithmetic.
+type Tally struct {
+	// Ballots is the number of vote objects.
+	Ballots int `json:"ballots"`
+
+	// Invalid is the number of vote objects that could not be counted.
+	Invalid int `json:"invalid"`
+
+	// VotesCast is the sum of the weights of all vote objects.
+	VotesCast Decimal `json:"votescast"`
+
+	// VotesValid is the sum of the weights of all counted vote objects.
+	VotesValid Decimal `json:"votesvalid"`
+
+	// Global contains the weighted sum of the global answers Y, N and A.
+	Global map[string]Decimal `json:"global,omitempty"`
+
+	// Options contains for each option id the weighted sum of each answer.
+	// All options of the poll are listed, also if nobody voted for them.
+	//
+	// For the methods Y and N, the answer is the method and the value is the
+	// weighted sum of the amounts.
+	Options map[int]map[string]Decimal `json:"options,omitempty"`
+
+	// Score contains for each option id the result of a score poll. It is
+	// only set for the method score.
+	Score map[int]ScoreResult `json:"score,omitempty"`
+
+	// Ranked is the result of the instant runoff count. It is only set for
+	// the method ranked.
+	Ranked *RankedResult `json:"ranked,omitempty"`
+
+	// Schulze is the result of the pairwise comparison. It is only set for
+	// the method schulze.
+	Schulze *SchulzeResult `json:"schulze,omitempty"`
+
+	// err is the first overflow, that happened while the votes were summed.
+	err error
+}
+
+// tally aggregates the vote objects of a poll.
+//
+// Vote objects that can not be decoded or that are not valid for the poll
+// are counted as invalid. Also vote objects with a negative weight are
+// invalid. So the sums of the ranked and schulze methods can not be bigger
+// then VotesValid.
+//
+// An error is returned, if a sum does not fit into a Decimal.
+func tally(poll pollConfig, objects [][]byte) (Tally, error) {
+	var t Tally
+	var rankings []rankedBallot
+
+	switch poll.method {
+	case "Y", "N", "YN", "YNA":
+		// List all options, also if nobody has voted for them.
+		for _, optionID := range poll.options {
+			t.addOption(optionID, "", 0)
+		}
+	}
+
+	for _, object := range objects {
+		t.Ballots++
+
+		var data struct {
+			Value  ballotValue `json:"value"`
+			Weight string      `json:"weight"`
+		}
+		if err := json.Unmarshal(object, &data); err != nil {
+			t.Invalid++
+			continue
+		}
+
+		weight, err := ParseDecimal(data.Weight)
+		if err != nil || weight < 0 {
+			t.Invalid++
+			continue
+		}
+		t.VotesCast = t.sum(t.VotesCast, weight)
+
+		if validation := validate(poll, data.Value); validation != "" {
+			t.Invalid++
+			continue
+		}
+		t.VotesValid = t.sum(t.VotesValid, weight)
+
+		if poll.method == "score" && data.Value.Type() == ballotValueOptionAmount {
+			t.addScore(data.Value.optionAmount, weight)
+			continue
+		}
+
+		if data.Value.Type() == ballotValueOptionRanking {
+			rankings = append(rankings, rankedBallot{ranking: data.Value.optionRanking, weight: weight})
+			continue
+		}
+
+		t.add(poll.method, data.Value, weight)
+	}
+
+	switch poll.method {
+	case "ranked":
+		result := instantRunoff(poll.options, rankings)
+		t.Ranked = &result
+
+	case "schulze":
+		result := schulze(poll.options, rankings)
+		t.Schulze = &result
+	}
+
+	if t.err != nil {
+		return Tally{}, fmt.Errorf("summing votes: %w", t.err)
+	}
+
+	return t, nil
+}
+
+// addInvalid counts the invalid ballots of an encrypted poll.
+func (t *Tally) addInvalid(ballots []InvalidBallot) {
+	for _, ballot := range ballots {
+		t.Ballots++
+		t.Invalid++
+
+		var data struct {
+			Weight string `json:"weight"`
+		}
+		if err := json.Unmarshal(ballot.Object, &data); err != nil {
+			continue
+		}
+
+		if weight, err := ParseDecimal(data.Weight); err == nil && weight >= 0 {
+			t.VotesCast = t.sum(t.VotesCast, weight)
+		}
+	}
+}
+
+// add adds a valid ballot value with its weight.
+func (t *Tally) add(method string, v ballotValue, weight Decimal) {
+	switch v.Type() {
+	case ballotValueString:
+		if t.Global == nil {
+			t.Global = make(map[string]Decimal)
+		}
+		t.Global[v.str] = t.sum(t.Global[v.str], weight)
+
+	case ballotValueOptionAmount:
+		for optionID, amount := range v.optionAmount {
+			t.addOption(optionID, method, t.product(weight, amount))
+		}
+
+	case ballotValueOptionString:
+		for optionID, answer := range v.optionYNA {
+			t.addOption(optionID, answer, weight)
+		}
+	}
+}
+
+func (t *Tally) addOption(optionID int, answer string, value Decimal) {
+	if t.Options == nil {
+		t.Options = make(map[int]map[string]Decimal)
+	}
+
+	if t.Options[optionID] == nil {
+		t.Options[optionID] = make(map[string]Decimal)
+	}
+
+	if answer != "" {
+		t.Options[optionID][answer] = t.sum(t.Options[optionID][answer], value)
+	}
+}
+
+// sum returns a + b. On an overflow, the error is saved in the tally.
+func (t *Tally) sum(a, b Decimal) Decimal {
+	result, err := a.checkedAdd(b)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	return result
+}
+
+// quotient returns d / divisor. On an overflow, the error is saved in the
+// tally.
+func (t *Tally) quotient(d, divisor Decimal) Decimal {
+	result, err := d.Div(divisor)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	return result
+}
+
+// product returns d * factor. On an overflow, the error is saved in the
+// tally.
+func (t *Tally) product(d Decimal, factor int) Decimal {
+	result, err := d.checkedMul(factor)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
+)
+
+// entitledUser is a user that is allowed to vote with its vote weight.
+type entitledUser struct {
+	UserID int    `json:"user_id"`
+	Weight string `json:"weight"`
+}
+
+// entitledUsers returns all users from the entitled groups with their vote
+// weight sorted by user id.
+//
+// It has to be called after preload. In this case, all values are already in
+// the cache.
+func (p pollConfig) entitledUsers(ctx context.Context, ds *dsfetch.Fetch) ([]entitledUser, error) {
+	seen := make(map[int]bool)
+	users := []entitledUser{}
+	for _, groupID := range p.groups {
+		meetingUserIDs, err := ds.Group_MeetingUserIDs(groupID).Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting meeting users of group %d: %w", groupID, err)
+		}
+
+		for _, muID := range meetingUserIDs {
+			userID, err := ds.MeetingUser_UserID(muID).Value(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("getting user of meeting user %d: %w", muID, err)
+			}
+
+			if seen[userID] {
+				continue
+			}
+			seen[userID] = true
+
+			weight, err := getVoteWeight(ctx, ds, p.meetingID, muID, userID)
+			if err != nil {
+				return nil, fmt.Errorf("getting vote weight of user %d: %w", userID, err)
+			}
+
+			users = append(users, entitledUser{UserID: userID, Weight: weight})
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users, nil
+}
+
+// Turnout contains statistics about the entitled users of a poll.
+type Turnout struct {
+	// Entitled is the number of entitled users.
+	Entitled int `json:"entitled"`
+
+	// EntitledWeight is the sum of the vote weights of all entitled users.
+	EntitledWeight Decimal `json:"entitled_weight"`
+
+	// Voted is the number of entitled users that have voted.
+	Voted int `json:"voted"`
+
+	// VotedWeight is the sum of the vote weights of all entitled users that
+	// have voted.
+	VotedWeight Decimal `json:"voted_weight"`
+
+	// NotVoted are the ids of the entitled users that have not voted. It is
+	// only set for named polls.
+	NotVoted []int `json:"not_voted,omitempty"`
+}
+
+// turnout calculates the turnout from the entitled users that where saved,
+// when the poll was started.
+//
+// Returns nil, if there are no saved entitled users.
+func (p pollConfig) turnout(votedUserIDs []int) (*Turnout, error) {
+	if len(p.entitled) == 0 {
+		return nil, nil
+	}
+
+	var entitled []entitledUser
+	if err := json.Unmarshal(p.entitled, &entitled); err != nil {
+		return nil, fmt.Errorf("decoding entitled users: %w", err)
+	}
+
+	voted := make(map[int]bool, len(votedUserIDs))
+	for _, userID := range votedUserIDs {
+		voted[userID] = true
+	}
+
+	var t Turnout
+	for _, user := range entitled {
+		weight, err := ParseDecimal(user.Weight)
+		if err != nil {
+			return nil, fmt.Errorf("parsing vote weight of user %d: %w", user.UserID, err)
+		}
+
+		t.Entitled++
+		t.EntitledWeight, err = t.EntitledWeight.checkedAdd(weight)
+		if err != nil {
+			return nil, fmt.Errorf("summing entitled weight: %w", err)
+		}
+
+		if !voted[user.UserID] {
+			if p.ptype == "named" {
+				t.NotVoted = append(t.NotVoted, user.UserID)
+			}
+			continue
+		}
+
+		t.Voted++
+		t.VotedWeight, err = t.VotedWeight.checkedAdd(weight)
+		if err != nil {
+			return nil, fmt.Errorf("summing voted weight: %w", err)
+		}
+	}
+
+	return &t, nil
+}
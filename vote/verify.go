@@ -0,0 +1,86 @@
+package vote
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// VerifyResult checks an exported result of the stop request against a
+// published merkle root and the public key of the service.
+//
+// If root is not empty, it checks, that the vote objects in the result have the
+// given root and that all inclusion proofs in the result are valid.
+//
+// If publicKey is not nil, it checks the signature of the result.
+func VerifyResult(r io.Reader, root string, publicKey ed25519.PublicKey) error {
+	if root == "" && publicKey == nil {
+		return fmt.Errorf("a merkle root or a public key is required")
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading result: %w", err)
+	}
+
+	var result struct {
+		Votes     []json.RawMessage `json:"votes"`
+		Merkle    *Merkle           `json:"merkle"`
+		Signature *Signature        `json:"signature"`
+	}
+
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("decoding result: %w", err)
+	}
+
+	if publicKey != nil {
+		if result.Signature == nil {
+			return fmt.Errorf("the result is not signed")
+		}
+
+		if err := result.Signature.Verify(publicKey, raw); err != nil {
+			return fmt.Errorf("checking signature: %w", err)
+		}
+	}
+
+	// The tree is created from the compact json of the vote objects. Compact
+	// the objects in case the file was reformatted.
+	objects := make([][]byte, len(result.Votes))
+	for i, vote := range result.Votes {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, vote); err != nil {
+			return fmt.Errorf("compacting vote object %d: %w", i, err)
+		}
+		objects[i] = buf.Bytes()
+	}
+
+	if root == "" {
+		return nil
+	}
+
+	if got := MerkleRoot(objects); got != root {
+		return fmt.Errorf("the vote objects have the root %s, expected %s", got, root)
+	}
+
+	if result.Merkle == nil {
+		return nil
+	}
+
+	if result.Merkle.Root != root {
+		return fmt.Errorf("the result contains the root %s, expected %s", result.Merkle.Root, root)
+	}
+
+	if len(result.Merkle.Proofs) != len(objects) {
+		return fmt.Errorf("the result contains %d proofs for %d vote objects", len(result.Merkle.Proofs), len(objects))
+	}
+
+	for i, proof := range result.Merkle.Proofs {
+		if err := proof.Verify(objects[i], root); err != nil {
+			return fmt.Errorf("proof of vote object %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
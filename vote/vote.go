@@ -1,12 +1,15 @@
 package vote
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsfetch"
@@ -20,20 +23,74 @@ import (
 //
 // Vote has to be initializes with vote.New().
 type Vote struct {
-	fastBackend Backend
-	longBackend Backend
-	flow        flow.Flow
+	backends map[string]Backend // backends holds the backends by the name, that polls use in their backend field.
+	flow     flow.Flow
 
 	votedMu sync.Mutex
 	voted   map[int][]int // voted holds for all running polls, which user ids have already voted.
+
+	sharesMu sync.Mutex
+	shares   map[int][]trusteeShare // shares holds the trustee shares of encrypted polls.
+
+	aggregates  map[int]homomorphicAggregate // aggregates holds the aggregated ballots of stopped homomorphic polls.
+	decryptions map[int][]partialDecryption  // decryptions holds the partial decryptions of the trustees for the aggregates.
+
+	// singleInstance is true, if no other instance of the service uses the
+	// same backends. Only then, encrypted polls can be used, because the
+	// trustee shares are only saved in memory.
+	singleInstance bool
+
+	// migrateMu is locked while a poll is migrated to another backend.
+	migrateMu sync.RWMutex
+
+	migratedMu sync.Mutex
+	migrated   map[int]string // migrated holds the backend names of polls, that were migrated to another backend.
+	failedOver map[int]bool   // failedOver holds the polls, that were started in the long backend, while the fast backend could not tell, if it knows them.
+
+	configMu sync.Mutex
+	configs  map[int]cachedConfig // configs holds the frozen configs of running polls, so they are not fetched from the backend on every vote.
+
+	// failover is true, if fast polls use the long backend, while the fast
+	// backend is not reachable. degraded is true in this case.
+	failover bool
+	degraded atomic.Bool
+
+	anonymityThreshold int
+}
+
+// Option is an optional argument for New.
+type Option func(*Vote)
+
+// WithBackend adds a backend with a name. Polls with this name in their
+// backend field use this backend.
+//
+// The names fast and long are the backends given to New.
+func WithBackend(name string, backend Backend) Option {
+	return func(v *Vote) {
+		v.backends[name] = backend
+	}
 }
 
 // New creates an initializes vote service.
-func New(ctx context.Context, fast, long Backend, flow flow.Flow, singleInstance bool) (*Vote, func(context.Context, func(error)), error) {
+func New(ctx context.Context, fast, long Backend, flow flow.Flow, singleInstance bool, options ...Option) (*Vote, func(context.Context, func(error)), error) {
 	v := &Vote{
-		fastBackend: fast,
-		longBackend: long,
-		flow:        flow,
+		backends:       map[string]Backend{"fast": fast, "long": long},
+		flow:           flow,
+		shares:         make(map[int][]trusteeShare),
+		aggregates:     make(map[int]homomorphicAggregate),
+		decryptions:    make(map[int][]partialDecryption),
+		singleInstance: singleInstance,
+		migrated:       make(map[int]string),
+		failedOver:     make(map[int]bool),
+		configs:        make(map[int]cachedConfig),
+	}
+
+	for _, o := range options {
+		o(v)
+	}
+
+	if v.failover {
+		v.pingFastBackend(ctx)
 	}
 
 	if err := v.loadVoted(ctx); err != nil {
@@ -43,6 +100,10 @@ func New(ctx context.Context, fast, long Backend, flow flow.Flow, singleInstance
 	bg := func(ctx context.Context, errorHandler func(error)) {
 		go v.flow.Update(ctx, nil)
 
+		if v.failover {
+			go v.watchFastBackend(ctx, errorHandler)
+		}
+
 		if singleInstance {
 			return
 		}
@@ -62,20 +123,58 @@ func New(ctx context.Context, fast, long Backend, flow flow.Flow, singleInstance
 
 // backend returns the poll backend for a pollConfig object.
 func (v *Vote) backend(p pollConfig) Backend {
-	backend := v.longBackend
-	if p.backend == "fast" {
-		backend = v.fastBackend
-	}
+	backend := v.backends[v.backendName(p)]
 	log.Debug("Used backend: %v", backend)
 	return backend
 }
 
+// backendName returns the name of the backend of a poll.
+//
+// Polls with an unknown backend use the long backend. Polls that were
+// migrated use the new backend.
+func (v *Vote) backendName(p pollConfig) string {
+	v.migratedMu.Lock()
+	name, ok := v.migrated[p.id]
+	v.migratedMu.Unlock()
+	if ok {
+		return name
+	}
+
+	if _, ok := v.backends[p.backend]; ok {
+		return p.backend
+	}
+	return "long"
+}
+
+// backendNames returns the names of all backends in a stable order.
+func (v *Vote) backendNames() []string {
+	names := make([]string, 0, len(v.backends))
+	for name := range v.backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// StartOption is an option for Vote.Start.
+type StartOption func(*pollConfig)
+
+// Revotable lets the users change their vote until the poll is stopped.
+func Revotable() StartOption {
+	return func(p *pollConfig) {
+		p.revotable = true
+	}
+}
+
 // Start an electronic vote.
 //
 // This function is idempotence. If you call it with the same input, you will
 // get the same output. This means, that when a poll is stopped, Start() will
 // not throw an error.
-func (v *Vote) Start(ctx context.Context, pollID int) error {
+//
+// The options are saved with the poll config. They are ignored, if the poll is
+// already started.
+func (v *Vote) Start(ctx context.Context, pollID int, options ...StartOption) error {
 	recorder := dsrecorder.New(v.flow)
 	ds := dsfetch.New(recorder)
 
@@ -93,8 +192,48 @@ func (v *Vote) Start(ctx context.Context, pollID int) error {
 	}
 	log.Debug("Preload cache. Received keys: %v", recorder.Keys())
 
+	entitled, err := poll.entitledUsers(ctx, ds)
+	if err != nil {
+		return fmt.Errorf("loading entitled users: %w", err)
+	}
+
+	poll.entitled, err = json.Marshal(entitled)
+	if err != nil {
+		return fmt.Errorf("encoding entitled users: %w", err)
+	}
+
+	for _, option := range options {
+		option(&poll)
+	}
+
+	if err := poll.validateEncryptionKey(); err != nil {
+		return err
+	}
+
+	if err := poll.validateScoreRange(); err != nil {
+		return err
+	}
+
+	if poll.ptype == encryptedPollType && !v.singleInstance {
+		return MessageError(ErrInvalid, "Encrypted polls can only be started, if the service runs as a single instance")
+	}
+
+	config, err := json.Marshal(poll)
+	if err != nil {
+		return fmt.Errorf("encoding poll config: %w", err)
+	}
+
+	started, err := v.startInLong(ctx, poll)
+	if err != nil {
+		return err
+	}
+
+	if started {
+		return nil
+	}
+
 	backend := v.backend(poll)
-	if err := backend.Start(ctx, pollID); err != nil {
+	if err := backend.Start(ctx, pollID, config); err != nil {
 		return fmt.Errorf("starting poll in the backend: %w", err)
 	}
 
@@ -105,6 +244,36 @@ func (v *Vote) Start(ctx context.Context, pollID int) error {
 type StopResult struct {
 	Votes   [][]byte
 	UserIDs []int
+	Tally   Tally
+
+	// Method is the poll method from the config of the started poll.
+	Method string
+
+	// ConfigChanged is true, if the poll config in the datastore is different
+	// from the config when the poll was started.
+	ConfigChanged bool
+
+	// Turnout contains the statistics about the entitled users. It is nil, if
+	// the poll was started without a list of entitled users.
+	Turnout *Turnout
+
+	// Receipts are the sorted receipts of all vote objects. It is only set for
+	// polls that are not named.
+	Receipts []string
+
+	// Merkle is a merkle tree over the vote objects.
+	Merkle Merkle
+
+	// InvalidBallots are the vote objects of an encrypted poll, that could not
+	// be decrypted or that are invalid after the decryption. They are not part
+	// of Votes.
+	InvalidBallots []InvalidBallot
+
+	// BallotsWithheld is true, if the poll is not named and less users have
+	// voted than the anonymity threshold. In this case, Votes, Receipts,
+	// InvalidBallots and the merkle proofs are empty and only the tally and
+	// the merkle root are returned.
+	BallotsWithheld bool
 }
 
 // Stop ends a poll.
@@ -118,7 +287,38 @@ func (v *Vote) Stop(ctx context.Context, pollID int) (StopResult, error) {
 		return StopResult{}, fmt.Errorf("loading poll: %w", err)
 	}
 
+	v.migrateMu.RLock()
+	defer v.migrateMu.RUnlock()
+
+	if err := v.followMigrations(ctx, poll); err != nil {
+		return StopResult{}, fmt.Errorf("looking for migrated poll: %w", err)
+	}
+
 	backend := v.backend(poll)
+	frozen, err := frozenConfig(ctx, backend, poll)
+	if err != nil {
+		var errNotExist interface{ DoesNotExist() }
+		if errors.As(err, &errNotExist) {
+			return StopResult{}, MessageError(ErrNotExists, "Poll %d does not exist in the backend", pollID)
+		}
+
+		return StopResult{}, fmt.Errorf("loading poll config from backend: %w", err)
+	}
+
+	// The trustee shares are checked before the poll is stopped. Otherwise,
+	// missing shares would leave a stopped poll without a result.
+	//
+	// Homomorphic polls are decrypted with partial decryptions of the
+	// aggregate. The aggregate can only be created, after the poll is
+	// stopped.
+	var secret []byte
+	if frozen.ptype == encryptedPollType && !frozen.homomorphic {
+		secret, err = combineTrusteeShares(frozen, v.pollShares(pollID))
+		if err != nil {
+			return StopResult{}, fmt.Errorf("combining trustee shares: %w", err)
+		}
+	}
+
 	ballots, userIDs, err := backend.Stop(ctx, pollID)
 	if err != nil {
 		var errNotExist interface{ DoesNotExist() }
@@ -128,24 +328,122 @@ func (v *Vote) Stop(ctx context.Context, pollID int) (StopResult, error) {
 
 		return StopResult{}, fmt.Errorf("fetching vote objects: %w", err)
 	}
+	v.forgetConfig(pollID)
+
+	turnout, err := frozen.turnout(userIDs)
+	if err != nil {
+		return StopResult{}, fmt.Errorf("calculating turnout: %w", err)
+	}
+
+	var voteReceipts []string
+	if frozen.ptype != "named" {
+		voteReceipts = receipts(ballots)
+	}
+
+	var voteTally Tally
+	var invalid []InvalidBallot
+	switch {
+	case frozen.homomorphic:
+		// Only the totals are decrypted. The encrypted ballots are not
+		// returned, because the trustees could decrypt each of them.
+		var aggregate homomorphicAggregate
+		voteTally, aggregate, err = aggregateBallots(frozen, ballots)
+		if err != nil {
+			return StopResult{}, fmt.Errorf("counting homomorphic votes: %w", err)
+		}
+
+		combined, err := combineDecryptions(frozen, aggregate, v.saveAggregate(aggregate))
+		if err != nil {
+			return StopResult{}, fmt.Errorf("combining partial decryptions: %w", err)
+		}
+
+		voteTally, err = decryptAggregate(frozen, voteTally, aggregate, combined)
+		if err != nil {
+			return StopResult{}, fmt.Errorf("decrypting totals: %w", err)
+		}
+
+	case frozen.ptype == encryptedPollType:
+		ballots, invalid, err = decryptBallots(frozen, secret, ballots)
+		if err != nil {
+			return StopResult{}, fmt.Errorf("decrypting votes: %w", err)
+		}
+
+		voteTally, err = tally(frozen, ballots)
+		if err != nil {
+			return StopResult{}, fmt.Errorf("counting votes: %w", err)
+		}
+
+		voteTally.addInvalid(invalid)
+		if voteTally.err != nil {
+			return StopResult{}, fmt.Errorf("counting invalid votes: %w", voteTally.err)
+		}
+
+	default:
+		voteTally, err = tally(frozen, ballots)
+		if err != nil {
+			return StopResult{}, fmt.Errorf("counting votes: %w", err)
+		}
+	}
 
-	return StopResult{ballots, userIDs}, nil
+	// The root is created before the ballots are withheld, so it can be
+	// compared with the root of a later export of the ballots.
+	merkle := NewMerkle(ballots)
+
+	withheld := v.withholdBallots(frozen, len(userIDs))
+	if withheld {
+		ballots = nil
+		voteReceipts = nil
+		invalid = nil
+		merkle.Proofs = nil
+	}
+
+	// The encrypted ballots of homomorphic polls are never published. The
+	// receipts and the root still let the voters check, that their ballot was
+	// counted.
+	if frozen.homomorphic {
+		ballots = nil
+		merkle.Proofs = nil
+	}
+
+	return StopResult{
+		Votes:           ballots,
+		UserIDs:         userIDs,
+		Tally:           voteTally,
+		Method:          frozen.method,
+		ConfigChanged:   !frozen.equal(poll),
+		Turnout:         turnout,
+		Receipts:        voteReceipts,
+		Merkle:          merkle,
+		InvalidBallots:  invalid,
+		BallotsWithheld: withheld,
+	}, nil
 }
 
 // Clear removes all knowlage of a poll.
 func (v *Vote) Clear(ctx context.Context, pollID int) error {
-	if err := v.fastBackend.Clear(ctx, pollID); err != nil {
-		return fmt.Errorf("clearing fastBackend: %w", err)
-	}
-
-	if err := v.longBackend.Clear(ctx, pollID); err != nil {
-		return fmt.Errorf("clearing longBackend: %w", err)
+	for _, name := range v.backendNames() {
+		if err := v.backends[name].Clear(ctx, pollID); err != nil {
+			return fmt.Errorf("clearing backend %s: %w", name, err)
+		}
 	}
 
 	v.votedMu.Lock()
 	v.voted[pollID] = nil
 	v.votedMu.Unlock()
 
+	v.sharesMu.Lock()
+	delete(v.shares, pollID)
+	delete(v.aggregates, pollID)
+	delete(v.decryptions, pollID)
+	v.sharesMu.Unlock()
+
+	v.migratedMu.Lock()
+	delete(v.migrated, pollID)
+	delete(v.failedOver, pollID)
+	v.migratedMu.Unlock()
+
+	v.forgetConfig(pollID)
+
 	return nil
 }
 
@@ -159,37 +457,200 @@ func (v *Vote) ClearAll(ctx context.Context) error {
 		r.Reset()
 	}
 
-	if err := v.fastBackend.ClearAll(ctx); err != nil {
-		return fmt.Errorf("clearing fastBackend: %w", err)
-	}
-
-	if err := v.longBackend.ClearAll(ctx); err != nil {
-		return fmt.Errorf("clearing long Backend: %w", err)
+	for _, name := range v.backendNames() {
+		if err := v.backends[name].ClearAll(ctx); err != nil {
+			return fmt.Errorf("clearing backend %s: %w", name, err)
+		}
 	}
 
 	v.votedMu.Lock()
 	v.voted = make(map[int][]int)
 	v.votedMu.Unlock()
 
+	v.sharesMu.Lock()
+	v.shares = make(map[int][]trusteeShare)
+	v.aggregates = make(map[int]homomorphicAggregate)
+	v.decryptions = make(map[int][]partialDecryption)
+	v.sharesMu.Unlock()
+
+	v.migratedMu.Lock()
+	v.migrated = make(map[int]string)
+	v.failedOver = make(map[int]bool)
+	v.migratedMu.Unlock()
+
+	v.configMu.Lock()
+	v.configs = make(map[int]cachedConfig)
+	v.configMu.Unlock()
+
 	return nil
 }
 
 // Vote validates and saves the vote.
-func (v *Vote) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) error {
+//
+// For polls, that are not named, it returns a receipt. The receipt is saved
+// with the vote object, but not with the user id. After the poll is stopped,
+// the user can use it to find the vote in the result.
+func (v *Vote) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) (string, error) {
+	poll, voteUser, object, err := v.prepareVote(ctx, pollID, requestUser, r)
+	if err != nil {
+		return "", err
+	}
+
+	if poll.ptype != "named" {
+		object.Receipt, err = newReceipt()
+		if err != nil {
+			return "", fmt.Errorf("creating receipt: %w", err)
+		}
+	}
+
+	bs, err := json.Marshal(object)
+	if err != nil {
+		return "", fmt.Errorf("encoding vote data: %w", err)
+	}
+
+	v.migrateMu.RLock()
+	defer v.migrateMu.RUnlock()
+
+	save := func() error {
+		if poll.revotable {
+			return v.backend(poll).Revote(ctx, pollID, voteUser, bs)
+		}
+		return v.backend(poll).Vote(ctx, pollID, voteUser, bs)
+	}
+
+	err = save()
+
+	// The poll could be migrated to another backend by another instance.
+	var errStopped interface{ Stopped() }
+	var errNotExist interface{ DoesNotExist() }
+	if errors.As(err, &errStopped) || errors.As(err, &errNotExist) {
+		// The cached config could belong to a poll, that does not exist
+		// anymore in this form.
+		v.forgetConfig(pollID)
+
+		discover := v.discoverMigration
+		if errors.As(err, &errStopped) {
+			discover = v.waitForMigration
+		}
+
+		migrated, discoverErr := discover(ctx, poll)
+		if discoverErr != nil {
+			return "", fmt.Errorf("looking for migrated poll: %w", discoverErr)
+		}
+
+		if migrated {
+			err = save()
+		}
+	}
+
+	if err != nil {
+		if errors.As(err, &errNotExist) {
+			return "", ErrNotExists
+		}
+
+		var errDoubleVote interface{ DoubleVote() }
+		if errors.As(err, &errDoubleVote) {
+			return "", ErrDoubleVote
+		}
+
+		if errors.As(err, &errStopped) {
+			return "", ErrStopped
+		}
+
+		return "", fmt.Errorf("save vote: %w", err)
+	}
+
+	v.votedMu.Lock()
+	if !poll.revotable || !slices.Contains(v.voted[pollID], voteUser) {
+		v.voted[pollID] = append(v.voted[pollID], voteUser)
+	}
+	v.votedMu.Unlock()
+
+	return object.Receipt, nil
+}
+
+// Validate does the same checks as Vote but does not save the vote.
+//
+// It returns the object, that would be saved in the backend, including the
+// vote weight.
+func (v *Vote) Validate(ctx context.Context, pollID, requestUser int, r io.Reader) (json.RawMessage, error) {
+	_, _, object, err := v.prepareVote(ctx, pollID, requestUser, r)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("encoding vote data: %w", err)
+	}
+	return bs, nil
+}
+
+// voteObject is the object that is saved in the backend.
+type voteObject struct {
+	RequestUser int             `json:"request_user_id,omitempty"`
+	VoteUser    int             `json:"vote_user_id,omitempty"`
+	Value       json.RawMessage `json:"value"`
+	Weight      string          `json:"weight"`
+	Receipt     string          `json:"receipt,omitempty"`
+}
+
+// prepareVote validates a vote request and creates the object that is saved in
+// the backend.
+//
+// It returns the poll config, the id of the user the vote is for and the vote
+// object.
+func (v *Vote) prepareVote(ctx context.Context, pollID, requestUser int, r io.Reader) (pollConfig, int, voteObject, error) {
 	ds := dsfetch.New(v.flow)
-	poll, err := loadPoll(ctx, ds, pollID)
+	dsPoll, err := loadPoll(ctx, ds, pollID)
 	if err != nil {
-		return fmt.Errorf("loading poll: %w", err)
+		return pollConfig{}, 0, voteObject{}, fmt.Errorf("loading poll: %w", err)
+	}
+
+	// Validate the vote against the config from the time the poll was started.
+	poll, err := v.frozenConfig(ctx, dsPoll)
+
+	// The poll could be started in another backend by the failover of this or
+	// another instance.
+	var errNotExist interface{ DoesNotExist() }
+	if errors.As(err, &errNotExist) || (err != nil && v.degraded.Load()) {
+		migrated, discoverErr := v.discoverMigration(ctx, dsPoll)
+		if discoverErr != nil {
+			return pollConfig{}, 0, voteObject{}, fmt.Errorf("looking for migrated poll: %w", discoverErr)
+		}
+
+		if migrated {
+			poll, err = v.frozenConfig(ctx, dsPoll)
+		}
+	}
+
+	if err != nil {
+		if errors.As(err, &errNotExist) {
+			return pollConfig{}, 0, voteObject{}, ErrNotExists
+		}
+		return pollConfig{}, 0, voteObject{}, fmt.Errorf("loading poll config from backend: %w", err)
 	}
 	log.Debug("Poll config: %v", poll)
 
 	if err := ensurePresent(ctx, ds, poll.meetingID, requestUser); err != nil {
-		return err
+		return pollConfig{}, 0, voteObject{}, err
+	}
+
+	var body struct {
+		UserID maybeInt        `json:"user_id"`
+		Value  json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return pollConfig{}, 0, voteObject{}, MessageError(ErrInvalid, "decoding payload: %v", err)
 	}
 
-	var vote ballot
-	if err := json.NewDecoder(r).Decode(&vote); err != nil {
-		return MessageError(ErrInvalid, "decoding payload: %v", err)
+	// The value of homomorphic polls is no ballotValue. It is validated with
+	// its proofs.
+	vote := ballot{UserID: body.UserID}
+	if !poll.homomorphic && body.Value != nil {
+		if err := json.Unmarshal(body.Value, &vote.Value); err != nil {
+			return pollConfig{}, 0, voteObject{}, MessageError(ErrInvalid, "decoding payload: %v", err)
+		}
 	}
 
 	voteUser, exist := vote.UserID.Value()
@@ -198,62 +659,49 @@ func (v *Vote) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) e
 	}
 
 	if voteUser == 0 {
-		return MessageError(ErrNotAllowed, "Votes for anonymous user are not allowed")
+		return pollConfig{}, 0, voteObject{}, MessageError(ErrNotAllowed, "Votes for anonymous user are not allowed")
 	}
 
 	voteMeetingUserID, found, err := getMeetingUser(ctx, ds, voteUser, poll.meetingID)
 	if err != nil {
-		return fmt.Errorf("get meeting user for vote user: %w", err)
+		return pollConfig{}, 0, voteObject{}, fmt.Errorf("get meeting user for vote user: %w", err)
 	}
 
 	if !found {
-		return MessageError(ErrNotAllowed, "You are not in the right meeting")
+		return pollConfig{}, 0, voteObject{}, MessageError(ErrNotAllowed, "You are not in the right meeting")
 	}
 
 	if err := ensureVoteUser(ctx, ds, poll, voteUser, voteMeetingUserID, requestUser); err != nil {
-		return err
+		return pollConfig{}, 0, voteObject{}, err
 	}
 
-	if validation := validate(poll, vote.Value); validation != "" {
-		return MessageError(ErrInvalid, validation)
+	var validation string
+	switch {
+	case poll.homomorphic:
+		validation = validateHomomorphic(poll, body.Value)
+	case poll.ptype == encryptedPollType:
+		// The value is validated, when it is decrypted.
+		validation = validateCiphertext(vote.Value)
+	default:
+		validation = validate(poll, vote.Value)
 	}
 
-	// voteData.Weight is a DecimalField with 6 zeros.
-	var voteWeightEnabled bool
-	var meetingUserVoteWeight string
-	var userDefaultVoteWeight string
-	ds.Meeting_UsersEnableVoteWeight(poll.meetingID).Lazy(&voteWeightEnabled)
-	ds.MeetingUser_VoteWeight(voteMeetingUserID).Lazy(&meetingUserVoteWeight)
-	ds.User_DefaultVoteWeight(voteUser).Lazy(&userDefaultVoteWeight)
-
-	if err := ds.Execute(ctx); err != nil {
-		return fmt.Errorf("getting vote weight: %w", err)
+	if validation != "" {
+		return pollConfig{}, 0, voteObject{}, MessageError(ErrInvalid, validation)
 	}
 
-	var voteWeight string
-	if voteWeightEnabled {
-		voteWeight = meetingUserVoteWeight
-		if voteWeight == "" {
-			voteWeight = userDefaultVoteWeight
-		}
-	}
-
-	if voteWeight == "" {
-		voteWeight = "1.000000"
+	voteWeight, err := getVoteWeight(ctx, ds, poll.meetingID, voteMeetingUserID, voteUser)
+	if err != nil {
+		return pollConfig{}, 0, voteObject{}, fmt.Errorf("getting vote weight: %w", err)
 	}
 
 	log.Debug("Using voteWeight %s", voteWeight)
 
-	voteData := struct {
-		RequestUser int             `json:"request_user_id,omitempty"`
-		VoteUser    int             `json:"vote_user_id,omitempty"`
-		Value       json.RawMessage `json:"value"`
-		Weight      string          `json:"weight"`
-	}{
-		requestUser,
-		voteUser,
-		vote.Value.original,
-		voteWeight,
+	voteData := voteObject{
+		RequestUser: requestUser,
+		VoteUser:    voteUser,
+		Value:       body.Value,
+		Weight:      voteWeight,
 	}
 
 	if poll.ptype != "named" {
@@ -261,35 +709,7 @@ func (v *Vote) Vote(ctx context.Context, pollID, requestUser int, r io.Reader) e
 		voteData.VoteUser = 0
 	}
 
-	bs, err := json.Marshal(voteData)
-	if err != nil {
-		return fmt.Errorf("decoding vote data: %w", err)
-	}
-
-	if err := v.backend(poll).Vote(ctx, pollID, voteUser, bs); err != nil {
-		var errNotExist interface{ DoesNotExist() }
-		if errors.As(err, &errNotExist) {
-			return ErrNotExists
-		}
-
-		var errDoubleVote interface{ DoubleVote() }
-		if errors.As(err, &errDoubleVote) {
-			return ErrDoubleVote
-		}
-
-		var errNotOpen interface{ Stopped() }
-		if errors.As(err, &errNotOpen) {
-			return ErrStopped
-		}
-
-		return fmt.Errorf("save vote: %w", err)
-	}
-
-	v.votedMu.Lock()
-	v.voted[pollID] = append(v.voted[pollID], voteUser)
-	v.votedMu.Unlock()
-
-	return nil
+	return poll, voteUser, voteData, nil
 }
 
 // getMeetingUser returns the meeting_user id between a userID and a meetingID.
@@ -317,6 +737,36 @@ func getMeetingUser(ctx context.Context, fetch *dsfetch.Fetch, userID, meetingID
 	return 0, false, nil
 }
 
+// getVoteWeight returns the vote weight of a user in a meeting.
+//
+// The vote weight is a DecimalField with 6 zeros.
+func getVoteWeight(ctx context.Context, ds *dsfetch.Fetch, meetingID, meetingUserID, userID int) (string, error) {
+	var voteWeightEnabled bool
+	var meetingUserVoteWeight string
+	var userDefaultVoteWeight string
+	ds.Meeting_UsersEnableVoteWeight(meetingID).Lazy(&voteWeightEnabled)
+	ds.MeetingUser_VoteWeight(meetingUserID).Lazy(&meetingUserVoteWeight)
+	ds.User_DefaultVoteWeight(userID).Lazy(&userDefaultVoteWeight)
+
+	if err := ds.Execute(ctx); err != nil {
+		return "", fmt.Errorf("fetching vote weight: %w", err)
+	}
+
+	var voteWeight string
+	if voteWeightEnabled {
+		voteWeight = meetingUserVoteWeight
+		if voteWeight == "" {
+			voteWeight = userDefaultVoteWeight
+		}
+	}
+
+	if voteWeight == "" {
+		voteWeight = "1.000000"
+	}
+
+	return voteWeight, nil
+}
+
 // ensurePresent makes sure that the user sending the vote request is present.
 func ensurePresent(ctx context.Context, ds *dsfetch.Fetch, meetingID, user int) error {
 	presentMeetings, err := ds.User_IsPresentInMeetingIDs(user).Value(ctx)
@@ -482,23 +932,35 @@ func (v *Vote) VoteCount(ctx context.Context) map[int]int {
 
 // loadVoted creates the value for v.voted by the backends.
 func (v *Vote) loadVoted(ctx context.Context) error {
-	fastData, err := v.fastBackend.Voted(ctx)
-	if err != nil {
-		return fmt.Errorf("fetching data from fast backend: %w", err)
-	}
+	voted := make(map[int][]int)
+	for _, name := range v.backendNames() {
+		if name == "fast" && v.degraded.Load() {
+			continue
+		}
 
-	longData, err := v.longBackend.Voted(ctx)
-	if err != nil {
-		return fmt.Errorf("fetching data from long backend: %w", err)
-	}
+		data, err := v.backends[name].Voted(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching data from backend %s: %w", name, err)
+		}
 
-	for pid, userIDs := range longData {
-		fastData[pid] = userIDs
+		for pid, userIDs := range data {
+			voted[pid] = userIDs
+		}
 	}
 
 	v.votedMu.Lock()
-	v.voted = fastData
+	v.voted = voted
 	v.votedMu.Unlock()
+
+	// Polls without votes could be cleared by another instance. Their config
+	// is fetched again on the next vote.
+	v.configMu.Lock()
+	for pollID := range v.configs {
+		if _, ok := voted[pollID]; !ok {
+			delete(v.configs, pollID)
+		}
+	}
+	v.configMu.Unlock()
 	return nil
 }
 
@@ -507,7 +969,14 @@ type Backend interface {
 	// Start opens the poll for votes. To start a poll that is already started
 	// is ok. To start an stopped poll is also ok, but it has to be a noop (the
 	// stop-state does not change).
-	Start(ctx context.Context, pollID int) error
+	//
+	// The config is saved with the poll. If the poll is already started or
+	// stopped, the config must not be changed.
+	Start(ctx context.Context, pollID int, config []byte) error
+
+	// Config returns the config that was saved, when the poll was started. On
+	// an unknown poll `DoesNotExist()` has to be returned.
+	Config(ctx context.Context, pollID int) ([]byte, error)
 
 	// Vote saves vote data into the backend. The backend has to check that the
 	// poll is started and the userID has not voted before.
@@ -519,6 +988,15 @@ type Backend interface {
 	// The return value is the number of already voted objects.
 	Vote(ctx context.Context, pollID int, userID int, object []byte) error
 
+	// Revote saves vote data like Vote, but if the user has already voted, the
+	// previous vote object is replaced by the new one. The vote objects of the
+	// poll must not contain both objects at any time.
+	//
+	// A poll has to use either Vote or Revote for all of its votes. When the
+	// poll is stopped, the backend should remove the link between the user ids
+	// and the vote objects.
+	Revote(ctx context.Context, pollID int, userID int, object []byte) error
+
 	// Stop ends a poll and returns all poll objects and all userIDs from users
 	// that have voted. It is ok to call Stop() on a stopped poll. On a unknown
 	// poll `DoesNotExist()` has to be returned.
@@ -552,6 +1030,34 @@ type pollConfig struct {
 	maxVotesPerOption int
 	options           []int
 	state             string
+
+	// entitled is the encoded list of entitled users from the time the poll
+	// was started. It is only decoded when needed. It is not part of the
+	// cached configs, so only Stop, that reads the config from the backend,
+	// has it.
+	entitled json.RawMessage
+
+	// revotable is true, if the users can change their vote. It is set by the
+	// options of the start request.
+	revotable bool
+
+	// encryptionKey is the public key of an encrypted poll. It is set by the
+	// options of the start request.
+	encryptionKey []byte
+
+	// homomorphic is true, if the votes of an encrypted poll are counted
+	// without decrypting single ballots. It is set by the options of the start
+	// request.
+	homomorphic bool
+
+	// minScore and maxScore are the range of the scores of a poll with the
+	// method score. They are set by the options of the start request.
+	minScore int
+	maxScore int
+
+	// migratedFrom is the name of the backend, from which the poll was
+	// migrated. It is only set in the config of the new backend.
+	migratedFrom string
 }
 
 func loadPoll(ctx context.Context, ds *dsfetch.Fetch, pollID int) (pollConfig, error) {
@@ -581,6 +1087,174 @@ func loadPoll(ctx context.Context, ds *dsfetch.Fetch, pollID int) (pollConfig, e
 	return p, nil
 }
 
+// pollConfigJSON is the representation of a pollConfig that is saved in the
+// backend.
+type pollConfigJSON struct {
+	ID                int    `json:"id"`
+	MeetingID         int    `json:"meeting_id"`
+	Backend           string `json:"backend"`
+	Type              string `json:"type"`
+	Method            string `json:"pollmethod"`
+	Groups            []int  `json:"entitled_group_ids"`
+	GlobalYes         bool   `json:"global_yes"`
+	GlobalNo          bool   `json:"global_no"`
+	GlobalAbstain     bool   `json:"global_abstain"`
+	MinAmount         int    `json:"min_votes_amount"`
+	MaxAmount         int    `json:"max_votes_amount"`
+	MaxVotesPerOption int    `json:"max_votes_per_option"`
+	Options           []int  `json:"option_ids"`
+
+	Entitled      json.RawMessage `json:"entitled_users,omitempty"`
+	Revotable     bool            `json:"revotable,omitempty"`
+	EncryptionKey []byte          `json:"encryption_key,omitempty"`
+	Homomorphic   bool            `json:"homomorphic,omitempty"`
+	MinScore      int             `json:"min_score,omitempty"`
+	MaxScore      int             `json:"max_score,omitempty"`
+	MigratedFrom  string          `json:"migrated_from,omitempty"`
+}
+
+// MarshalJSON encodes the poll config without its state.
+func (p pollConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pollConfigJSON{
+		ID:                p.id,
+		MeetingID:         p.meetingID,
+		Backend:           p.backend,
+		Type:              p.ptype,
+		Method:            p.method,
+		Groups:            p.groups,
+		GlobalYes:         p.globalYes,
+		GlobalNo:          p.globalNo,
+		GlobalAbstain:     p.globalAbstain,
+		MinAmount:         p.minAmount,
+		MaxAmount:         p.maxAmount,
+		MaxVotesPerOption: p.maxVotesPerOption,
+		Options:           p.options,
+		Entitled:          p.entitled,
+		Revotable:         p.revotable,
+		EncryptionKey:     p.encryptionKey,
+		Homomorphic:       p.homomorphic,
+		MinScore:          p.minScore,
+		MaxScore:          p.maxScore,
+		MigratedFrom:      p.migratedFrom,
+	})
+}
+
+// UnmarshalJSON decodes a poll config created with MarshalJSON.
+func (p *pollConfig) UnmarshalJSON(b []byte) error {
+	var data pollConfigJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return fmt.Errorf("decoding poll config: %w", err)
+	}
+
+	*p = pollConfig{
+		id:                data.ID,
+		meetingID:         data.MeetingID,
+		backend:           data.Backend,
+		ptype:             data.Type,
+		method:            data.Method,
+		groups:            data.Groups,
+		globalYes:         data.GlobalYes,
+		globalNo:          data.GlobalNo,
+		globalAbstain:     data.GlobalAbstain,
+		minAmount:         data.MinAmount,
+		maxAmount:         data.MaxAmount,
+		maxVotesPerOption: data.MaxVotesPerOption,
+		options:           data.Options,
+		entitled:          data.Entitled,
+		revotable:         data.Revotable,
+		encryptionKey:     data.EncryptionKey,
+		homomorphic:       data.Homomorphic,
+		minScore:          data.MinScore,
+		maxScore:          data.MaxScore,
+		migratedFrom:      data.MigratedFrom,
+	}
+	return nil
+}
+
+// equal returns true, if both poll configs are the same. The state, the
+// entitled users, the start options and the migration are ignored.
+func (p pollConfig) equal(other pollConfig) bool {
+	p.entitled = nil
+	other.entitled = nil
+	p.revotable = false
+	other.revotable = false
+	p.encryptionKey = nil
+	other.encryptionKey = nil
+	p.homomorphic = false
+	other.homomorphic = false
+	p.minScore, p.maxScore = 0, 0
+	other.minScore, other.maxScore = 0, 0
+	p.migratedFrom = ""
+	other.migratedFrom = ""
+	b1, err1 := json.Marshal(p)
+	b2, err2 := json.Marshal(other)
+	return err1 == nil && err2 == nil && bytes.Equal(b1, b2)
+}
+
+// cachedConfig is a frozen config together with the config from the
+// datastore, that was used to fetch it.
+type cachedConfig struct {
+	ds     pollConfig
+	frozen pollConfig
+}
+
+// frozenConfig returns the frozen config of a poll like the function
+// frozenConfig, but uses the cached config, if the config from the datastore
+// did not change since it was fetched.
+//
+// The returned config does not contain the entitled users.
+func (v *Vote) frozenConfig(ctx context.Context, poll pollConfig) (pollConfig, error) {
+	v.configMu.Lock()
+	cached, ok := v.configs[poll.id]
+	v.configMu.Unlock()
+
+	if ok && cached.ds.equal(poll) {
+		return cached.frozen, nil
+	}
+
+	frozen, err := frozenConfig(ctx, v.backend(poll), poll)
+	if err != nil {
+		return pollConfig{}, err
+	}
+
+	// The entitled users are only needed at Stop and can be big.
+	frozen.entitled = nil
+
+	v.configMu.Lock()
+	v.configs[poll.id] = cachedConfig{ds: poll, frozen: frozen}
+	v.configMu.Unlock()
+	return frozen, nil
+}
+
+// forgetConfig removes the cached config of a poll.
+func (v *Vote) forgetConfig(pollID int) {
+	v.configMu.Lock()
+	delete(v.configs, pollID)
+	v.configMu.Unlock()
+}
+
+// frozenConfig returns the poll config that was saved in the backend when the
+// poll was started.
+//
+// If the poll was started without a config, for example by an older version of
+// the service, the given config from the datastore is returned.
+func frozenConfig(ctx context.Context, backend Backend, poll pollConfig) (pollConfig, error) {
+	raw, err := backend.Config(ctx, poll.id)
+	if err != nil {
+		return pollConfig{}, fmt.Errorf("fetching poll config: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return poll, nil
+	}
+
+	var frozen pollConfig
+	if err := json.Unmarshal(raw, &frozen); err != nil {
+		return pollConfig{}, err
+	}
+	return frozen, nil
+}
+
 // preload loads all data in the cache, that is needed later for the vote
 // requests.
 func (p pollConfig) preload(ctx context.Context, ds *dsfetch.Fetch) error {
@@ -692,29 +1366,45 @@ func (v ballot) String() string {
 	return string(bs)
 }
 
-func validate(poll pollConfig, v ballotValue) string {
-	if poll.minAmount == 0 {
-		poll.minAmount = 1
+// withDefaults returns the poll config with the default values for the vote
+// amounts.
+func (p pollConfig) withDefaults() pollConfig {
+	if p.minAmount == 0 {
+		p.minAmount = 1
 	}
 
-	if poll.maxAmount == 0 {
-		poll.maxAmount = 1
+	if p.maxAmount == 0 {
+		p.maxAmount = 1
+		if p.method == "ranked" || p.method == "schulze" {
+			// Per default, all options can be ranked.
+			p.maxAmount = max(len(p.options), 1)
+		}
 	}
 
-	if poll.maxVotesPerOption == 0 {
-		poll.maxVotesPerOption = 1
+	if p.maxVotesPerOption == 0 {
+		p.maxVotesPerOption = 1
 	}
+	return p
+}
+
+// allowedGlobal returns for each global answer, if it is enabled.
+func (p pollConfig) allowedGlobal() map[string]bool {
+	return map[string]bool{
+		"Y": p.globalYes,
+		"N": p.globalNo,
+		"A": p.globalAbstain,
+	}
+}
+
+func validate(poll pollConfig, v ballotValue) string {
+	poll = poll.withDefaults()
 
 	allowedOptions := make(map[int]bool, len(poll.options))
 	for _, o := range poll.options {
 		allowedOptions[o] = true
 	}
 
-	allowedGlobal := map[string]bool{
-		"Y": poll.globalYes,
-		"N": poll.globalNo,
-		"A": poll.globalAbstain,
-	}
+	allowedGlobal := poll.allowedGlobal()
 
 	var voteIsValid string
 
@@ -782,6 +1472,67 @@ func validate(poll pollConfig, v ballotValue) string {
 			return "Your vote has a wrong format"
 		}
 
+	case "score":
+		switch v.Type() {
+		case ballotValueString:
+			// The user answered with Y, N or A (or another invalid string).
+			if !allowedGlobal[v.str] {
+				return fmt.Sprintf("Global vote %s is not enabled", v.str)
+			}
+			return voteIsValid
+
+		case ballotValueOptionAmount:
+			if len(v.optionAmount) == 0 {
+				return "You have to score at least one option"
+			}
+
+			for optionID, score := range v.optionAmount {
+				if !allowedOptions[optionID] {
+					return fmt.Sprintf("Option_id %d does not belong to the poll", optionID)
+				}
+
+				if score < poll.minScore || score > poll.maxScore {
+					return fmt.Sprintf("Your score for option %d has to be between %d and %d", optionID, poll.minScore, poll.maxScore)
+				}
+			}
+			return voteIsValid
+
+		default:
+			return "Your vote has a wrong format"
+		}
+
+	case "ranked", "schulze":
+		switch v.Type() {
+		case ballotValueString:
+			// The user answered with Y, N or A (or another invalid string).
+			if !allowedGlobal[v.str] {
+				return fmt.Sprintf("Global vote %s is not enabled", v.str)
+			}
+			return voteIsValid
+
+		case ballotValueOptionRanking:
+			ranked := make(map[int]bool, len(v.optionRanking))
+			for _, optionID := range v.optionRanking {
+				if !allowedOptions[optionID] {
+					return fmt.Sprintf("Option_id %d does not belong to the poll", optionID)
+				}
+
+				if ranked[optionID] {
+					return fmt.Sprintf("Option_id %d is ranked more then once", optionID)
+				}
+				ranked[optionID] = true
+			}
+
+			if len(v.optionRanking) < poll.minAmount || len(v.optionRanking) > poll.maxAmount {
+				return fmt.Sprintf("You have to rank between %d and %d options", poll.minAmount, poll.maxAmount)
+			}
+
+			return voteIsValid
+
+		default:
+			return "Your vote has a wrong format"
+		}
+
 	default:
 		return "Your vote has a wrong format"
 	}
@@ -793,6 +1544,10 @@ type ballotValue struct {
 	optionAmount map[int]int
 	optionYNA    map[int]string
 
+	// optionRanking is a list of option ids. The first option is the most
+	// preferred one.
+	optionRanking []int
+
 	original json.RawMessage
 }
 
@@ -818,6 +1573,12 @@ func (v *ballotValue) UnmarshalJSON(b []byte) error {
 		// voteData is option_id to string
 		return nil
 	}
+	v.optionYNA = nil
+
+	if err := json.Unmarshal(b, &v.optionRanking); err == nil {
+		// voteData is an ordered list of option_ids
+		return nil
+	}
 
 	return fmt.Errorf("unknown vote value: `%s`", b)
 }
@@ -827,6 +1588,7 @@ const (
 	ballotValueString
 	ballotValueOptionAmount
 	ballotValueOptionString
+	ballotValueOptionRanking
 )
 
 func (v *ballotValue) Type() int {
@@ -842,6 +1604,10 @@ func (v *ballotValue) Type() int {
 		return ballotValueOptionString
 	}
 
+	if v.optionRanking != nil {
+		return ballotValueOptionRanking
+	}
+
 	return ballotValueUnknown
 }
 
@@ -0,0 +1,286 @@
+package vote_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsmock"
+	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func TestVoteEncrypted(t *testing.T) {
+	ctx := context.Background()
+
+	pollData := `
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: Y
+		global_yes: true
+		backend: fast
+		type: encrypted
+
+	meeting/1/id: 1
+	group/1/meeting_user_ids: [10, 20, 30]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+	user/2:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [20]
+	user/3:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [30]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/20:
+		user_id: 2
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/30:
+		user_id: 3
+		group_ids: [1]
+		meeting_id: 1
+	`
+
+	publicKey, shares, err := vote.NewEncryptionKey(2, 3)
+	if err != nil {
+		t.Fatalf("NewEncryptionKey returned unexpected error: %v", err)
+	}
+
+	encryptedBallot := func(pollID int, value string) string {
+		encrypted, err := vote.EncryptBallot(publicKey, pollID, []byte(value))
+		if err != nil {
+			t.Fatalf("EncryptBallot returned unexpected error: %v", err)
+		}
+		return fmt.Sprintf(`{"value":"%s"}`, encrypted)
+	}
+
+	t.Run("Start without key", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Start returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Start with many instances", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, false)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Start returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Plain vote", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Vote returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Decrypt on stop", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		key, err := v.EncryptionKey(ctx, 1)
+		if err != nil {
+			t.Fatalf("EncryptionKey returned unexpected error: %v", err)
+		}
+
+		if string(key) != string(publicKey) {
+			t.Errorf("EncryptionKey returned %x, expected %x", key, publicKey)
+		}
+
+		// Global no is not enabled. The ballot is only invalid after the
+		// decryption.
+		garbage := base64.StdEncoding.EncodeToString(make([]byte, 64))
+		for userID, body := range map[int]string{
+			1: encryptedBallot(1, `"Y"`),
+			2: encryptedBallot(1, `"N"`),
+			3: fmt.Sprintf(`{"value":"%s"}`, garbage),
+		} {
+			if _, err := v.Vote(ctx, 1, userID, strings.NewReader(body)); err != nil {
+				t.Fatalf("Vote for user %d returned unexpected error: %v", userID, err)
+			}
+		}
+
+		if err := v.AddShare(ctx, 1, shares[2]); err != nil {
+			t.Fatalf("AddShare returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Stop with one share returned %v, expected ErrInvalid", err)
+		}
+
+		if err := v.AddShare(ctx, 1, shares[0]); err != nil {
+			t.Fatalf("AddShare returned unexpected error: %v", err)
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 1 {
+			t.Fatalf("Got %d votes, expected 1", len(result.Votes))
+		}
+
+		var object struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(result.Votes[0], &object); err != nil {
+			t.Fatalf("decoding vote object: %v", err)
+		}
+
+		if object.Value != "Y" {
+			t.Errorf("Got decrypted value %s, expected Y", object.Value)
+		}
+
+		if len(result.InvalidBallots) != 2 {
+			t.Fatalf("Got %d invalid ballots, expected 2", len(result.InvalidBallots))
+		}
+
+		if result.Tally.Ballots != 3 || result.Tally.Invalid != 2 {
+			t.Errorf("Got tally %+v, expected 3 ballots with 2 invalid", result.Tally)
+		}
+
+		if len(result.UserIDs) != 3 {
+			t.Errorf("Got user ids %v, expected 3 users", result.UserIDs)
+		}
+	})
+
+	t.Run("Missing shares do not stop the poll", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if err := v.AddShare(ctx, 1, shares[0]); err != nil {
+			t.Fatalf("AddShare returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Stop with one share returned %v, expected ErrInvalid", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(encryptedBallot(1, `"Y"`))); err != nil {
+			t.Errorf("Vote after the failed stop returned unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Ballot of another poll", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		// The ballot was encrypted for poll 2 with the same key.
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(encryptedBallot(2, `"Y"`))); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		for _, share := range shares[:2] {
+			if err := v.AddShare(ctx, 1, share); err != nil {
+				t.Fatalf("AddShare returned unexpected error: %v", err)
+			}
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 0 || len(result.InvalidBallots) != 1 {
+			t.Errorf("Got %d votes and %d invalid ballots, expected only one invalid ballot", len(result.Votes), len(result.InvalidBallots))
+		}
+	})
+
+	t.Run("Shares of another key", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		_, otherShares, err := vote.NewEncryptionKey(2, 3)
+		if err != nil {
+			t.Fatalf("NewEncryptionKey returned unexpected error: %v", err)
+		}
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		for _, share := range otherShares[:2] {
+			if err := v.AddShare(ctx, 1, share); err != nil {
+				t.Fatalf("AddShare returned unexpected error: %v", err)
+			}
+		}
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Stop returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Shares of another key first", func(t *testing.T) {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		_, otherShares, err := vote.NewEncryptionKey(2, 3)
+		if err != nil {
+			t.Fatalf("NewEncryptionKey returned unexpected error: %v", err)
+		}
+
+		_, higherShares, err := vote.NewEncryptionKey(3, 3)
+		if err != nil {
+			t.Fatalf("NewEncryptionKey returned unexpected error: %v", err)
+		}
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey)); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(encryptedBallot(1, `"Y"`))); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		for _, share := range []string{higherShares[0], otherShares[0], shares[1], otherShares[1], shares[2]} {
+			if err := v.AddShare(ctx, 1, share); err != nil {
+				t.Fatalf("AddShare returned unexpected error: %v", err)
+			}
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 1 {
+			t.Errorf("Got %d votes, expected 1", len(result.Votes))
+		}
+	})
+}
@@ -0,0 +1,327 @@
+package vote_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func TestEvaluate(t *testing.T) {
+	one := vote.DecimalFromInt(1)
+
+	result := vote.StopResult{
+		Method: "YNA",
+		Tally: vote.Tally{
+			Ballots:    10,
+			Invalid:    1,
+			VotesCast:  10 * one,
+			VotesValid: 9 * one,
+			Options: map[int]map[string]vote.Decimal{
+				1: {"Y": 6 * one, "N": 2 * one, "A": 1 * one},
+				2: {"Y": 5 * one, "N": 4 * one},
+				3: {},
+			},
+		},
+		Turnout: &vote.Turnout{
+			Entitled:       20,
+			EntitledWeight: 25 * one,
+			Voted:          10,
+			VotedWeight:    10 * one,
+		},
+	}
+
+	for _, tt := range []struct {
+		name          string
+		majority      string
+		base          string
+		quorum        string
+		quorumBase    string
+		expectQuorum  bool
+		expectPassed  map[int]bool
+		expectInvalid bool
+	}{
+		{
+			name:         "simple",
+			majority:     "simple",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: true, 2: true, 3: false},
+		},
+		{
+			name:         "absolute of cast votes",
+			majority:     "absolute",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: true, 2: false, 3: false},
+		},
+		{
+			name:         "absolute of valid votes",
+			majority:     "absolute",
+			base:         "valid",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: true, 2: true, 3: false},
+		},
+		{
+			name:         "two thirds of valid votes",
+			majority:     "two_thirds",
+			base:         "valid",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: true, 2: false, 3: false},
+		},
+		{
+			name:         "two thirds of cast votes",
+			majority:     "two_thirds",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: false, 2: false, 3: false},
+		},
+		{
+			name:         "custom fraction of entitled",
+			majority:     "1/4",
+			base:         "entitled",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: false, 2: false, 3: false},
+		},
+		{
+			name:         "custom fraction of entitled weight",
+			majority:     "1/4",
+			base:         "entitled_weight",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: false, 2: false, 3: false},
+		},
+		{
+			name:         "quorum reached",
+			majority:     "simple",
+			quorum:       "1/2",
+			expectQuorum: true,
+			expectPassed: map[int]bool{1: true, 2: true, 3: false},
+		},
+		{
+			name:         "quorum by weight not reached",
+			majority:     "simple",
+			quorum:       "1/2",
+			quorumBase:   "entitled_weight",
+			expectQuorum: false,
+			expectPassed: map[int]bool{1: false, 2: false, 3: false},
+		},
+		{
+			name:          "invalid majority",
+			majority:      "most",
+			expectInvalid: true,
+		},
+		{
+			name:          "invalid fraction",
+			majority:      "3/2",
+			expectInvalid: true,
+		},
+		{
+			name:          "invalid base",
+			majority:      "simple",
+			base:          "present",
+			expectInvalid: true,
+		},
+		{
+			name:          "invalid quorum base",
+			majority:      "simple",
+			quorum:        "1/2",
+			quorumBase:    "valid",
+			expectInvalid: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := vote.ParseMajorityRule(tt.majority, tt.base, tt.quorum, tt.quorumBase)
+			if tt.expectInvalid {
+				if err == nil {
+					t.Fatalf("ParseMajorityRule did not return an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseMajorityRule returned unexpected error: %v", err)
+			}
+
+			got, err := vote.Evaluate(result, rule)
+			if err != nil {
+				t.Fatalf("Evaluate returned unexpected error: %v", err)
+			}
+
+			if got.QuorumReached != tt.expectQuorum {
+				t.Errorf("Got quorum reached %t, expected %t", got.QuorumReached, tt.expectQuorum)
+			}
+
+			for optionID, expect := range tt.expectPassed {
+				if got.Options[optionID].Passed != expect {
+					t.Errorf("Option %d: got passed %t, expected %t", optionID, got.Options[optionID].Passed, expect)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateWeighted(t *testing.T) {
+	one := vote.DecimalFromInt(1)
+
+	// Three users with the weights 1, 1 and 4. Only the user with weight 4
+	// votes yes.
+	result := vote.StopResult{
+		Method: "YN",
+		Tally: vote.Tally{
+			Ballots:    1,
+			VotesCast:  4 * one,
+			VotesValid: 4 * one,
+			Global:     map[string]vote.Decimal{"Y": 4 * one},
+		},
+		Turnout: &vote.Turnout{
+			Entitled:       3,
+			EntitledWeight: 6 * one,
+			Voted:          1,
+			VotedWeight:    4 * one,
+		},
+	}
+
+	for _, tt := range []struct {
+		name         string
+		base         string
+		quorumBase   string
+		expectQuorum bool
+		expectPassed bool
+	}{
+		{
+			name:         "entitled",
+			base:         "entitled",
+			quorumBase:   "entitled",
+			expectQuorum: false,
+			expectPassed: false,
+		},
+		{
+			name:         "entitled weight",
+			base:         "entitled_weight",
+			quorumBase:   "entitled_weight",
+			expectQuorum: true,
+			expectPassed: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := vote.ParseMajorityRule("two_thirds", tt.base, "1/2", tt.quorumBase)
+			if err != nil {
+				t.Fatalf("ParseMajorityRule returned unexpected error: %v", err)
+			}
+
+			got, err := vote.Evaluate(result, rule)
+			if err != nil {
+				t.Fatalf("Evaluate returned unexpected error: %v", err)
+			}
+
+			// 4 of 6 is two thirds of the weight, but 4 is more then the 3
+			// entitled users.
+			if got.Base != 6*one {
+				t.Errorf("Got base %s, expected 6", got.Base)
+			}
+
+			if got.QuorumReached != tt.expectQuorum {
+				t.Errorf("Got quorum reached %t, expected %t", got.QuorumReached, tt.expectQuorum)
+			}
+
+			if got.Global == nil {
+				t.Fatalf("Got no evaluation of the global answers")
+			}
+
+			if got.Global.Passed != tt.expectPassed {
+				t.Errorf("Got passed %t, expected %t", got.Global.Passed, tt.expectPassed)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithoutTurnout(t *testing.T) {
+	rule, err := vote.ParseMajorityRule("absolute", "entitled", "", "")
+	if err != nil {
+		t.Fatalf("ParseMajorityRule returned unexpected error: %v", err)
+	}
+
+	_, err = vote.Evaluate(vote.StopResult{Method: "YN"}, rule)
+	if !errors.Is(err, vote.ErrInvalid) {
+		t.Errorf("Evaluate without turnout returned `%v`, expected an ErrInvalid", err)
+	}
+}
+
+func TestEvaluateMethods(t *testing.T) {
+	one := vote.DecimalFromInt(1)
+
+	rule, err := vote.ParseMajorityRule("simple", "", "", "")
+	if err != nil {
+		t.Fatalf("ParseMajorityRule returned unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name          string
+		result        vote.StopResult
+		expectPassed  map[int]bool
+		expectInvalid bool
+	}{
+		{
+			name: "Y with global no",
+			result: vote.StopResult{
+				Method: "Y",
+				Tally: vote.Tally{
+					VotesCast: 10 * one,
+					Global:    map[string]vote.Decimal{"N": 3 * one},
+					Options: map[int]map[string]vote.Decimal{
+						1: {"Y": 5 * one},
+						2: {"Y": 2 * one},
+					},
+				},
+			},
+			expectPassed: map[int]bool{1: true, 2: false},
+		},
+		{
+			name: "N with global yes",
+			result: vote.StopResult{
+				Method: "N",
+				Tally: vote.Tally{
+					VotesCast: 10 * one,
+					Global:    map[string]vote.Decimal{"Y": 3 * one},
+					Options: map[int]map[string]vote.Decimal{
+						1: {"N": 5 * one},
+						2: {"N": 2 * one},
+					},
+				},
+			},
+			expectPassed: map[int]bool{1: false, 2: true},
+		},
+		{
+			name:          "score",
+			result:        vote.StopResult{Method: "score"},
+			expectInvalid: true,
+		},
+		{
+			name:          "ranked",
+			result:        vote.StopResult{Method: "ranked"},
+			expectInvalid: true,
+		},
+		{
+			name:          "schulze",
+			result:        vote.StopResult{Method: "schulze"},
+			expectInvalid: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vote.Evaluate(tt.result, rule)
+			if tt.expectInvalid {
+				if !errors.Is(err, vote.ErrInvalid) {
+					t.Errorf("Evaluate returned `%v`, expected an ErrInvalid", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Evaluate returned unexpected error: %v", err)
+			}
+
+			for optionID, expect := range tt.expectPassed {
+				if got.Options[optionID].Passed != expect {
+					t.Errorf("Option %d: got passed %t, expected %t", optionID, got.Options[optionID].Passed, expect)
+				}
+			}
+		})
+	}
+}
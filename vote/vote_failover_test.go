@@ -0,0 +1,292 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsmock"
+	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+)
+
+// errConnection is returned by unreachableBackend, while it is down.
+var errConnection = errors.New("connection refused")
+
+// unreachableBackend is a memory backend, that returns a connection error on
+// each call, while down is set.
+type unreachableBackend struct {
+	*memory.Backend
+	down atomic.Bool
+}
+
+func (b *unreachableBackend) Ping(ctx context.Context) error {
+	if b.down.Load() {
+		return errConnection
+	}
+	return nil
+}
+
+func (b *unreachableBackend) Start(ctx context.Context, pollID int, config []byte) error {
+	if b.down.Load() {
+		return errConnection
+	}
+	return b.Backend.Start(ctx, pollID, config)
+}
+
+func (b *unreachableBackend) Config(ctx context.Context, pollID int) ([]byte, error) {
+	if b.down.Load() {
+		return nil, errConnection
+	}
+	return b.Backend.Config(ctx, pollID)
+}
+
+func (b *unreachableBackend) Vote(ctx context.Context, pollID int, userID int, object []byte) error {
+	if b.down.Load() {
+		return errConnection
+	}
+	return b.Backend.Vote(ctx, pollID, userID, object)
+}
+
+func (b *unreachableBackend) Revote(ctx context.Context, pollID int, userID int, object []byte) error {
+	if b.down.Load() {
+		return errConnection
+	}
+	return b.Backend.Revote(ctx, pollID, userID, object)
+}
+
+func (b *unreachableBackend) Stop(ctx context.Context, pollID int) ([][]byte, []int, error) {
+	if b.down.Load() {
+		return nil, nil, errConnection
+	}
+	return b.Backend.Stop(ctx, pollID)
+}
+
+func (b *unreachableBackend) StopChecked(ctx context.Context, pollID int) ([][]byte, []int, bool, error) {
+	if b.down.Load() {
+		return nil, nil, false, errConnection
+	}
+	return b.Backend.StopChecked(ctx, pollID)
+}
+
+func (b *unreachableBackend) Voted(ctx context.Context) (map[int][]int, error) {
+	if b.down.Load() {
+		return nil, errConnection
+	}
+	return b.Backend.Voted(ctx)
+}
+
+func TestVoteFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failoverInterval = time.Millisecond
+	defer func() { failoverInterval = time.Second }()
+
+	fast := &unreachableBackend{Backend: memory.New()}
+	long := memory.New()
+
+	v, bg, err := New(ctx, fast, long, dsmock.NewFlow(dsmock.YAMLData(failoverData)), true, WithFailover())
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+	go bg(ctx, func(err error) { t.Errorf("Background task returned unexpected error: %v", err) })
+
+	// other is another instance, that does not use the failover.
+	other, _, err := New(ctx, fast, long, dsmock.NewFlow(dsmock.YAMLData(failoverData)), true)
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	// Poll 4 is started by the other instance. So the instance with the
+	// failover does not know it.
+	if err := other.Start(ctx, 4); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := other.Vote(ctx, 4, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	fast.down.Store(true)
+	waitFor(t, "degraded state", v.Degraded)
+
+	// The votes of poll 1 can not be read from the fast backend. So it can
+	// not be migrated and can not be used, until the fast backend is back.
+	if _, err := long.Config(ctx, 1); err == nil {
+		t.Errorf("Poll 1 was migrated without its votes")
+	}
+
+	if _, err := v.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err == nil {
+		t.Errorf("Vote on poll in the unreachable fast backend returned no error")
+	}
+
+	if err := v.Start(ctx, 2); err != nil {
+		t.Fatalf("Start while degraded returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 2, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote while degraded returned unexpected error: %v", err)
+	}
+
+	long.AssertUserHasVoted(t, 2, 1)
+
+	// The fast backend can not tell, that it knows poll 4. So it is started
+	// a second time in the long backend.
+	if err := v.Start(ctx, 4); err != nil {
+		t.Fatalf("Start of an unknown poll while degraded returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 4, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote while degraded returned unexpected error: %v", err)
+	}
+
+	fast.down.Store(false)
+	waitFor(t, "healthy state", func() bool { return !v.Degraded() })
+
+	// The votes of the copy of poll 4 in the fast backend are added to the
+	// poll in the long backend.
+	result, err := v.Stop(ctx, 4)
+	if err != nil {
+		t.Fatalf("Stop of poll 4 returned unexpected error: %v", err)
+	}
+
+	if len(result.Votes) != 2 || !slices.Equal(result.UserIDs, []int{1, 2}) {
+		t.Errorf("Got %d votes from %v, expected 2 votes from [1 2]", len(result.Votes), result.UserIDs)
+	}
+
+	if _, _, stoppedBefore, err := fast.StopChecked(ctx, 4); err != nil || !stoppedBefore {
+		t.Errorf("The copy of poll 4 in the fast backend is not stopped: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote on poll 1 after recovery returned unexpected error: %v", err)
+	}
+
+	fast.AssertUserHasVoted(t, 1, 2)
+
+	if err := v.Start(ctx, 3); err != nil {
+		t.Fatalf("Start after recovery returned unexpected error: %v", err)
+	}
+
+	if _, err := fast.Config(ctx, 3); err != nil {
+		t.Errorf("Poll started after recovery is not in the fast backend: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 2, 1, strings.NewReader(`{"value":"Y"}`)); !errors.Is(err, ErrDoubleVote) {
+		t.Errorf("Second vote on poll started while degraded returned %v, expected ErrDoubleVote", err)
+	}
+
+	result, err = v.Stop(ctx, 2)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if len(result.Votes) != 1 {
+		t.Errorf("Got %d votes, expected 1", len(result.Votes))
+	}
+}
+
+func TestVoteFailoverStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fast := &unreachableBackend{Backend: memory.New()}
+	fast.down.Store(true)
+	long := memory.New()
+
+	v, _, err := New(ctx, fast, long, dsmock.NewFlow(dsmock.YAMLData(failoverData)), true, WithFailover())
+	if err != nil {
+		t.Fatalf("New with an unreachable fast backend returned unexpected error: %v", err)
+	}
+
+	if !v.Degraded() {
+		t.Errorf("Service started with an unreachable fast backend is not degraded")
+	}
+
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := long.Config(ctx, 1); err != nil {
+		t.Errorf("Poll is not started in the long backend: %v", err)
+	}
+}
+
+const failoverData = `
+poll/1:
+	meeting_id: 1
+	entitled_group_ids: [1]
+	pollmethod: Y
+	global_yes: true
+	backend: fast
+	type: pseudoanonymous
+	state: started
+poll/2:
+	meeting_id: 1
+	entitled_group_ids: [1]
+	pollmethod: Y
+	global_yes: true
+	backend: fast
+	type: pseudoanonymous
+	state: started
+poll/3:
+	meeting_id: 1
+	entitled_group_ids: [1]
+	pollmethod: Y
+	global_yes: true
+	backend: fast
+	type: pseudoanonymous
+	state: started
+poll/4:
+	meeting_id: 1
+	entitled_group_ids: [1]
+	pollmethod: Y
+	global_yes: true
+	backend: fast
+	type: pseudoanonymous
+	state: started
+
+meeting/1/id: 1
+group/1/meeting_user_ids: [10, 20]
+
+user/1:
+	is_present_in_meeting_ids: [1]
+	meeting_user_ids: [10]
+user/2:
+	is_present_in_meeting_ids: [1]
+	meeting_user_ids: [20]
+
+meeting_user/10:
+	user_id: 1
+	group_ids: [1]
+	meeting_id: 1
+meeting_user/20:
+	user_id: 2
+	group_ids: [1]
+	meeting_id: 1
+`
+
+func waitFor(t *testing.T, name string, condition func() bool) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for !condition() {
+		select {
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %s", name)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
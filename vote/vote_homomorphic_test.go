@@ -0,0 +1,270 @@
+package vote_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsmock"
+	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func TestVoteHomomorphic(t *testing.T) {
+	ctx := context.Background()
+
+	pollData := `
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: YNA
+		option_ids: [1, 2]
+		backend: fast
+		type: encrypted
+
+	meeting/1/users_enable_vote_weight: true
+	group/1/meeting_user_ids: [10, 20, 30]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+	user/2:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [20]
+	user/3:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [30]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+		vote_weight: "1.000000"
+	meeting_user/20:
+		user_id: 2
+		group_ids: [1]
+		meeting_id: 1
+		vote_weight: "2.500000"
+	meeting_user/30:
+		user_id: 3
+		group_ids: [1]
+		meeting_id: 1
+		vote_weight: "1.000000"
+	`
+
+	publicKey, shares, err := vote.NewHomomorphicKey(2, 3)
+	if err != nil {
+		t.Fatalf("NewHomomorphicKey returned unexpected error: %v", err)
+	}
+
+	encryptedBallot := func(pollID int, choices map[int]string) string {
+		value, err := vote.EncryptHomomorphic(publicKey, pollID, "YNA", choices)
+		if err != nil {
+			t.Fatalf("EncryptHomomorphic returned unexpected error: %v", err)
+		}
+		return fmt.Sprintf(`{"value":%s}`, value)
+	}
+
+	startPoll := func(t *testing.T) *vote.Vote {
+		backend := memory.New()
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(pollData)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey), vote.Homomorphic()); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+		return v
+	}
+
+	castVotes := func(t *testing.T, v *vote.Vote) {
+		for userID, choices := range map[int]map[int]string{
+			1: {1: "Y", 2: "N"},
+			2: {1: "Y", 2: "A"},
+			3: {1: "N", 2: "A"},
+		} {
+			if _, err := v.Vote(ctx, 1, userID, strings.NewReader(encryptedBallot(1, choices))); err != nil {
+				t.Fatalf("Vote for user %d returned unexpected error: %v", userID, err)
+			}
+		}
+	}
+
+	partialDecryption := func(t *testing.T, v *vote.Vote, share string) []byte {
+		aggregate, err := v.Aggregate(ctx, 1)
+		if err != nil {
+			t.Fatalf("Aggregate returned unexpected error: %v", err)
+		}
+
+		partial, err := vote.PartialDecrypt(share, aggregate)
+		if err != nil {
+			t.Fatalf("PartialDecrypt returned unexpected error: %v", err)
+		}
+		return partial
+	}
+
+	t.Run("Count totals", func(t *testing.T) {
+		v := startPoll(t)
+		castVotes(t, v)
+
+		if err := v.AddShare(ctx, 1, shares[0]); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("AddShare returned %v, expected ErrInvalid", err)
+		}
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Stop without partial decryptions returned %v, expected ErrInvalid", err)
+		}
+
+		for _, share := range shares[1:] {
+			if err := v.AddDecryption(ctx, 1, partialDecryption(t, v, share)); err != nil {
+				t.Fatalf("AddDecryption returned unexpected error: %v", err)
+			}
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		got, err := json.Marshal(result.Tally.Options)
+		if err != nil {
+			t.Fatalf("encoding tally: %v", err)
+		}
+
+		expect := `{"1":{"A":"0.000000","N":"1.000000","Y":"3.500000"},"2":{"A":"3.500000","N":"1.000000","Y":"0.000000"}}`
+		if string(got) != expect {
+			t.Errorf("Got options %s, expected %s", got, expect)
+		}
+
+		if result.Tally.Ballots != 3 || result.Tally.VotesValid != 4_500_000 {
+			t.Errorf("Got tally %+v, expected 3 ballots with 4.5 valid votes", result.Tally)
+		}
+
+		if len(result.Votes) != 0 || len(result.Receipts) != 3 {
+			t.Errorf("Got %d votes and %d receipts, expected 0 and 3", len(result.Votes), len(result.Receipts))
+		}
+	})
+
+	t.Run("Tampered partial decryption", func(t *testing.T) {
+		v := startPoll(t)
+		castVotes(t, v)
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Stop returned %v, expected ErrInvalid", err)
+		}
+
+		var partial map[string]any
+		if err := json.Unmarshal(partialDecryption(t, v, shares[0]), &partial); err != nil {
+			t.Fatalf("decoding partial decryption: %v", err)
+		}
+
+		// Use the partial decryption of another answer.
+		answers := partial["options"].(map[string]any)["1"].([]any)
+		answers[0].(map[string]any)["d"] = answers[1].(map[string]any)["d"]
+
+		body, err := json.Marshal(partial)
+		if err != nil {
+			t.Fatalf("encoding partial decryption: %v", err)
+		}
+
+		if err := v.AddDecryption(ctx, 1, body); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("AddDecryption returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Partial decryptions of another key", func(t *testing.T) {
+		v := startPoll(t)
+		castVotes(t, v)
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Stop returned %v, expected ErrInvalid", err)
+		}
+
+		_, otherShares, err := vote.NewHomomorphicKey(2, 3)
+		if err != nil {
+			t.Fatalf("NewHomomorphicKey returned unexpected error: %v", err)
+		}
+
+		for _, share := range otherShares[:2] {
+			if err := v.AddDecryption(ctx, 1, partialDecryption(t, v, share)); err != nil {
+				t.Fatalf("AddDecryption returned unexpected error: %v", err)
+			}
+		}
+
+		if _, err := v.Stop(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Stop returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Aggregate of running poll", func(t *testing.T) {
+		v := startPoll(t)
+
+		if _, err := v.Aggregate(ctx, 1); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Aggregate returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Plain vote", func(t *testing.T) {
+		v := startPoll(t)
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":{"1":"Y","2":"N"}}`)); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Vote returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Missing option", func(t *testing.T) {
+		v := startPoll(t)
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(encryptedBallot(1, map[int]string{1: "Y"}))); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Vote returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Ballot for other poll", func(t *testing.T) {
+		v := startPoll(t)
+
+		body := encryptedBallot(2, map[int]string{1: "Y", 2: "N"})
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(body)); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Vote returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Two answers for one option", func(t *testing.T) {
+		v := startPoll(t)
+
+		var yes, no struct {
+			Value map[string]map[string]any `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(encryptedBallot(1, map[int]string{1: "Y", 2: "Y"})), &yes); err != nil {
+			t.Fatalf("decoding ballot: %v", err)
+		}
+		if err := json.Unmarshal([]byte(encryptedBallot(1, map[int]string{1: "N", 2: "N"})), &no); err != nil {
+			t.Fatalf("decoding ballot: %v", err)
+		}
+
+		// Replace the ciphertext for N of option 1 with one that encrypts 1.
+		// The bit proof is valid, but the sum proof is not.
+		yes.Value["1"]["ciphertexts"].([]any)[1] = no.Value["1"]["ciphertexts"].([]any)[1]
+		yes.Value["1"]["bit_proofs"].([]any)[1] = no.Value["1"]["bit_proofs"].([]any)[1]
+
+		body, err := json.Marshal(yes)
+		if err != nil {
+			t.Fatalf("encoding ballot: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, bytes.NewReader(body)); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Vote returned %v, expected ErrInvalid", err)
+		}
+	})
+
+	t.Run("Method not supported", func(t *testing.T) {
+		backend := memory.New()
+		data := strings.Replace(pollData, "pollmethod: YNA", "pollmethod: Y", 1)
+		v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: dsmock.YAMLData(data)}, true)
+
+		if err := v.Start(ctx, 1, vote.Encrypted(publicKey), vote.Homomorphic()); !errors.Is(err, vote.ErrInvalid) {
+			t.Errorf("Start returned %v, expected ErrInvalid", err)
+		}
+	})
+}
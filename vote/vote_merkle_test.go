@@ -0,0 +1,97 @@
+package vote_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func merkleObjects(count int) [][]byte {
+	objects := make([][]byte, count)
+	for i := range objects {
+		objects[i] = []byte(fmt.Sprintf(`{"value":"Y","weight":"%d.000000"}`, i+1))
+	}
+	return objects
+}
+
+func TestMerkle(t *testing.T) {
+	for count := 0; count <= 9; count++ {
+		t.Run(fmt.Sprintf("%d objects", count), func(t *testing.T) {
+			objects := merkleObjects(count)
+			tree := vote.NewMerkle(objects)
+
+			if root := vote.MerkleRoot(objects); tree.Root != root {
+				t.Errorf("NewMerkle returned root %s, MerkleRoot returned %s", tree.Root, root)
+			}
+
+			if len(tree.Proofs) != count {
+				t.Fatalf("Got %d proofs, expected %d", len(tree.Proofs), count)
+			}
+
+			for i, proof := range tree.Proofs {
+				if err := proof.Verify(objects[i], tree.Root); err != nil {
+					t.Errorf("Proof %d is invalid: %v", i, err)
+				}
+			}
+
+			reversed := make([][]byte, count)
+			for i, object := range objects {
+				reversed[count-1-i] = object
+			}
+
+			if root := vote.MerkleRoot(reversed); root != tree.Root {
+				t.Errorf("The root depends on the order of the objects")
+			}
+
+			if count > 0 {
+				if err := tree.Proofs[0].Verify([]byte(`{"value":"N","weight":"1.000000"}`), tree.Root); err == nil {
+					t.Errorf("Proof of a changed object is valid")
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyResult(t *testing.T) {
+	objects := merkleObjects(5)
+	tree := vote.NewMerkle(objects)
+
+	votes := make([]json.RawMessage, len(objects))
+	for i, object := range objects {
+		votes[i] = object
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"votes":  votes,
+		"merkle": tree,
+	}, "", "  ")
+	if err != nil {
+		t.Fatalf("encoding result: %v", err)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		if err := vote.VerifyResult(strings.NewReader(string(result)), tree.Root, nil); err != nil {
+			t.Errorf("VerifyResult returned unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Wrong root", func(t *testing.T) {
+		if err := vote.VerifyResult(strings.NewReader(string(result)), vote.MerkleRoot(nil), nil); err == nil {
+			t.Errorf("VerifyResult returned no error")
+		}
+	})
+
+	t.Run("Changed vote", func(t *testing.T) {
+		changed := strings.Replace(string(result), `"weight": "1.000000"`, `"weight": "9.000000"`, 1)
+		if changed == string(result) {
+			t.Fatalf("Vote was not changed")
+		}
+
+		if err := vote.VerifyResult(strings.NewReader(changed), tree.Root, nil); err == nil {
+			t.Errorf("VerifyResult returned no error")
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package vote_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore/dsmock"
+	"github.com/OpenSlides/openslides-vote-service/backend/memory"
+	"github.com/OpenSlides/openslides-vote-service/backend/mirror"
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func TestVoteMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	pollData := `
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: Y
+		global_yes: true
+		backend: fast
+		type: pseudoanonymous
+		state: started
+
+	meeting/1/id: 1
+	group/1/meeting_user_ids: [10, 20, 30]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+	user/2:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [20]
+	user/3:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [30]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/20:
+		user_id: 2
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/30:
+		user_id: 3
+		group_ids: [1]
+		meeting_id: 1
+	`
+
+	newService := func(t *testing.T, fast, long vote.Backend, data string) *vote.Vote {
+		v, _, err := vote.New(ctx, fast, long, &StubGetter{data: dsmock.YAMLData(data)}, true)
+		if err != nil {
+			t.Fatalf("New returned unexpected error: %v", err)
+		}
+		return v
+	}
+
+	t.Run("Votes before and after the migration", func(t *testing.T) {
+		fast := memory.New()
+		long := memory.New()
+		v := newService(t, fast, long, pollData)
+
+		if err := v.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote before migration returned unexpected error: %v", err)
+		}
+
+		if err := v.Migrate(ctx, 1, "long"); err != nil {
+			t.Fatalf("Migrate returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); !errors.Is(err, vote.ErrDoubleVote) {
+			t.Errorf("Second vote after migration returned %v, expected ErrDoubleVote", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote after migration returned unexpected error: %v", err)
+		}
+
+		long.AssertUserHasVoted(t, 1, 2)
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 2 || len(result.UserIDs) != 2 {
+			t.Errorf("Got %d votes from %v, expected 2 votes from [1 2]", len(result.Votes), result.UserIDs)
+		}
+
+		if result.ConfigChanged {
+			t.Errorf("Migration changed the poll config")
+		}
+	})
+
+	t.Run("Migration by another instance", func(t *testing.T) {
+		fast := memory.New()
+		long := memory.New()
+		v1 := newService(t, fast, long, pollData)
+		v2 := newService(t, fast, long, pollData)
+
+		if err := v1.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v2.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote before migration returned unexpected error: %v", err)
+		}
+
+		if err := v1.Migrate(ctx, 1, "long"); err != nil {
+			t.Fatalf("Migrate returned unexpected error: %v", err)
+		}
+
+		if _, err := v2.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote on other instance returned unexpected error: %v", err)
+		}
+
+		long.AssertUserHasVoted(t, 1, 2)
+
+		result, err := v2.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 2 {
+			t.Errorf("Got %d votes, expected 2", len(result.Votes))
+		}
+	})
+
+	t.Run("Vote while another instance migrates", func(t *testing.T) {
+		fast := memory.New()
+		long := &slowImporter{Backend: memory.New(), importing: make(chan struct{})}
+		v1 := newService(t, fast, long, pollData)
+		v2 := newService(t, fast, long, pollData)
+
+		if err := v1.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		migrateErr := make(chan error, 1)
+		go func() {
+			migrateErr <- v1.Migrate(ctx, 1, "long")
+		}()
+
+		// The poll is stopped in the old backend but not yet imported into
+		// the new one.
+		<-long.importing
+
+		if _, err := v2.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote while migrating returned unexpected error: %v", err)
+		}
+
+		if err := <-migrateErr; err != nil {
+			t.Fatalf("Migrate returned unexpected error: %v", err)
+		}
+
+		long.AssertUserHasVoted(t, 1, 1)
+	})
+
+	t.Run("Into the mirror of the backend", func(t *testing.T) {
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		long := memory.New()
+		fast := mirror.New(memory.New(), long, 10)
+		go fast.Run(runCtx)
+
+		v := newService(t, fast, long, pollData)
+
+		if err := v.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote before migration returned unexpected error: %v", err)
+		}
+
+		if err := v.Migrate(ctx, 1, "long"); err != nil {
+			t.Fatalf("Migrate returned unexpected error: %v", err)
+		}
+
+		if _, err := v.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote after migration returned unexpected error: %v", err)
+		}
+
+		long.AssertUserHasVoted(t, 1, 2)
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if len(result.Votes) != 2 {
+			t.Errorf("Got %d votes, expected 2", len(result.Votes))
+		}
+	})
+
+	t.Run("Poll stopped by another instance", func(t *testing.T) {
+		fast := memory.New()
+		long := memory.New()
+		v1 := newService(t, fast, long, pollData)
+		v2 := newService(t, fast, long, pollData)
+
+		if err := v1.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		if _, err := v1.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		// The other instance has stopped the poll, but the datastore still
+		// says, that the poll is started.
+		if _, err := v2.Stop(ctx, 1); err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if err := v1.Migrate(ctx, 1, "long"); !errors.Is(err, vote.ErrInvalid) {
+			t.Fatalf("Migrate returned %v, expected ErrInvalid", err)
+		}
+
+		if _, err := long.Config(ctx, 1); err == nil {
+			t.Errorf("The stopped poll was imported into the new backend")
+		}
+
+		if _, err := v1.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); !errors.Is(err, vote.ErrStopped) {
+			t.Errorf("Vote after the refused migration returned %v, expected ErrStopped", err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name    string
+		data    string
+		backend string
+		options []vote.StartOption
+	}{
+		{"Unknown backend", pollData, "unknown", nil},
+		{"Same backend", pollData, "fast", nil},
+		{"Revotable poll", pollData, "long", []vote.StartOption{vote.Revotable()}},
+		{"Poll not started", strings.Replace(pollData, "state: started", "state: finished", 1), "long", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newService(t, memory.New(), memory.New(), tt.data)
+
+			if err := v.Start(ctx, 1, tt.options...); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			if err := v.Migrate(ctx, 1, tt.backend); !errors.Is(err, vote.ErrInvalid) {
+				t.Errorf("Migrate returned %v, expected ErrInvalid", err)
+			}
+		})
+	}
+}
+
+// slowImporter signals, when a poll is imported, and waits before the import.
+type slowImporter struct {
+	*memory.Backend
+	importing chan struct{}
+}
+
+func (s *slowImporter) Import(ctx context.Context, pollID int, config []byte, userIDs []int, objects [][]byte) error {
+	close(s.importing)
+	time.Sleep(100 * time.Millisecond)
+	return s.Backend.Import(ctx, pollID, config, userIDs, objects)
+}
@@ -0,0 +1,148 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstantRunoff(t *testing.T) {
+	one := DecimalFromInt(1)
+
+	for _, tt := range []struct {
+		name    string
+		options []int
+		ballots []rankedBallot
+		expect  RankedResult
+	}{
+		{
+			"No ballots",
+			[]int{1, 2},
+			nil,
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: 0, 2: 0}},
+				},
+			},
+		},
+		{
+			"Majority in first round",
+			[]int{1, 2, 3},
+			[]rankedBallot{
+				{[]int{1, 2}, one},
+				{[]int{1}, one},
+				{[]int{2, 1}, one},
+			},
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: 2 * one, 2: one, 3: 0}},
+				},
+				Winner: 1,
+			},
+		},
+		{
+			"Elimination with transfer",
+			[]int{1, 2, 3},
+			[]rankedBallot{
+				{[]int{1, 3}, one},
+				{[]int{1, 3}, one},
+				{[]int{2, 3}, one},
+				{[]int{2, 3}, one},
+				{[]int{3, 2}, DecimalFromInt(2)},
+				{[]int{3}, one},
+			},
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: 2 * one, 2: 2 * one, 3: 3 * one}, Eliminated: []int{2}, Tied: []int{1, 2}},
+					{Votes: map[int]Decimal{1: 2 * one, 3: 5 * one}},
+				},
+				Winner: 3,
+			},
+		},
+		{
+			// Eliminating 2 and 3 together would let 4 win.
+			"One elimination per round",
+			[]int{1, 2, 3, 4},
+			[]rankedBallot{
+				{[]int{1, 2}, 3 * one},
+				{[]int{2, 4}, 2 * one},
+				{[]int{3, 2}, 2 * one},
+				{[]int{4}, 4 * one},
+			},
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: 3 * one, 2: 2 * one, 3: 2 * one, 4: 4 * one}, Eliminated: []int{3}, Tied: []int{2, 3}},
+					{Votes: map[int]Decimal{1: 3 * one, 2: 4 * one, 4: 4 * one}, Eliminated: []int{1}},
+					{Votes: map[int]Decimal{2: 7 * one, 4: 4 * one}},
+				},
+				Winner: 2,
+			},
+		},
+		{
+			"Tie broken by earlier round",
+			[]int{1, 2, 3, 4},
+			[]rankedBallot{
+				{[]int{1}, 5 * one},
+				{[]int{2}, 2 * one},
+				{[]int{3}, 3 * one},
+				{[]int{4, 2}, one},
+			},
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: 5 * one, 2: 2 * one, 3: 3 * one, 4: one}, Eliminated: []int{4}},
+					{Votes: map[int]Decimal{1: 5 * one, 2: 3 * one, 3: 3 * one}, Eliminated: []int{2}, Tied: []int{2, 3}},
+					{Votes: map[int]Decimal{1: 5 * one, 3: 3 * one}, Exhausted: 3 * one},
+				},
+				Winner: 1,
+			},
+		},
+		{
+			"Tie",
+			[]int{1, 2},
+			[]rankedBallot{
+				{[]int{1, 2}, one},
+				{[]int{2, 1}, one},
+			},
+			RankedResult{
+				Rounds: []RankedRound{
+					{Votes: map[int]Decimal{1: one, 2: one}},
+				},
+				Tie: []int{1, 2},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instantRunoff(tt.options, tt.ballots)
+
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Got %+v, expected %+v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestTallyRanked(t *testing.T) {
+	poll := pollConfig{method: "ranked", options: []int{1, 2}, globalAbstain: true}
+	objects := [][]byte{
+		[]byte(`{"value":[2,1],"weight":"1.000000"}`),
+		[]byte(`{"value":[2],"weight":"1.000000"}`),
+		[]byte(`{"value":[1,1],"weight":"1.000000"}`),
+		[]byte(`{"value":"A","weight":"1.000000"}`),
+	}
+
+	got, err := tally(poll, objects)
+	if err != nil {
+		t.Fatalf("tally returned unexpected error: %v", err)
+	}
+
+	if got.Invalid != 1 {
+		t.Errorf("Got %d invalid ballots, expected 1", got.Invalid)
+	}
+
+	if got.Global["A"] != DecimalFromInt(1) {
+		t.Errorf("Got global abstain %s, expected 1.000000", got.Global["A"])
+	}
+
+	if got.Ranked == nil || got.Ranked.Winner != 2 {
+		t.Errorf("Got ranked result %+v, expected winner 2", got.Ranked)
+	}
+}
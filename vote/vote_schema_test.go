@@ -0,0 +1,107 @@
+package vote
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBallotSchema(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		poll   pollConfig
+		expect string
+	}{
+		{
+			"Global only",
+			pollConfig{method: "Y", globalYes: true, globalAbstain: true},
+			`{"description":"Global answer","enum":["Y","A"]}`,
+		},
+		{
+			"Method Y",
+			pollConfig{method: "Y", options: []int{1, 2}, maxAmount: 3, maxVotesPerOption: 2},
+			`{
+				"description":"Amount for each option. The sum of all amounts has to be between 1 and 3.",
+				"type":"object",
+				"properties":{
+					"1":{"type":"integer","minimum":0,"maximum":2},
+					"2":{"type":"integer","minimum":0,"maximum":2}
+				},
+				"additionalProperties":false
+			}`,
+		},
+		{
+			"Method YNA with global no",
+			pollConfig{method: "YNA", options: []int{1}, globalNo: true},
+			`{"oneOf":[
+				{"description":"Global answer","enum":["N"]},
+				{
+					"description":"Answer for each option",
+					"type":"object",
+					"properties":{"1":{"enum":["Y","N","A"]}},
+					"additionalProperties":false
+				}
+			]}`,
+		},
+		{
+			"Method score",
+			pollConfig{method: "score", options: []int{1}, maxScore: 10},
+			`{
+				"description":"Score for each option",
+				"type":"object",
+				"properties":{"1":{"type":"integer","minimum":0,"maximum":10}},
+				"additionalProperties":false,
+				"minProperties":1
+			}`,
+		},
+		{
+			"Method ranked",
+			pollConfig{method: "ranked", options: []int{1, 2, 3}},
+			`{
+				"description":"Option ids ordered by preference. The first option is the most preferred.",
+				"type":"array",
+				"items":{"enum":[1,2,3]},
+				"uniqueItems":true,
+				"minItems":1,
+				"maxItems":3
+			}`,
+		},
+		{
+			"Nothing allowed",
+			pollConfig{method: "unknown"},
+			`{"not":{}}`,
+		},
+		{
+			"Encrypted",
+			pollConfig{method: "Y", ptype: "encrypted", globalYes: true},
+			`{
+				"description":"The value encrypted with the encryption key of the poll. The decrypted value has to be valid for #/$defs/decrypted_value.",
+				"type":"string",
+				"contentEncoding":"base64"
+			}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := ballotSchema(tt.poll)
+
+			properties := schema["properties"].(map[string]any)
+			got, err := json.Marshal(properties["value"])
+			if err != nil {
+				t.Fatalf("encoding schema: %v", err)
+			}
+
+			var gotValue, expectValue any
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("decoding schema: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.expect), &expectValue); err != nil {
+				t.Fatalf("decoding expected schema: %v", err)
+			}
+
+			gotJSON, _ := json.Marshal(gotValue)
+			expectJSON, _ := json.Marshal(expectValue)
+			if string(gotJSON) != string(expectJSON) {
+				t.Errorf("Got value schema\n%s\nexpected\n%s", gotJSON, expectJSON)
+			}
+		})
+	}
+}
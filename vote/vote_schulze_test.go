@@ -0,0 +1,91 @@
+package vote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchulze(t *testing.T) {
+	// Example from https://en.wikipedia.org/wiki/Schulze_method with the
+	// options A=1, B=2, C=3, D=4 and E=5.
+	var ballots []rankedBallot
+	for _, group := range []struct {
+		count   int
+		ranking []int
+	}{
+		{5, []int{1, 3, 2, 5, 4}},
+		{5, []int{1, 4, 5, 3, 2}},
+		{8, []int{2, 5, 4, 1, 3}},
+		{3, []int{3, 1, 2, 5, 4}},
+		{7, []int{3, 1, 5, 2, 4}},
+		{2, []int{3, 2, 1, 4, 5}},
+		{7, []int{4, 3, 5, 2, 1}},
+		{8, []int{5, 2, 1, 4, 3}},
+	} {
+		for i := 0; i < group.count; i++ {
+			ballots = append(ballots, rankedBallot{group.ranking, DecimalFromInt(1)})
+		}
+	}
+
+	got := schulze([]int{1, 2, 3, 4, 5}, ballots)
+
+	matrix := func(rows [5][5]int) map[int]map[int]Decimal {
+		m := make(map[int]map[int]Decimal)
+		for i, row := range rows {
+			m[i+1] = make(map[int]Decimal)
+			for j, v := range row {
+				if i != j {
+					m[i+1][j+1] = DecimalFromInt(v)
+				}
+			}
+		}
+		return m
+	}
+
+	expectPairwise := matrix([5][5]int{
+		{0, 20, 26, 30, 22},
+		{25, 0, 16, 33, 18},
+		{19, 29, 0, 17, 24},
+		{15, 12, 28, 0, 14},
+		{23, 27, 21, 31, 0},
+	})
+	if !reflect.DeepEqual(got.Pairwise, expectPairwise) {
+		t.Errorf("Got pairwise matrix\n%v, expected\n%v", got.Pairwise, expectPairwise)
+	}
+
+	expectPaths := matrix([5][5]int{
+		{0, 28, 28, 30, 24},
+		{25, 0, 28, 33, 24},
+		{25, 29, 0, 29, 24},
+		{25, 28, 28, 0, 24},
+		{25, 28, 28, 31, 0},
+	})
+	if !reflect.DeepEqual(got.Paths, expectPaths) {
+		t.Errorf("Got paths\n%v, expected\n%v", got.Paths, expectPaths)
+	}
+
+	if !reflect.DeepEqual(got.Winners, []int{5}) {
+		t.Errorf("Got winners %v, expected [5]", got.Winners)
+	}
+}
+
+func TestSchulzeUnrankedOptions(t *testing.T) {
+	ballots := []rankedBallot{
+		{[]int{2}, DecimalFromInt(1)},
+		{[]int{1, 2}, DecimalFromInt(1)},
+	}
+
+	got := schulze([]int{1, 2, 3}, ballots)
+
+	if got.Pairwise[2][3] != DecimalFromInt(2) || got.Pairwise[3][2] != 0 {
+		t.Errorf("Ranked option 2 has to be preferred over unranked option 3, got %v", got.Pairwise)
+	}
+
+	if got.Pairwise[1][2] != DecimalFromInt(1) || got.Pairwise[2][1] != DecimalFromInt(1) {
+		t.Errorf("Got pairwise 1-2: %s, 2-1: %s, expected 1.000000 each", got.Pairwise[1][2], got.Pairwise[2][1])
+	}
+
+	if !reflect.DeepEqual(got.Winners, []int{1, 2}) {
+		t.Errorf("Got winners %v, expected tie [1 2]", got.Winners)
+	}
+}
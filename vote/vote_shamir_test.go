@@ -0,0 +1,55 @@
+package vote
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte("a secret with 32 bytes of length")
+
+	shares, err := shamirSplit(secret, 3, 5)
+	if err != nil {
+		t.Fatalf("shamirSplit returned unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		indexes []int
+		expect  bool
+	}{
+		{"First shares", []int{0, 1, 2}, true},
+		{"Last shares", []int{2, 3, 4}, true},
+		{"Mixed shares", []int{4, 0, 2}, true},
+		{"All shares", []int{0, 1, 2, 3, 4}, true},
+		{"Too few shares", []int{0, 1}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var selected [][]byte
+			for _, i := range tt.indexes {
+				selected = append(selected, shares[i])
+			}
+
+			got, err := shamirCombine(selected)
+			if err != nil {
+				t.Fatalf("shamirCombine returned unexpected error: %v", err)
+			}
+
+			if bytes.Equal(got, secret) != tt.expect {
+				t.Errorf("Got secret %q, expected correct secret: %t", got, tt.expect)
+			}
+		})
+	}
+
+	t.Run("Duplicate share", func(t *testing.T) {
+		if _, err := shamirCombine([][]byte{shares[0], shares[0], shares[1]}); err == nil {
+			t.Errorf("shamirCombine returned no error")
+		}
+	})
+
+	t.Run("Invalid threshold", func(t *testing.T) {
+		if _, err := shamirSplit(secret, 6, 5); err == nil {
+			t.Errorf("shamirSplit returned no error")
+		}
+	})
+}
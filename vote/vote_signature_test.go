@@ -0,0 +1,178 @@
+package vote_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/environment"
+	"github.com/OpenSlides/openslides-vote-service/vote"
+)
+
+func TestSigningKey(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 42
+
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "valid")
+	if err := os.WriteFile(validFile, []byte(base64.StdEncoding.EncodeToString(seed)+"\n"), 0o600); err != nil {
+		t.Fatalf("writing key file: %v", err)
+	}
+
+	invalidFile := filepath.Join(dir, "invalid")
+	if err := os.WriteFile(invalidFile, []byte("too short"), 0o600); err != nil {
+		t.Fatalf("writing key file: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		env       map[string]string
+		expectKey ed25519.PrivateKey
+		expectErr bool
+	}{
+		{
+			"Development",
+			map[string]string{"VOTE_SIGNING_KEY_FILE": validFile},
+			nil,
+			false,
+		},
+		{
+			"Valid file",
+			map[string]string{"OPENSLIDES_DEVELOPMENT": "false", "VOTE_SIGNING_KEY_FILE": validFile},
+			ed25519.NewKeyFromSeed(seed),
+			false,
+		},
+		{
+			"Missing file",
+			map[string]string{"OPENSLIDES_DEVELOPMENT": "false", "VOTE_SIGNING_KEY_FILE": filepath.Join(dir, "missing")},
+			nil,
+			false,
+		},
+		{
+			"Invalid file",
+			map[string]string{"OPENSLIDES_DEVELOPMENT": "false", "VOTE_SIGNING_KEY_FILE": invalidFile},
+			nil,
+			true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := vote.SigningKey(environment.ForTests(tt.env))
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("SigningKey returned no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("SigningKey returned unexpected error: %v", err)
+			}
+
+			if !key.Equal(tt.expectKey) {
+				t.Errorf("Got key %x, expected %x", key, tt.expectKey)
+			}
+		})
+	}
+}
+
+func TestVerifySignedResult(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	votes := [][]byte{[]byte(`{"value":"Y","weight":"1.000000"}`), []byte(`{"value":"N","weight":"1.000000"}`)}
+	encodableVotes := make([]json.RawMessage, len(votes))
+	for i, v := range votes {
+		encodableVotes[i] = v
+	}
+
+	result := map[string]any{
+		"votes":    encodableVotes,
+		"user_ids": []int{1, 2},
+		"tally": vote.Tally{
+			Ballots: 2,
+			Global:  map[string]vote.Decimal{"Y": vote.DecimalFromInt(1), "N": vote.DecimalFromInt(1)},
+		},
+		"config_changed": false,
+		"evaluation":     vote.Evaluation{QuorumReached: true, Global: &vote.OptionEvaluation{Passed: false}},
+		"merkle":         vote.NewMerkle(votes),
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("encoding result: %v", err)
+	}
+
+	signature, err := vote.SignResult(key, 5, encoded, time.Unix(1_700_000_000, 0))
+	if err != nil {
+		t.Fatalf("SignResult returned unexpected error: %v", err)
+	}
+
+	if signature.PollID != 5 || signature.SignedAt != 1_700_000_000 {
+		t.Errorf("Got signature for poll %d at %d, expected poll 5 at 1700000000", signature.PollID, signature.SignedAt)
+	}
+
+	// export returns the signed result with one changed field. The result is
+	// indented, like a reformatted file.
+	export := func(key string, value any) string {
+		exported := make(map[string]any, len(result)+1)
+		for k, v := range result {
+			exported[k] = v
+		}
+		if key != "" {
+			exported[key] = value
+		}
+		exported["signature"] = signature
+
+		b, err := json.MarshalIndent(exported, "", "  ")
+		if err != nil {
+			t.Fatalf("encoding result: %v", err)
+		}
+		return string(b)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		if err := vote.VerifyResult(strings.NewReader(export("", nil)), "", publicKey); err != nil {
+			t.Errorf("VerifyResult returned unexpected error: %v", err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name  string
+		key   string
+		value any
+	}{
+		{"Changed user ids", "user_ids", []int{1, 3}},
+		{"Changed tally", "tally", vote.Tally{Ballots: 2, Global: map[string]vote.Decimal{"Y": vote.DecimalFromInt(2)}}},
+		{"Changed evaluation", "evaluation", vote.Evaluation{QuorumReached: true, Global: &vote.OptionEvaluation{Passed: true}}},
+		{"Changed config changed", "config_changed", true},
+		{"Added field", "ballots_withheld", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := export(tt.key, tt.value)
+			if err := vote.VerifyResult(strings.NewReader(changed), "", publicKey); err == nil {
+				t.Errorf("VerifyResult returned no error")
+			}
+		})
+	}
+
+	t.Run("Other key", func(t *testing.T) {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = 1
+		other := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+		if err := vote.VerifyResult(strings.NewReader(export("", nil)), "", other); err == nil {
+			t.Errorf("VerifyResult returned no error")
+		}
+	})
+
+	t.Run("Nothing to verify", func(t *testing.T) {
+		if err := vote.VerifyResult(strings.NewReader(export("", nil)), "", nil); err == nil {
+			t.Errorf("VerifyResult returned no error")
+		}
+	})
+}
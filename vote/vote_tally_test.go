@@ -0,0 +1,265 @@
+package vote
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		value     string
+		expect    Decimal
+		expectErr bool
+	}{
+		{"1.000000", 1_000_000, false},
+		{"1", 1_000_000, false},
+		{"0.5", 500_000, false},
+		{"12.345678", 12_345_678, false},
+		{"-2.500000", -2_500_000, false},
+		{".25", 250_000, false},
+		{"", 0, true},
+		{".", 0, true},
+		{"1.0000001", 0, true},
+		{"abc", 0, true},
+		{"1.-5", 0, true},
+		{"9223372036854.775807", 9_223_372_036_854_775_807, false},
+		{"9223372036855", 0, true},
+		{"9223372036854.775808", 0, true},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDecimal(tt.value)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("ParseDecimal returned %v, expected an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseDecimal returned unexpected error: %v", err)
+			}
+
+			if got != tt.expect {
+				t.Errorf("Got %d, expected %d", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	for _, tt := range []struct {
+		value  Decimal
+		expect string
+	}{
+		{0, "0.000000"},
+		{1_000_000, "1.000000"},
+		{1_500_001, "1.500001"},
+		{-250_000, "-0.250000"},
+	} {
+		if got := tt.value.String(); got != tt.expect {
+			t.Errorf("Decimal(%d).String() == %s, expected %s", tt.value, got, tt.expect)
+		}
+	}
+}
+
+func TestDecimalDiv(t *testing.T) {
+	for _, tt := range []struct {
+		value   Decimal
+		divisor Decimal
+		expect  Decimal
+	}{
+		{DecimalFromInt(10), DecimalFromInt(4), 2_500_000},
+		{DecimalFromInt(1), DecimalFromInt(3), 333_333},
+		{DecimalFromInt(2), DecimalFromInt(3), 666_667},
+		{DecimalFromInt(-2), DecimalFromInt(3), -666_667},
+		{DecimalFromInt(1), 0, 0},
+	} {
+		got, err := tt.value.Div(tt.divisor)
+		if err != nil {
+			t.Fatalf("%s / %s returned unexpected error: %v", tt.value, tt.divisor, err)
+		}
+
+		if got != tt.expect {
+			t.Errorf("%s / %s == %s, expected %s", tt.value, tt.divisor, got, tt.expect)
+		}
+	}
+
+	t.Run("Overflow", func(t *testing.T) {
+		_, err := Decimal(math.MaxInt64).Div(1)
+		if !errors.Is(err, errDecimalOverflow) {
+			t.Errorf("Div returned %v, expected an overflow error", err)
+		}
+	})
+}
+
+func TestTally(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		poll    pollConfig
+		objects []string
+		expect  Tally
+	}{
+		{
+			"No votes",
+			pollConfig{method: "Y"},
+			nil,
+			Tally{},
+		},
+		{
+			"Global votes",
+			pollConfig{method: "Y", globalYes: true, globalNo: true},
+			[]string{
+				`{"value":"Y","weight":"1.000000"}`,
+				`{"value":"Y","weight":"2.500000"}`,
+				`{"value":"N","weight":"0.333333"}`,
+			},
+			Tally{
+				Ballots:    3,
+				VotesCast:  3_833_333,
+				VotesValid: 3_833_333,
+				Global:     map[string]Decimal{"Y": 3_500_000, "N": 333_333},
+			},
+		},
+		{
+			"Method Y with amounts",
+			pollConfig{method: "Y", options: []int{1, 2}, maxAmount: 3, maxVotesPerOption: 2},
+			[]string{
+				`{"value":{"1":2,"2":1},"weight":"1.500000"}`,
+				`{"value":{"2":1},"weight":"1.000000"}`,
+			},
+			Tally{
+				Ballots:    2,
+				VotesCast:  2_500_000,
+				VotesValid: 2_500_000,
+				Options: map[int]map[string]Decimal{
+					1: {"Y": 3_000_000},
+					2: {"Y": 2_500_000},
+				},
+			},
+		},
+		{
+			"Method N with amounts",
+			pollConfig{method: "N", options: []int{1, 2}},
+			[]string{
+				`{"value":{"1":1},"weight":"1.000000"}`,
+			},
+			Tally{
+				Ballots:    1,
+				VotesCast:  1_000_000,
+				VotesValid: 1_000_000,
+				Options: map[int]map[string]Decimal{
+					1: {"N": 1_000_000},
+					2: {},
+				},
+			},
+		},
+		{
+			"Method YNA",
+			pollConfig{method: "YNA", options: []int{1, 2}, globalAbstain: true},
+			[]string{
+				`{"value":{"1":"Y","2":"N"},"weight":"1.000000"}`,
+				`{"value":{"1":"A","2":"N"},"weight":"2.000000"}`,
+				`{"value":"A","weight":"1.000000"}`,
+			},
+			Tally{
+				Ballots:    3,
+				VotesCast:  4_000_000,
+				VotesValid: 4_000_000,
+				Global:     map[string]Decimal{"A": 1_000_000},
+				Options: map[int]map[string]Decimal{
+					1: {"Y": 1_000_000, "A": 2_000_000},
+					2: {"N": 3_000_000},
+				},
+			},
+		},
+		{
+			"Method score",
+			pollConfig{method: "score", options: []int{1, 2}, maxScore: 10},
+			[]string{
+				`{"value":{"1":10,"2":3},"weight":"1.000000"}`,
+				`{"value":{"1":4},"weight":"2.000000"}`,
+				`{"value":{"1":11},"weight":"1.000000"}`,
+			},
+			Tally{
+				Ballots:    3,
+				Invalid:    1,
+				VotesCast:  4_000_000,
+				VotesValid: 3_000_000,
+				Score: map[int]ScoreResult{
+					1: {Ballots: 2, Weight: 3_000_000, Sum: 18_000_000, Average: 6_000_000},
+					2: {Ballots: 1, Weight: 1_000_000, Sum: 3_000_000, Average: 3_000_000},
+				},
+			},
+		},
+		{
+			"Invalid objects",
+			pollConfig{method: "YN", options: []int{1}},
+			[]string{
+				`"not a vote object"`,
+				`{"value":"Y","weight":"invalid"}`,
+				`{"value":{"1":"A"},"weight":"1.000000"}`,
+				`{"value":{"1":"Y"},"weight":"1.000000"}`,
+			},
+			Tally{
+				Ballots:    4,
+				Invalid:    3,
+				VotesCast:  2_000_000,
+				VotesValid: 1_000_000,
+				Options: map[int]map[string]Decimal{
+					1: {"Y": 1_000_000},
+				},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := make([][]byte, len(tt.objects))
+			for i, o := range tt.objects {
+				objects[i] = []byte(o)
+			}
+
+			got, err := tally(tt.poll, objects)
+			if err != nil {
+				t.Fatalf("tally returned unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Got %+v, expected %+v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestTallyOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		poll    pollConfig
+		objects []string
+	}{
+		{
+			"sum of weights",
+			pollConfig{method: "Y", globalYes: true},
+			[]string{
+				`{"value":"Y","weight":"9000000000000.000000"}`,
+				`{"value":"Y","weight":"9000000000000.000000"}`,
+			},
+		},
+		{
+			"amount times weight",
+			pollConfig{method: "Y", options: []int{1}, maxVotesPerOption: 3, maxAmount: 3},
+			[]string{`{"value":{"1":3},"weight":"9000000000000.000000"}`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := make([][]byte, len(tt.objects))
+			for i, o := range tt.objects {
+				objects[i] = []byte(o)
+			}
+
+			if _, err := tally(tt.poll, objects); !errors.Is(err, errDecimalOverflow) {
+				t.Errorf("tally returned %v, expected an overflow error", err)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package vote_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -200,6 +203,118 @@ func TestVoteStart(t *testing.T) {
 	})
 }
 
+func TestVoteStartScoreRange(t *testing.T) {
+	ctx := context.Background()
+
+	ds := dsmock.NewFlow(dsmock.YAMLData(`
+	poll:
+		1:
+			meeting_id: 5
+			state: started
+			backend: fast
+			type: pseudoanonymous
+			pollmethod: score
+			option_ids: [1]
+		2:
+			meeting_id: 5
+			state: started
+			backend: fast
+			type: pseudoanonymous
+			pollmethod: Y
+
+	meeting/5/id: 5
+	`))
+
+	for _, tt := range []struct {
+		name      string
+		pollID    int
+		options   []vote.StartOption
+		expectErr bool
+	}{
+		{"Score poll without range", 1, nil, true},
+		{"Score poll with empty range", 1, []vote.StartOption{vote.ScoreRange(3, 3)}, true},
+		{"Score poll with range", 1, []vote.StartOption{vote.ScoreRange(-2, 2)}, false},
+		{"Other poll with range", 2, []vote.StartOption{vote.ScoreRange(0, 10)}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := memory.New()
+			v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+			err := v.Start(ctx, tt.pollID, tt.options...)
+
+			if tt.expectErr {
+				if !errors.Is(err, vote.ErrInvalid) {
+					t.Errorf("Start returned %v, expected ErrInvalid", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Start returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVoteNamedBackend(t *testing.T) {
+	ctx := context.Background()
+
+	ds := &StubGetter{data: dsmock.YAMLData(`
+	poll:
+		1:
+			meeting_id: 5
+			backend: archive
+			type: pseudoanonymous
+			pollmethod: Y
+		2:
+			meeting_id: 5
+			backend: unknown
+			type: pseudoanonymous
+			pollmethod: Y
+
+	meeting/5/id: 5
+	`)}
+
+	fast := memory.New()
+	long := memory.New()
+	archive := memory.New()
+	v, _, _ := vote.New(ctx, fast, long, ds, true, vote.WithBackend("archive", archive))
+
+	for _, pollID := range []int{1, 2} {
+		if err := v.Start(ctx, pollID); err != nil {
+			t.Fatalf("Start poll %d returned unexpected error: %v", pollID, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		backend *memory.Backend
+		pollID  int
+		expect  bool
+	}{
+		{"Poll with archive in archive", archive, 1, true},
+		{"Poll with archive in fast", fast, 1, false},
+		{"Poll with archive in long", long, 1, false},
+		{"Poll with unknown backend in long", long, 2, true},
+		{"Poll with unknown backend in archive", archive, 2, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.backend.Config(ctx, tt.pollID)
+			if started := err == nil; started != tt.expect {
+				t.Errorf("Poll started in backend: %t, expected %t", started, tt.expect)
+			}
+		})
+	}
+
+	if err := v.Clear(ctx, 1); err != nil {
+		t.Fatalf("Clear returned unexpected error: %v", err)
+	}
+
+	if _, err := archive.Config(ctx, 1); err == nil {
+		t.Errorf("Clear did not remove the poll from the archive backend")
+	}
+}
+
 func TestVoteStartDSError(t *testing.T) {
 	ctx := context.Background()
 	backend := memory.New()
@@ -252,7 +367,7 @@ func TestVoteStop(t *testing.T) {
 	})
 
 	t.Run("Known poll", func(t *testing.T) {
-		if err := backend.Start(ctx, 2); err != nil {
+		if err := backend.Start(ctx, 2, nil); err != nil {
 			t.Fatalf("Start returned an unexpected error: %v", err)
 		}
 
@@ -264,6 +379,9 @@ func TestVoteStop(t *testing.T) {
 			t.Fatalf("Stop returned unexpected error: %v", err)
 		}
 
+		// The backend returns the votes in a random order.
+		slices.SortFunc(result.Votes, bytes.Compare)
+
 		expect := [][]byte{[]byte(`"polldata1"`), []byte(`"polldata2"`)}
 		if !reflect.DeepEqual(result.Votes, expect) {
 			t.Errorf("Got:\n`%s`, expected\n`%s`", result.Votes, expect)
@@ -273,6 +391,10 @@ func TestVoteStop(t *testing.T) {
 			t.Errorf("Got users %s, expected [1 2]", result.Votes)
 		}
 
+		if result.Tally.Ballots != 2 || result.Tally.Invalid != 2 {
+			t.Errorf("Got tally %+v, expected two invalid ballots", result.Tally)
+		}
+
 		err = backend.Vote(ctx, 2, 3, []byte(`"polldata3"`))
 		var errStopped interface{ Stopped() }
 		if !errors.As(err, &errStopped) {
@@ -281,7 +403,7 @@ func TestVoteStop(t *testing.T) {
 	})
 
 	t.Run("Poll without data", func(t *testing.T) {
-		if err := backend.Start(ctx, 3); err != nil {
+		if err := backend.Start(ctx, 3, nil); err != nil {
 			t.Fatalf("Start: %v", err)
 		}
 
@@ -300,6 +422,350 @@ func TestVoteStop(t *testing.T) {
 	})
 }
 
+func TestVoteStopAnonymityThreshold(t *testing.T) {
+	ctx := context.Background()
+
+	ds := &StubGetter{data: dsmock.YAMLData(`
+	poll:
+		1:
+			meeting_id: 1
+			backend: fast
+			type: pseudoanonymous
+			pollmethod: Y
+		2:
+			meeting_id: 1
+			backend: fast
+			type: named
+			pollmethod: Y
+	`)}
+
+	for _, tt := range []struct {
+		name         string
+		pollID       int
+		voterCount   int
+		expectHidden bool
+	}{
+		{"Less voters", 1, 2, true},
+		{"Enough voters", 1, 3, false},
+		{"Named poll", 2, 2, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := memory.New()
+			v, _, _ := vote.New(ctx, backend, backend, ds, true, vote.WithAnonymityThreshold(3))
+
+			if err := backend.Start(ctx, tt.pollID, nil); err != nil {
+				t.Fatalf("Start returned an unexpected error: %v", err)
+			}
+
+			for userID := 1; userID <= tt.voterCount; userID++ {
+				if err := backend.Vote(ctx, tt.pollID, userID, []byte(`"polldata"`)); err != nil {
+					t.Fatalf("Vote returned an unexpected error: %v", err)
+				}
+			}
+
+			result, err := v.Stop(ctx, tt.pollID)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			if result.BallotsWithheld != tt.expectHidden {
+				t.Errorf("Got BallotsWithheld %t, expected %t", result.BallotsWithheld, tt.expectHidden)
+			}
+
+			expectVotes := tt.voterCount
+			if tt.expectHidden {
+				expectVotes = 0
+			}
+
+			if len(result.Votes) != expectVotes {
+				t.Errorf("Got %d votes, expected %d", len(result.Votes), expectVotes)
+			}
+
+			if tt.expectHidden && len(result.Receipts) != 0 {
+				t.Errorf("Got receipts %v, expected none", result.Receipts)
+			}
+
+			ballots := make([][]byte, tt.voterCount)
+			for i := range ballots {
+				ballots[i] = []byte(`"polldata"`)
+			}
+
+			if expect := vote.MerkleRoot(ballots); result.Merkle.Root != expect {
+				t.Errorf("Got merkle root %s, expected %s", result.Merkle.Root, expect)
+			}
+
+			if len(result.Merkle.Proofs) != expectVotes {
+				t.Errorf("Got %d merkle proofs, expected %d", len(result.Merkle.Proofs), expectVotes)
+			}
+
+			if result.Tally.Ballots != tt.voterCount {
+				t.Errorf("Got tally with %d ballots, expected %d", result.Tally.Ballots, tt.voterCount)
+			}
+
+			if len(result.UserIDs) != tt.voterCount {
+				t.Errorf("Got user ids %v, expected %d users", result.UserIDs, tt.voterCount)
+			}
+		})
+	}
+}
+
+func TestVoteFrozenConfig(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New()
+
+	pollData := `
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: Y
+		global_yes: %t
+		backend: fast
+		type: pseudoanonymous
+
+	meeting/1/id: 1
+	group/1/meeting_user_ids: [10, 11]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+	user/2:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [11]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/11:
+		user_id: 2
+		group_ids: [1]
+		meeting_id: 1
+	`
+
+	ds := &StubGetter{data: dsmock.YAMLData(fmt.Sprintf(pollData, true))}
+	v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	result, err := v.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if result.ConfigChanged {
+		t.Errorf("Stop reported a changed config, but the config was not changed")
+	}
+
+	backend.Clear(ctx, 1)
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	// Disable global yes while the poll is running.
+	ds.data = dsmock.YAMLData(fmt.Sprintf(pollData, false))
+
+	if _, err := v.Vote(ctx, 1, 2, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote after changing the config returned unexpected error: %v", err)
+	}
+
+	result, err = v.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if !result.ConfigChanged {
+		t.Errorf("Stop did not report the changed config")
+	}
+
+	if result.Tally.Invalid != 0 {
+		t.Errorf("The vote was counted as invalid. It has to be counted with the config from the start")
+	}
+}
+
+// configCounter is a memory backend, that counts the calls to Config.
+type configCounter struct {
+	*memory.Backend
+	calls int
+}
+
+func (b *configCounter) Config(ctx context.Context, pollID int) ([]byte, error) {
+	b.calls++
+	return b.Backend.Config(ctx, pollID)
+}
+
+func TestVoteConfigCache(t *testing.T) {
+	ctx := context.Background()
+	backend := &configCounter{Backend: memory.New()}
+
+	ds := dsmock.YAMLData(`
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: Y
+		global_yes: true
+		backend: fast
+		type: pseudoanonymous
+
+	meeting/1/id: 1
+	group/1/meeting_user_ids: [10, 11]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+	user/2:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [11]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+	meeting_user/11:
+		user_id: 2
+		group_ids: [1]
+		meeting_id: 1
+	`)
+
+	v, _, _ := vote.New(ctx, backend, backend, &StubGetter{data: ds}, true)
+
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Validate(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Validate returned unexpected error: %v", err)
+	}
+
+	for _, userID := range []int{1, 2} {
+		if _, err := v.Vote(ctx, 1, userID, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+	}
+
+	if backend.calls != 1 {
+		t.Errorf("Config was called %d times, expected 1", backend.calls)
+	}
+
+	if err := v.Clear(ctx, 1); err != nil {
+		t.Fatalf("Clear returned unexpected error: %v", err)
+	}
+
+	if err := v.Start(ctx, 1); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	if backend.calls != 2 {
+		t.Errorf("Config was called %d times after Clear, expected 2", backend.calls)
+	}
+}
+
+func TestVoteTurnout(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name   string
+		ptype  string
+		expect vote.Turnout
+	}{
+		{
+			"named",
+			"named",
+			vote.Turnout{
+				Entitled:       3,
+				EntitledWeight: 4_500_000,
+				Voted:          2,
+				VotedWeight:    3_500_000,
+				NotVoted:       []int{3},
+			},
+		},
+		{
+			"pseudoanonymous",
+			"pseudoanonymous",
+			vote.Turnout{
+				Entitled:       3,
+				EntitledWeight: 4_500_000,
+				Voted:          2,
+				VotedWeight:    3_500_000,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := memory.New()
+			ds := &StubGetter{data: dsmock.YAMLData(fmt.Sprintf(`
+			poll/1:
+				meeting_id: 1
+				entitled_group_ids: [1, 2]
+				pollmethod: Y
+				global_yes: true
+				backend: fast
+				type: %s
+
+			meeting/1/users_enable_vote_weight: true
+			group/1/meeting_user_ids: [10, 11]
+			group/2/meeting_user_ids: [11, 12]
+
+			user/1:
+				is_present_in_meeting_ids: [1]
+				meeting_user_ids: [10]
+			user/2:
+				is_present_in_meeting_ids: [1]
+				meeting_user_ids: [11]
+				default_vote_weight: "2.500000"
+			user/3:
+				meeting_user_ids: [12]
+
+			meeting_user/10:
+				user_id: 1
+				group_ids: [1]
+				meeting_id: 1
+			meeting_user/11:
+				user_id: 2
+				group_ids: [1, 2]
+				meeting_id: 1
+			meeting_user/12:
+				user_id: 3
+				group_ids: [2]
+				meeting_id: 1
+				vote_weight: "1.000000"
+			`, tt.ptype))}
+			v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+			if err := v.Start(ctx, 1); err != nil {
+				t.Fatalf("Start returned unexpected error: %v", err)
+			}
+
+			for _, userID := range []int{1, 2} {
+				if _, err := v.Vote(ctx, 1, userID, strings.NewReader(`{"value":"Y"}`)); err != nil {
+					t.Fatalf("Vote for user %d returned unexpected error: %v", userID, err)
+				}
+			}
+
+			result, err := v.Stop(ctx, 1)
+			if err != nil {
+				t.Fatalf("Stop returned unexpected error: %v", err)
+			}
+
+			if result.Turnout == nil {
+				t.Fatalf("Stop returned no turnout")
+			}
+
+			if !reflect.DeepEqual(*result.Turnout, tt.expect) {
+				t.Errorf("Got turnout %+v, expected %+v", *result.Turnout, tt.expect)
+			}
+		})
+	}
+}
+
 func TestVoteClear(t *testing.T) {
 	ctx := context.Background()
 	backend := memory.New()
@@ -348,7 +814,7 @@ func TestVoteVote(t *testing.T) {
 	v, _, _ := vote.New(ctx, backend, backend, ds, true)
 
 	t.Run("Poll does not exist in DS", func(t *testing.T) {
-		err := v.Vote(ctx, 404, 1, strings.NewReader(`{"value":"Y"}`))
+		_, err := v.Vote(ctx, 404, 1, strings.NewReader(`{"value":"Y"}`))
 
 		if !errors.Is(err, vote.ErrNotExists) {
 			t.Errorf("Expected ErrNotExists, got: %v", err)
@@ -356,19 +822,19 @@ func TestVoteVote(t *testing.T) {
 	})
 
 	t.Run("Unknown poll", func(t *testing.T) {
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
 
 		if !errors.Is(err, vote.ErrNotExists) {
 			t.Errorf("Expected ErrNotExists, got: %v", err)
 		}
 	})
 
-	if err := backend.Start(ctx, 1); err != nil {
+	if err := backend.Start(ctx, 1, nil); err != nil {
 		t.Fatalf("Starting poll returned unexpected error: %v", err)
 	}
 
 	t.Run("Invalid json", func(t *testing.T) {
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{123`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{123`))
 
 		var errTyped vote.TypeError
 		if !errors.As(err, &errTyped) {
@@ -381,7 +847,7 @@ func TestVoteVote(t *testing.T) {
 	})
 
 	t.Run("Invalid format", func(t *testing.T) {
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{}`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{}`))
 
 		var errTyped vote.TypeError
 		if !errors.As(err, &errTyped) {
@@ -394,14 +860,14 @@ func TestVoteVote(t *testing.T) {
 	})
 
 	t.Run("Valid data", func(t *testing.T) {
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
 		if err != nil {
 			t.Fatalf("Vote returned unexpected error: %v", err)
 		}
 	})
 
 	t.Run("User has voted", func(t *testing.T) {
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
 		if err == nil {
 			t.Fatalf("Vote returned no error")
 		}
@@ -419,7 +885,7 @@ func TestVoteVote(t *testing.T) {
 	t.Run("Poll is stopped", func(t *testing.T) {
 		backend.Stop(ctx, 1)
 
-		err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		_, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
 		if err == nil {
 			t.Fatalf("Vote returned no error")
 		}
@@ -435,6 +901,228 @@ func TestVoteVote(t *testing.T) {
 	})
 }
 
+func TestVoteRevotable(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New()
+	ds := &StubGetter{
+		data: dsmock.YAMLData(`
+		poll/1:
+			meeting_id: 1
+			entitled_group_ids: [1]
+			pollmethod: Y
+			global_yes: true
+			global_no: true
+			backend: fast
+			type: pseudoanonymous
+
+		meeting/1/id: 1
+		group/1/meeting_user_ids: [10]
+
+		user/1:
+			is_present_in_meeting_ids: [1]
+			meeting_user_ids: [10]
+
+		meeting_user/10:
+			user_id: 1
+			group_ids: [1]
+			meeting_id: 1
+		`),
+	}
+	v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+	if err := v.Start(ctx, 1, vote.Revotable()); err != nil {
+		t.Fatalf("Start returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+		t.Fatalf("Vote returned unexpected error: %v", err)
+	}
+
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"N"}`)); err != nil {
+		t.Fatalf("Second vote returned unexpected error: %v", err)
+	}
+
+	if count := v.VoteCount(ctx)[1]; count != 1 {
+		t.Errorf("Got vote count %d, expected 1", count)
+	}
+
+	result, err := v.Stop(ctx, 1)
+	if err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	if len(result.Votes) != 1 {
+		t.Fatalf("Got %d votes, expected 1", len(result.Votes))
+	}
+
+	if result.ConfigChanged {
+		t.Errorf("Stop reported a changed config for a revotable poll")
+	}
+
+	expect := map[string]vote.Decimal{"N": vote.DecimalFromInt(1)}
+	if !reflect.DeepEqual(result.Tally.Global, expect) {
+		t.Errorf("Got global tally %v, expected %v", result.Tally.Global, expect)
+	}
+}
+
+func TestVoteReceipt(t *testing.T) {
+	ctx := context.Background()
+
+	pollData := `
+	poll/1:
+		meeting_id: 1
+		entitled_group_ids: [1]
+		pollmethod: Y
+		global_yes: true
+		backend: fast
+		type: %s
+
+	meeting/1/id: 1
+	group/1/meeting_user_ids: [10]
+
+	user/1:
+		is_present_in_meeting_ids: [1]
+		meeting_user_ids: [10]
+
+	meeting_user/10:
+		user_id: 1
+		group_ids: [1]
+		meeting_id: 1
+	`
+
+	t.Run("Secret poll", func(t *testing.T) {
+		backend := memory.New()
+		ds := &StubGetter{data: dsmock.YAMLData(fmt.Sprintf(pollData, "pseudoanonymous"))}
+		v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+		if err := v.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		receipt, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		if err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		if receipt == "" {
+			t.Fatalf("Vote returned no receipt")
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result.Receipts, []string{receipt}) {
+			t.Errorf("Got receipts %v, expected [%s]", result.Receipts, receipt)
+		}
+
+		var object struct {
+			Value   string `json:"value"`
+			Receipt string `json:"receipt"`
+		}
+		if err := json.Unmarshal(result.Votes[0], &object); err != nil {
+			t.Fatalf("decoding vote object: %v", err)
+		}
+
+		if object.Receipt != receipt || object.Value != "Y" {
+			t.Errorf("Got vote object %s, expected value Y with receipt %s", result.Votes[0], receipt)
+		}
+	})
+
+	t.Run("Named poll", func(t *testing.T) {
+		backend := memory.New()
+		ds := &StubGetter{data: dsmock.YAMLData(fmt.Sprintf(pollData, "named"))}
+		v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+		if err := v.Start(ctx, 1); err != nil {
+			t.Fatalf("Start returned unexpected error: %v", err)
+		}
+
+		receipt, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		if err != nil {
+			t.Fatalf("Vote returned unexpected error: %v", err)
+		}
+
+		if receipt != "" {
+			t.Errorf("Vote returned receipt %s on a named poll", receipt)
+		}
+
+		result, err := v.Stop(ctx, 1)
+		if err != nil {
+			t.Fatalf("Stop returned unexpected error: %v", err)
+		}
+
+		if result.Receipts != nil {
+			t.Errorf("Got receipts %v on a named poll", result.Receipts)
+		}
+	})
+}
+
+func TestVoteDryRun(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New()
+	ds := &StubGetter{
+		data: dsmock.YAMLData(`
+		poll/1:
+			meeting_id: 1
+			entitled_group_ids: [1]
+			pollmethod: Y
+			global_yes: true
+			backend: fast
+			type: named
+
+		meeting/1/users_enable_vote_weight: true
+
+		user/1:
+			is_present_in_meeting_ids: [1]
+			meeting_user_ids: [10]
+
+		meeting_user/10:
+			user_id: 1
+			group_ids: [1]
+			meeting_id: 1
+			vote_weight: "2.500000"
+		`),
+	}
+	v, _, _ := vote.New(ctx, backend, backend, ds, true)
+
+	if err := backend.Start(ctx, 1, nil); err != nil {
+		t.Fatalf("Starting poll returned unexpected error: %v", err)
+	}
+
+	t.Run("Invalid vote", func(t *testing.T) {
+		_, err := v.Validate(ctx, 1, 1, strings.NewReader(`{"value":"N"}`))
+
+		var errTyped vote.TypeError
+		if !errors.As(err, &errTyped) {
+			t.Fatalf("Validate() did not return an TypeError, got: %v", err)
+		}
+
+		if errTyped != vote.ErrInvalid {
+			t.Errorf("Got error type `%s`, expected `%s`", errTyped.Type(), vote.ErrInvalid.Type())
+		}
+	})
+
+	t.Run("Valid vote", func(t *testing.T) {
+		object, err := v.Validate(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`))
+		if err != nil {
+			t.Fatalf("Validate returned unexpected error: %v", err)
+		}
+
+		expect := `{"request_user_id":1,"vote_user_id":1,"value":"Y","weight":"2.500000"}`
+		if string(object) != expect {
+			t.Errorf("Got object %s, expected %s", object, expect)
+		}
+	})
+
+	t.Run("Vote is not saved", func(t *testing.T) {
+		if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			t.Fatalf("Vote after Validate returned unexpected error: %v", err)
+		}
+	})
+}
+
 func TestVoteNoRequests(t *testing.T) {
 	// This tests makes sure, that a request to vote does not do any reading
 	// from the database. All values have to be in the cache from pollpreload.
@@ -597,7 +1285,7 @@ func TestVoteNoRequests(t *testing.T) {
 
 			counter.Reset()
 
-			if err := v.Vote(ctx, 1, 1, strings.NewReader(tt.vote)); err != nil {
+			if _, err := v.Vote(ctx, 1, 1, strings.NewReader(tt.vote)); err != nil {
 				t.Errorf("Vote returned unexpected error: %v", err)
 			}
 
@@ -1078,11 +1766,11 @@ func TestVoteDelegationAndGroup(t *testing.T) {
 
 			v, _, _ := vote.New(ctx, backend, backend, ds, true)
 
-			if err := backend.Start(ctx, 1); err != nil {
+			if err := backend.Start(ctx, 1, nil); err != nil {
 				t.Fatalf("backend.Start(): %v", err)
 			}
 
-			err := v.Vote(ctx, 1, 1, strings.NewReader(tt.vote))
+			_, err := v.Vote(ctx, 1, 1, strings.NewReader(tt.vote))
 
 			if tt.expectVotedUserID != 0 {
 				if err != nil {
@@ -1232,11 +1920,11 @@ func TestVoteWeight(t *testing.T) {
 			ds := &StubGetter{data: dsmock.YAMLData(tt.data)}
 			v, _, _ := vote.New(ctx, backend, backend, ds, true)
 
-			if err := backend.Start(ctx, 1); err != nil {
+			if err := backend.Start(ctx, 1, nil); err != nil {
 				t.Fatalf("bakckend.Start: %v", err)
 			}
 
-			if err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
+			if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value":"Y"}`)); err != nil {
 				t.Fatalf("vote returned unexpected error: %v", err)
 			}
 
@@ -1321,11 +2009,11 @@ func TestItLikeBackend(t *testing.T) {
 	`))
 
 	v, _, _ := vote.New(ctx, backend, backend, ds, true)
-	if err := backend.Start(ctx, 1); err != nil {
+	if err := backend.Start(ctx, 1, nil); err != nil {
 		t.Fatalf("bakckend.Start: %v", err)
 	}
 
-	if err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value": {"1": "Y"}}`)); err != nil {
+	if _, err := v.Vote(ctx, 1, 1, strings.NewReader(`{"value": {"1": "Y"}}`)); err != nil {
 		t.Fatalf("vote returned unexpected error: %v", err)
 	}
 
@@ -1346,7 +2034,7 @@ func TestVotedPolls(t *testing.T) {
 	user/5/id: 5
 	`))
 
-	backend.Start(ctx, 1)
+	backend.Start(ctx, 1, nil)
 	backend.Vote(ctx, 1, 5, []byte(`"Y"`))
 
 	v, _, _ := vote.New(ctx, backend, backend, ds, true)
@@ -1385,7 +2073,7 @@ func TestVotedPollsWithDelegation(t *testing.T) {
 		
 	`))
 
-	backend.Start(ctx, 1)
+	backend.Start(ctx, 1, nil)
 	backend.Vote(ctx, 1, 5, []byte(`"Y"`))
 	backend.Vote(ctx, 1, 6, []byte(`"Y"`))
 	backend.Vote(ctx, 1, 7, []byte(`"Y"`))
@@ -1405,10 +2093,10 @@ func TestVotedPollsWithDelegation(t *testing.T) {
 func TestVoteCount(t *testing.T) {
 	ctx := context.Background()
 	backend1 := memory.New()
-	backend1.Start(ctx, 23)
+	backend1.Start(ctx, 23, nil)
 	backend1.Vote(ctx, 23, 1, []byte("vote"))
 	backend2 := memory.New()
-	backend2.Start(ctx, 42)
+	backend2.Start(ctx, 42, nil)
 	backend2.Vote(ctx, 42, 1, []byte("vote"))
 	backend2.Vote(ctx, 42, 2, []byte("vote"))
 	ds := dsmock.NewFlow(dsmock.YAMLData(``))
@@ -283,6 +283,203 @@ func TestVoteValidate(t *testing.T) {
 			false,
 		},
 
+		// Test Method score.
+		{
+			"Method score, valid scores",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2, 3},
+				maxScore: 10,
+			},
+			`{"1":0,"2":10,"3":5}`,
+			true,
+		},
+		{
+			"Method score, score too high",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				maxScore: 10,
+			},
+			`{"1":11}`,
+			false,
+		},
+		{
+			"Method score, score too low",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				minScore: 1,
+				maxScore: 10,
+			},
+			`{"1":0}`,
+			false,
+		},
+		{
+			"Method score, negative score",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				maxScore: 10,
+			},
+			`{"1":-1}`,
+			false,
+		},
+		{
+			"Method score, negative range",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				minScore: -2,
+				maxScore: 2,
+			},
+			`{"1":-2,"2":2}`,
+			true,
+		},
+		{
+			"Method score, unknown option",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				maxScore: 10,
+			},
+			`{"3":1}`,
+			false,
+		},
+		{
+			"Method score, no option",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				maxScore: 10,
+			},
+			`{}`,
+			false,
+		},
+		{
+			"Method score, string on option",
+			pollConfig{
+				method:   "score",
+				options:  []int{1, 2},
+				maxScore: 10,
+			},
+			`{"1":"Y"}`,
+			false,
+		},
+
+		// Test Method ranked.
+		{
+			"Method ranked, full ranking",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`[3,1,2]`,
+			true,
+		},
+		{
+			"Method ranked, partial ranking",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`[2]`,
+			true,
+		},
+		{
+			"Method ranked, empty ranking",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`[]`,
+			false,
+		},
+		{
+			"Method ranked, unknown option",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`[1,4]`,
+			false,
+		},
+		{
+			"Method ranked, duplicate option",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`[1,2,1]`,
+			false,
+		},
+		{
+			"Method ranked, too few ranks",
+			pollConfig{
+				method:    "ranked",
+				options:   []int{1, 2, 3},
+				minAmount: 2,
+			},
+			`[1]`,
+			false,
+		},
+		{
+			"Method ranked, too many ranks",
+			pollConfig{
+				method:    "ranked",
+				options:   []int{1, 2, 3},
+				maxAmount: 2,
+			},
+			`[1,2,3]`,
+			false,
+		},
+		{
+			"Method ranked, global abstain",
+			pollConfig{
+				method:        "ranked",
+				options:       []int{1, 2, 3},
+				globalAbstain: true,
+			},
+			`"A"`,
+			true,
+		},
+		{
+			"Method ranked, amount on option",
+			pollConfig{
+				method:  "ranked",
+				options: []int{1, 2, 3},
+			},
+			`{"1":1}`,
+			false,
+		},
+		{
+			"Method schulze, full ranking",
+			pollConfig{
+				method:  "schulze",
+				options: []int{1, 2, 3},
+			},
+			`[2,3,1]`,
+			true,
+		},
+		{
+			"Method schulze, duplicate option",
+			pollConfig{
+				method:  "schulze",
+				options: []int{1, 2, 3},
+			},
+			`[2,2]`,
+			false,
+		},
+		{
+			"Method YNA, ranking",
+			pollConfig{
+				method:  "YNA",
+				options: []int{1, 2, 3},
+			},
+			`[1,2]`,
+			false,
+		},
+
 		// Unknown method
 		{
 			"Method Unknown",